@@ -1,15 +1,28 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/AlexFox86/auth-service/internal/delivery"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/audit"
+	"github.com/AlexFox86/auth-service/internal/pkg/ldap"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	"github.com/AlexFox86/auth-service/internal/pkg/oidc"
+	"github.com/AlexFox86/auth-service/internal/pkg/ratelimit"
+	"github.com/AlexFox86/auth-service/internal/pkg/saml"
+	"github.com/AlexFox86/auth-service/internal/pkg/sender"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
 	"github.com/AlexFox86/auth-service/internal/repository/postgres"
 	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 )
@@ -32,15 +45,260 @@ func main() {
 	}
 	defer db.Close()
 
+	smtpMailer := mailer.NewSMTPMailer(
+		os.Getenv("SMTP_HOST"),
+		os.Getenv("SMTP_PORT"),
+		os.Getenv("SMTP_USER"),
+		os.Getenv("SMTP_PASSWORD"),
+		os.Getenv("SMTP_FROM"),
+	)
+
+	opts := []service.Option{
+		service.WithMailer(smtpMailer),
+		service.WithBaseURL(os.Getenv("PUBLIC_URL")),
+		service.WithFrontendURL(os.Getenv("FRONTEND_URL")),
+		service.WithOTPSender(models.MFAMethodEmail, sender.NewEmailSender(smtpMailer, "Your verification code")),
+	}
+	if os.Getenv("EMAIL_VERIFICATION") == "required" {
+		opts = append(opts, service.WithEmailVerificationPolicy(service.EmailVerificationRequired))
+	}
+	if os.Getenv("ENUMERATION_PROTECTION") == "true" {
+		opts = append(opts, service.WithEnumerationProtection())
+	}
+	if url := os.Getenv("SMS_WEBHOOK_URL"); url != "" {
+		headers := map[string]string{}
+		if token := os.Getenv("SMS_WEBHOOK_TOKEN"); token != "" {
+			headers["Authorization"] = "Bearer " + token
+		}
+		opts = append(opts, service.WithOTPSender(models.MFAMethodSMS, sender.NewWebhookSender(url, headers)))
+	}
+
+	lockoutPolicy := service.DefaultLockoutPolicy()
+	if n, err := strconv.Atoi(os.Getenv("LOCKOUT_MAX_ATTEMPTS")); err == nil {
+		lockoutPolicy.MaxAttempts = n
+	}
+	if d, err := time.ParseDuration(os.Getenv("LOCKOUT_DURATION")); err == nil {
+		lockoutPolicy.LockoutDuration = d
+	}
+	opts = append(opts,
+		service.WithLockoutPolicy(lockoutPolicy),
+		service.WithLockoutNotifier(service.EmailLockoutNotifier(smtpMailer)),
+	)
+
+	if d, err := time.ParseDuration(os.Getenv("IMPERSONATION_EXPIRY")); err == nil {
+		opts = append(opts, service.WithImpersonationExpiry(d))
+	}
+	if d, err := time.ParseDuration(os.Getenv("DELETION_GRACE_PERIOD")); err == nil {
+		opts = append(opts, service.WithDeletionGracePeriod(d))
+	}
+
+	if path := os.Getenv("OIDC_PROVIDERS_FILE"); path != "" {
+		configs, err := oidc.LoadConfigs(path)
+		if err != nil {
+			panic(err)
+		}
+		for _, config := range configs {
+			opts = append(opts, service.WithOIDCProviders(oidc.NewProvider(config, nil)))
+		}
+	}
+	if path := os.Getenv("SAML_IDPS_FILE"); path != "" {
+		configs, err := saml.LoadConfigs(path)
+		if err != nil {
+			panic(err)
+		}
+		for _, config := range configs {
+			idp, err := saml.NewIdentityProvider(config)
+			if err != nil {
+				panic(err)
+			}
+			opts = append(opts, service.WithSAMLIdPs(idp))
+		}
+	}
+	if path := os.Getenv("LDAP_DIRECTORIES_FILE"); path != "" {
+		configs, err := ldap.LoadConfigs(path)
+		if err != nil {
+			panic(err)
+		}
+		for _, config := range configs {
+			opts = append(opts, service.WithAuthenticators(config.Tenant, service.LDAPAuthenticator(ldap.NewDirectory(config))))
+		}
+	}
+	switch os.Getenv("REGISTRATION_MODE") {
+	case "invite":
+		opts = append(opts, service.WithRegistrationMode(service.RegistrationInviteOnly))
+	case "domain":
+		domains := strings.Split(os.Getenv("REGISTRATION_DOMAINS"), ",")
+		opts = append(opts, service.WithRegistrationMode(service.RegistrationDomainRestricted, domains...))
+	}
+	if path := os.Getenv("DISPOSABLE_DOMAINS_FILE"); path != "" {
+		domains, err := service.LoadDomainList(path)
+		if err != nil {
+			panic(err)
+		}
+		opts = append(opts, service.WithDisposableEmailDomains(domains...))
+	}
+
 	repo := postgres.NewPgRepository(db)
-	service := service.New(repo, os.Getenv("SECRET"), time.Hour)
+	opts = append(opts, service.WithAuditSink(postgres.NewAuditSink(repo)))
+	if path := os.Getenv("AUDIT_LOG_FILE"); path != "" {
+		fileSink, err := audit.NewFileSink(path)
+		if err != nil {
+			panic(err)
+		}
+		defer fileSink.Close()
+		opts = append(opts, service.WithAuditSink(fileSink))
+	}
+	if os.Getenv("AUDIT_STDOUT") == "true" {
+		opts = append(opts, service.WithAuditSink(audit.NewStdoutSink()))
+	}
+
+	if path := os.Getenv("TENANTS_FILE"); path != "" {
+		tenants, err := service.LoadTenants(path)
+		if err != nil {
+			panic(err)
+		}
+		opts = append(opts, service.WithTenants(tenants...))
+	}
+
+	service := service.New(repo, os.Getenv("SECRET"), time.Hour, opts...)
 	handler := delivery.NewHandler(service)
 
-	http.HandleFunc("POST /register", handler.Register)
-	http.HandleFunc("POST /login", handler.Login)
-	http.HandleFunc("GET /validate", handler.Validate)
+	if err := service.EnsureAdminRoles(context.Background()); err != nil {
+		log.Printf("ensure admin roles: %v", err)
+	}
+
+	// users listed in ADMIN_IDS get the admin role on every start, so the first
+	// administrators can assign roles to others. Removing a user from the list
+	// doesn't revoke the role.
+	if ids := os.Getenv("ADMIN_IDS"); ids != "" {
+		for _, id := range strings.Split(ids, ",") {
+			userID, err := uuid.Parse(strings.TrimSpace(id))
+			if err != nil {
+				log.Printf("invalid admin id %q: %v", id, err)
+				continue
+			}
+			if err := service.AssignRole(context.Background(), userID, models.RoleAdmin); err != nil {
+				log.Printf("assign admin role to %s: %v", userID, err)
+			}
+		}
+	}
+
+	// deleted accounts are erased once their grace period is over
+	go func() {
+		for range time.Tick(time.Hour) {
+			n, err := service.PurgeDeletedUsers(context.Background())
+			if err != nil {
+				log.Printf("purge deleted users: %v", err)
+			} else if n > 0 {
+				log.Printf("purged %d deleted users", n)
+			}
+		}
+	}()
+
+	limits := ratelimit.NewMemoryStore()
+	registerLimit := delivery.RateLimit(limits,
+		delivery.RateLimitRule{Name: "register:ip", Key: delivery.ByIP, Limit: ratelimit.Limit{Requests: 5, Per: time.Minute}},
+		delivery.RateLimitRule{Name: "register:client", Key: delivery.ByClientID, Limit: ratelimit.Limit{Requests: 30, Per: time.Minute}},
+	)
+	loginLimit := delivery.RateLimit(limits,
+		delivery.RateLimitRule{Name: "login:ip", Key: delivery.ByIP, Limit: ratelimit.Limit{Requests: 20, Per: time.Minute}},
+		delivery.RateLimitRule{Name: "login:email", Key: delivery.ByEmail, Limit: ratelimit.Limit{Requests: 5, Per: time.Minute}},
+		delivery.RateLimitRule{Name: "login:client", Key: delivery.ByClientID, Limit: ratelimit.Limit{Requests: 100, Per: time.Minute}},
+	)
+	emailLimit := delivery.RateLimit(limits,
+		delivery.RateLimitRule{Name: "email:ip", Key: delivery.ByIP, Limit: ratelimit.Limit{Requests: 5, Per: time.Minute}},
+		delivery.RateLimitRule{Name: "email:email", Key: delivery.ByEmail, Limit: ratelimit.Limit{Requests: 3, Per: 10 * time.Minute}},
+	)
+	validateLimit := delivery.RateLimit(limits,
+		delivery.RateLimitRule{Name: "validate:ip", Key: delivery.ByIP, Limit: ratelimit.Limit{Requests: 300, Per: time.Minute}},
+		delivery.RateLimitRule{Name: "validate:client", Key: delivery.ByClientID, Limit: ratelimit.Limit{Requests: 1000, Per: time.Minute}},
+	)
+
+	http.Handle("POST /register", registerLimit(http.HandlerFunc(handler.Register)))
+	http.Handle("POST /login", loginLimit(http.HandlerFunc(handler.Login)))
+	http.Handle("GET /validate", validateLimit(http.HandlerFunc(handler.Validate)))
+	http.Handle("POST /login/magic-link", emailLimit(http.HandlerFunc(handler.RequestMagicLink)))
+	http.HandleFunc("GET /login/magic-link/verify", handler.VerifyMagicLink)
+	http.HandleFunc("GET /login/oidc", handler.ListOIDCProviders)
+	http.Handle("GET /login/oidc/{provider}", loginLimit(http.HandlerFunc(handler.StartOIDCLogin)))
+	http.HandleFunc("GET /login/oidc/{provider}/callback", handler.OIDCCallback)
+	http.HandleFunc("GET /login/saml", handler.ListSAMLProviders)
+	http.Handle("GET /login/saml/{provider}", loginLimit(http.HandlerFunc(handler.StartSAMLLogin)))
+	http.HandleFunc("GET /login/saml/{provider}/metadata", handler.SAMLMetadata)
+	http.HandleFunc("POST /login/saml/{provider}/acs", handler.SAMLAssertionConsumer)
+	http.HandleFunc("POST /login/mfa/send", handler.SendMFACode)
+	http.HandleFunc("POST /login/mfa/verify", handler.VerifyMFACode)
+	http.HandleFunc("POST /email/verify", handler.VerifyEmail)
+	http.HandleFunc("POST /password/reset", handler.ResetPassword)
+	http.Handle("POST /email/verify/resend", emailLimit(http.HandlerFunc(handler.ResendEmailVerification)))
+	http.Handle("GET /me", handler.AuthMiddleware(http.HandlerFunc(handler.GetProfile)))
+	http.Handle("PATCH /me", handler.AuthMiddleware(http.HandlerFunc(handler.UpdateProfile)))
+	http.Handle("POST /me/email", handler.AuthMiddleware(http.HandlerFunc(handler.ChangeEmail)))
+	http.HandleFunc("POST /me/email/confirm", handler.ConfirmEmailChange)
+	http.HandleFunc("POST /me/email/revert", handler.RevertEmailChange)
+	http.Handle("POST /step-up", loginLimit(handler.AuthMiddleware(http.HandlerFunc(handler.StepUp))))
+
+	// API keys can't manage the credentials of the account, only logged in users can
+	userSession := handler.RequireAuthLevel(token.ACRSingleFactor, 0)
+	http.Handle("GET /me/export", userSession(http.HandlerFunc(handler.ExportData)))
+	http.Handle("GET /me/mfa", userSession(http.HandlerFunc(handler.ListMFAMethods)))
+	http.Handle("POST /me/mfa", userSession(http.HandlerFunc(handler.AddMFAMethod)))
+	http.Handle("POST /me/mfa/{id}/confirm", userSession(http.HandlerFunc(handler.ConfirmMFAMethod)))
+	http.Handle("DELETE /me/mfa/{id}", userSession(http.HandlerFunc(handler.DeleteMFAMethod)))
+	http.Handle("GET /me/api-keys", userSession(http.HandlerFunc(handler.ListAPIKeys)))
+	http.Handle("POST /me/api-keys", userSession(http.HandlerFunc(handler.CreateAPIKey)))
+	http.Handle("DELETE /me/api-keys/{id}", userSession(http.HandlerFunc(handler.RevokeAPIKey)))
+	http.Handle("GET /me/identities", userSession(http.HandlerFunc(handler.ListIdentities)))
+	http.Handle("DELETE /me/identities/{id}", userSession(http.HandlerFunc(handler.UnlinkIdentity)))
+
+	// linking an identity and deleting the account require a recent login, e.g. after a step-up,
+	// the deletion is also confirmed with the password
+	recentSession := handler.RequireAuthLevel(token.ACRSingleFactor, 5*time.Minute)
+	http.Handle("DELETE /me", recentSession(http.HandlerFunc(handler.DeleteAccount)))
+	http.Handle("POST /me/identities/{provider}", recentSession(http.HandlerFunc(handler.LinkIdentity)))
+
+	http.Handle("POST /orgs", handler.AuthMiddleware(http.HandlerFunc(handler.CreateOrganization)))
+	http.Handle("GET /me/orgs", handler.AuthMiddleware(http.HandlerFunc(handler.ListUserOrganizations)))
+	http.HandleFunc("POST /token/org", handler.SwitchOrg)
+	http.Handle("POST /invitations/accept", handler.AuthMiddleware(http.HandlerFunc(handler.AcceptInvitation)))
+	http.Handle("GET /orgs/{org_id}/members", handler.RequireOrg(http.HandlerFunc(handler.ListMembers)))
+	http.Handle("PUT /orgs/{org_id}/members/{user_id}", handler.RequireOrg(http.HandlerFunc(handler.UpdateMember)))
+	http.Handle("DELETE /orgs/{org_id}/members/{user_id}", handler.RequireOrg(http.HandlerFunc(handler.RemoveMember)))
+	http.Handle("GET /orgs/{org_id}/invitations", handler.RequireOrg(http.HandlerFunc(handler.ListInvitations)))
+	http.Handle("POST /orgs/{org_id}/invitations", handler.RequireOrg(http.HandlerFunc(handler.InviteMember)))
+	http.Handle("DELETE /orgs/{org_id}/invitations/{id}", handler.RequireOrg(http.HandlerFunc(handler.RevokeInvitation)))
+
+	canReadAudit := handler.RequirePermission(models.PermissionAuditRead)
+	canReadUsers := handler.RequirePermission(models.PermissionUsersRead)
+	canWriteUsers := handler.RequirePermission(models.PermissionUsersWrite)
+	canManageRoles := handler.RequirePermission(models.PermissionRolesManage)
+	http.Handle("GET /admin/audit", canReadAudit(http.HandlerFunc(handler.AuditEvents)))
+	http.Handle("GET /admin/users", canReadUsers(http.HandlerFunc(handler.ListUsers)))
+	http.Handle("GET /admin/users/{id}", canReadUsers(http.HandlerFunc(handler.GetUser)))
+	http.Handle("PATCH /admin/users/{id}", canWriteUsers(http.HandlerFunc(handler.UpdateUser)))
+	http.Handle("DELETE /admin/users/{id}", canWriteUsers(http.HandlerFunc(handler.DeleteUser)))
+	http.Handle("PUT /admin/users/{id}/status", canWriteUsers(http.HandlerFunc(handler.SetUserStatus)))
+	http.Handle("POST /admin/users/{id}/disable", canWriteUsers(http.HandlerFunc(handler.DisableUser)))
+	http.Handle("POST /admin/users/{id}/enable", canWriteUsers(http.HandlerFunc(handler.EnableUser)))
+	http.Handle("POST /admin/users/{id}/restore", canWriteUsers(http.HandlerFunc(handler.RestoreUser)))
+	http.Handle("POST /admin/users/{id}/impersonate", handler.RequirePermission(models.PermissionImpersonate)(
+		http.HandlerFunc(handler.Impersonate)))
+	http.Handle("POST /admin/users/{id}/password-reset", canWriteUsers(http.HandlerFunc(handler.ForcePasswordReset)))
+	http.Handle("POST /admin/users/{id}/unlock", canWriteUsers(http.HandlerFunc(handler.UnlockUser)))
+	http.Handle("GET /admin/users/{id}/roles", canReadUsers(http.HandlerFunc(handler.ListUserRoles)))
+	http.Handle("POST /admin/users/{id}/roles", canManageRoles(http.HandlerFunc(handler.AssignRole)))
+	http.Handle("DELETE /admin/users/{id}/roles/{role}", canManageRoles(http.HandlerFunc(handler.RevokeRole)))
+	http.Handle("POST /admin/invites", handler.RequirePermission(models.PermissionUsersWrite, models.PermissionRolesManage)(
+		http.HandlerFunc(handler.CreateInvite)))
+	http.Handle("GET /admin/invites", canReadUsers(http.HandlerFunc(handler.ListInvites)))
+	http.Handle("DELETE /admin/invites/{id}", canWriteUsers(http.HandlerFunc(handler.RevokeInvite)))
+	http.Handle("GET /admin/roles", canManageRoles(http.HandlerFunc(handler.ListRoles)))
+	http.Handle("POST /admin/roles", canManageRoles(http.HandlerFunc(handler.CreateRole)))
+	http.Handle("PUT /admin/roles/{name}", canManageRoles(http.HandlerFunc(handler.UpdateRole)))
+	http.Handle("DELETE /admin/roles/{name}", canManageRoles(http.HandlerFunc(handler.DeleteRole)))
+	http.Handle("GET /admin/permissions", canManageRoles(http.HandlerFunc(handler.ListPermissions)))
 
 	port := os.Getenv("AUTH_PORT")
 	fmt.Println("Server is running on port", port)
-	log.Fatal(http.ListenAndServe(port, nil))
+	log.Fatal(http.ListenAndServe(port, delivery.WithClientInfo(handler.ResolveTenant(http.DefaultServeMux))))
 }
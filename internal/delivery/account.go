@@ -0,0 +1,77 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/service"
+)
+
+// DeleteAccount deletes the account of the recently authenticated user confirmed with the password
+func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	deletion, err := h.service.DeleteAccount(r.Context(), userID, &req)
+	if err != nil {
+		if writeImpersonating(w, err) {
+			return
+		}
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "account deletion failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(deletion)
+}
+
+// ExportData returns all data stored about the authenticated user as a JSON file
+func (h *Handler) ExportData(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	export, err := h.service.ExportData(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "data export failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition",
+		`attachment; filename="export-`+export.ExportedAt.UTC().Format(time.DateOnly)+`.json"`)
+	json.NewEncoder(w).Encode(export)
+}
+
+// RestoreUser cancels the deletion of the user's account
+func (h *Handler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	h.userAction(w, r, h.service.RestoreUser)
+}
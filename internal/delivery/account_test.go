@@ -0,0 +1,130 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandlerDeleteAccount(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("password123")
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+	tests := []struct {
+		name           string
+		user           *models.User
+		amr            []string
+		authTime       time.Time
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "success",
+			user:           &models.User{ID: userID, Password: hashedPassword},
+			amr:            []string{token.AMRPassword},
+			authTime:       time.Now(),
+			body:           `{"password":"password123"}`,
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "wrong password",
+			user:           &models.User{ID: userID, Password: hashedPassword},
+			amr:            []string{token.AMRPassword},
+			authTime:       time.Now(),
+			body:           `{"password":"wrong"}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "missing password",
+			user:           &models.User{ID: userID, Password: hashedPassword},
+			amr:            []string{token.AMRPassword},
+			authTime:       time.Now(),
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "fresh federated login",
+			user:           &models.User{ID: userID},
+			amr:            []string{token.AMRFederated},
+			authTime:       time.Now(),
+			body:           `{"password":"password123"}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "old login",
+			user:           &models.User{ID: userID, Password: hashedPassword},
+			amr:            []string{token.AMRPassword},
+			authTime:       time.Now().Add(-time.Hour),
+			body:           `{"password":"password123"}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(tt.user, nil)
+			if tt.expectedStatus == http.StatusAccepted {
+				mockRepo.On("SetUserDeleted", mock.Anything, userID, mock.AnythingOfType("*time.Time")).Return(nil)
+			}
+			svc := service.New(mockRepo, "secret", time.Hour)
+			handler := NewHandler(svc)
+			accessToken, _ := token.GenerateToken(tt.user, svc.JwtSecret(), time.Hour, token.WithAuthContext(tt.amr, tt.authTime))
+
+			req := httptest.NewRequest("DELETE", "/me", bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+			w := httptest.NewRecorder()
+
+			handler.RequireAuthLevel(token.ACRSingleFactor, 5*time.Minute)(http.HandlerFunc(handler.DeleteAccount)).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if w.Code == http.StatusAccepted {
+				var deletion dto.AccountDeletion
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&deletion))
+				assert.True(t, deletion.PurgeAt.After(time.Now()))
+			} else {
+				mockRepo.AssertNotCalled(t, "SetUserDeleted", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestHandlerExportData(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID, Username: "alex"}, nil)
+	mockRepo.On("GetUserRoles", mock.Anything, userID).Return([]models.Role{}, nil)
+	mockRepo.On("GetUserOrganizations", mock.Anything, userID).Return([]models.UserOrganization{}, nil)
+	mockRepo.On("GetMFAMethods", mock.Anything, userID).Return([]models.MFAMethod{}, nil)
+	mockRepo.On("ListAPIKeys", mock.Anything, userID).Return([]models.APIKey{}, nil)
+	mockRepo.On("ListUserIdentities", mock.Anything, userID).Return([]models.UserIdentity{}, nil)
+	mockRepo.On("ListAuditEvents", mock.Anything, mock.Anything).Return([]models.AuditEvent{}, nil)
+	handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+	req := httptest.NewRequest("GET", "/me/export", nil)
+	req = req.WithContext(context.WithValue(req.Context(), contextKeyUserID, userID.String()))
+	w := httptest.NewRecorder()
+
+	handler.ExportData(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+	var export dto.DataExport
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&export))
+	assert.Equal(t, "alex", export.User.Username)
+}
@@ -0,0 +1,99 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+)
+
+// writeAPIKeyError maps API key errors to HTTP responses
+func writeAPIKeyError(w http.ResponseWriter, err error) {
+	if writeImpersonating(w, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, service.ErrAPIKeyNotFound):
+		http.Error(w, "api key not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidScope):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrInvalidExpiry):
+		http.Error(w, "expiration time is in the past", http.StatusBadRequest)
+	default:
+		http.Error(w, "api key operation failed", http.StatusInternalServerError)
+	}
+}
+
+// CreateAPIKey creates a personal API key of the authenticated user
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.service.CreateAPIKey(r.Context(), userID, &req)
+	if err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(key)
+}
+
+// ListAPIKeys returns the API keys of the authenticated user
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := h.service.APIKeys(r.Context(), userID)
+	if err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// RevokeAPIKey revokes the API key of the authenticated user
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keyID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid api key id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RevokeAPIKey(r.Context(), userID, keyID); err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
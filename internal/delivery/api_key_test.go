@@ -0,0 +1,132 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandlerCreateAPIKey(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	roles := []models.Role{{Name: "auditor", Permissions: []string{models.PermissionAuditRead}}}
+
+	tests := []struct {
+		name           string
+		requestBody    any
+		mockSetup      func(*mockrepo.MockRepository)
+		expectedStatus int
+	}{
+		{
+			name:        "success",
+			requestBody: dto.CreateAPIKeyRequest{Name: "ci", Scopes: []string{models.PermissionAuditRead}},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserRoles", mock.Anything, userID).Return(roles, nil)
+				m.On("CreateAPIKey", mock.Anything, mock.AnythingOfType("models.APIKey")).Return(nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:        "invalid scope",
+			requestBody: dto.CreateAPIKeyRequest{Name: "ci", Scopes: []string{models.PermissionRolesManage}},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserRoles", mock.Anything, userID).Return(roles, nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing name",
+			requestBody:    dto.CreateAPIKeyRequest{},
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest("POST", "/me/api-keys", bytes.NewReader(body))
+			req = req.WithContext(context.WithValue(req.Context(), contextKeyUserID, userID.String()))
+			w := httptest.NewRecorder()
+
+			handler.CreateAPIKey(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if w.Code == http.StatusCreated {
+				var created dto.APIKeyCreated
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+				assert.True(t, service.IsAPIKey(created.Key))
+				assert.NotContains(t, w.Body.String(), "key_hash")
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandlerRevokeAPIKey(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	keyID := uuid.MustParse("00000000-0000-0000-0000-0000000000b1")
+
+	tests := []struct {
+		name           string
+		keyID          string
+		mockSetup      func(*mockrepo.MockRepository)
+		expectedStatus int
+	}{
+		{
+			name:  "success",
+			keyID: keyID.String(),
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("DeleteAPIKey", mock.Anything, userID, keyID).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:  "not found",
+			keyID: keyID.String(),
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("DeleteAPIKey", mock.Anything, userID, keyID).Return(models.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid id",
+			keyID:          "invalid",
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+			req := httptest.NewRequest("DELETE", "/me/api-keys/"+tt.keyID, nil)
+			req.SetPathValue("id", tt.keyID)
+			req = req.WithContext(context.WithValue(req.Context(), contextKeyUserID, userID.String()))
+			w := httptest.NewRecorder()
+
+			handler.RevokeAPIKey(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/audit"
+	"github.com/google/uuid"
+)
+
+// WithClientInfo adds the client IP and user agent to the request context for audit events
+func WithClientInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := audit.ContextWithClient(r.Context(), audit.Client{
+			IP:        ByIP(r),
+			UserAgent: r.UserAgent(),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// AuditEvents returns the audit events filtered by the user_id, type,
+// from and to (RFC 3339) query parameters, paginated with limit and offset
+func (h *Handler) AuditEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var filter models.AuditFilter
+
+	if userID := query.Get("user_id"); userID != "" {
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			http.Error(w, "invalid user_id", http.StatusBadRequest)
+			return
+		}
+		filter.UserID = &id
+	}
+	filter.Type = query.Get("type")
+
+	for name, field := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, "invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*field = t
+		}
+	}
+
+	for name, field := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				http.Error(w, "invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*field = n
+		}
+	}
+
+	events, err := h.service.AuditEvents(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "failed to get audit events", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
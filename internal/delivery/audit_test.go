@@ -0,0 +1,91 @@
+package delivery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/audit"
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWithClientInfo(t *testing.T) {
+	var client audit.Client
+	handler := WithClientInfo(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client = audit.ClientFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/validate", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("User-Agent", "curl/8.0")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, audit.Client{IP: "192.0.2.1", UserAgent: "curl/8.0"}, client)
+}
+
+func TestHandlerAuditEvents(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	from := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		query          string
+		mockSetup      func(*mockrepo.MockRepository)
+		expectedStatus int
+	}{
+		{
+			name:  "filters",
+			query: "?user_id=" + userID.String() + "&type=login.failed&from=2025-07-01T00:00:00Z&limit=10&offset=20",
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("ListAuditEvents", mock.Anything, models.AuditFilter{
+					UserID: &userID,
+					Type:   models.AuditLoginFailed,
+					From:   from,
+					Limit:  10,
+					Offset: 20,
+				}).Return([]models.AuditEvent{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid user id",
+			query:          "?user_id=invalid",
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid time",
+			query:          "?to=yesterday",
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid limit",
+			query:          "?limit=-1",
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+			req := httptest.NewRequest("GET", "/admin/audit"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.AuditEvents(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
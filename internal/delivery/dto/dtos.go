@@ -1,18 +1,32 @@
 package dto
 
-import "github.com/AlexFox86/auth-service/internal/models"
+import (
+	"encoding/json"
+	"time"
 
-// RegisterRequest registration request
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// RegisterRequest registration request.
+// The username can't contain '@' to be distinguishable from emails at login.
 type RegisterRequest struct {
-	Username string `json:"username" validate:"required,min=3,max=32"`
+	Username string `json:"username" validate:"required,min=3,max=32,excludesall=@"`
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=8"`
+
+	// InviteToken the token of the registration invite, required if registration is invite-only
+	InviteToken string `json:"invite_token,omitempty"`
 }
 
-// LoginRequest login request
+// LoginRequest login request.
+// The identifier is a username or an email; the email field is kept for compatibility.
 type LoginRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required"`
+	Identifier string `json:"identifier" validate:"required_without=Email,omitempty,max=254"`
+	Email      string `json:"email" validate:"required_without=Identifier,omitempty,email"`
+	Password   string `json:"password" validate:"required"`
+	// OrgID scopes the token to the organization, optional
+	OrgID uuid.UUID `json:"org_id"`
 }
 
 // Response response with a token
@@ -20,3 +34,237 @@ type Response struct {
 	Token string      `json:"token"`
 	User  models.User `json:"user"`
 }
+
+// ErrorResponse machine-readable error
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Field string `json:"field,omitempty"`
+}
+
+// MagicLinkRequest passwordless login request
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// MFAMethod the MFA method available to pass the login challenge
+type MFAMethod struct {
+	ID          uuid.UUID `json:"id"`
+	Type        string    `json:"type"`
+	Destination string    `json:"destination"`
+}
+
+// MFAChallenge response to a login which requires a second factor
+type MFAChallenge struct {
+	MFARequired    bool        `json:"mfa_required"`
+	ChallengeToken string      `json:"challenge_token"`
+	Methods        []MFAMethod `json:"methods"`
+}
+
+// MFASendRequest request to send a one-time code during login
+type MFASendRequest struct {
+	ChallengeToken string    `json:"challenge_token" validate:"required"`
+	MethodID       uuid.UUID `json:"method_id" validate:"required"`
+}
+
+// MFAVerifyRequest request to complete login with a one-time code
+type MFAVerifyRequest struct {
+	ChallengeToken string    `json:"challenge_token" validate:"required"`
+	MethodID       uuid.UUID `json:"method_id" validate:"required"`
+	Code           string    `json:"code" validate:"required,numeric,len=6"`
+}
+
+// AddMFAMethodRequest request to enroll a new MFA method.
+// The destination defaults to the user's email for the email method.
+type AddMFAMethodRequest struct {
+	Type        string `json:"type" validate:"required,oneof=email sms"`
+	Destination string `json:"destination" validate:"required_if=Type sms,omitempty,e164|email"`
+}
+
+// OTPCodeRequest request with a one-time code
+type OTPCodeRequest struct {
+	Code string `json:"code" validate:"required,numeric,len=6"`
+}
+
+// StepUpRequest re-authentication request
+type StepUpRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// VerifyEmailRequest email verification request
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ResendVerificationRequest request to resend the email verification link
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ChangeEmailRequest request to change the user's email
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+// EmailChangeTokenRequest request with the token from the email change link
+type EmailChangeTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// RoleRequest request to create a role
+type RoleRequest struct {
+	Name        string   `json:"name" validate:"required,max=64,excludesall= /"`
+	Description string   `json:"description" validate:"max=255"`
+	Permissions []string `json:"permissions" validate:"dive,required,max=64,excludesall= "`
+}
+
+// UpdateRoleRequest request to replace the description and permissions of a role
+type UpdateRoleRequest struct {
+	Description string   `json:"description" validate:"max=255"`
+	Permissions []string `json:"permissions" validate:"dive,required,max=64,excludesall= "`
+}
+
+// AssignRoleRequest request to assign a role to a user
+type AssignRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
+
+// CreateOrgRequest request to create an organization
+type CreateOrgRequest struct {
+	Name string `json:"name" validate:"required,max=128"`
+	Slug string `json:"slug" validate:"required,min=2,max=63"`
+}
+
+// SwitchOrgRequest request to scope the token to another organization.
+// A missing org_id issues an unscoped token.
+type SwitchOrgRequest struct {
+	OrgID uuid.UUID `json:"org_id"`
+}
+
+// UpdateMemberRequest request to change the role of an organization member
+type UpdateMemberRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+// InviteRequest request to invite a user to an organization
+type InviteRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+// AcceptInvitationRequest request to accept an invitation to an organization
+type AcceptInvitationRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// CreateAPIKeyRequest request to create a personal API key.
+// The scopes are permissions of the user granted to the key.
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=64"`
+	Scopes    []string   `json:"scopes" validate:"dive,required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// APIKeyCreated the created API key with the key itself, which is shown only once
+type APIKeyCreated struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+// UpdateUserRequest admin request to change the username or email of a user.
+// Missing fields are not changed.
+type UpdateUserRequest struct {
+	Username *string `json:"username" validate:"omitnil,min=3,max=32,excludesall=@"`
+	Email    *string `json:"email" validate:"omitnil,email"`
+}
+
+// UserPage page of the user list
+type UserPage struct {
+	Users  []models.User `json:"users"`
+	Total  int           `json:"total"`
+	Limit  int           `json:"limit"`
+	Offset int           `json:"offset"`
+}
+
+// ResetPasswordRequest request to set a new password with the token from the reset link
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+// UpdateProfileRequest request to change the user's own profile. Missing fields are not changed,
+// the metadata is replaced as a whole. The updated_at of the profile the changes are based on
+// is required unless it is sent in the If-Match header as the ETag.
+type UpdateProfileRequest struct {
+	Username    *string         `json:"username" validate:"omitnil,min=3,max=32,excludesall=@"`
+	DisplayName *string         `json:"display_name" validate:"omitnil,max=64"`
+	Locale      *string         `json:"locale" validate:"omitnil,omitempty,bcp47_language_tag"`
+	Timezone    *string         `json:"timezone" validate:"omitnil,omitempty,timezone"`
+	Metadata    json.RawMessage `json:"metadata"`
+	UpdatedAt   *time.Time      `json:"updated_at"`
+}
+
+// DeleteAccountRequest request to delete the user's own account
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// AccountDeletion response to the account deletion with the time its data is erased
+type AccountDeletion struct {
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// DataExport all data stored about the user
+type DataExport struct {
+	ExportedAt    time.Time                 `json:"exported_at"`
+	User          models.User               `json:"user"`
+	Roles         []string                  `json:"roles"`
+	Organizations []models.UserOrganization `json:"organizations"`
+	MFAMethods    []models.MFAMethod        `json:"mfa_methods"`
+	APIKeys       []models.APIKey           `json:"api_keys"`
+	Identities    []models.UserIdentity     `json:"identities"`
+	AuditEvents   []models.AuditEvent       `json:"audit_events"`
+}
+
+// SetUserStatusRequest request to change the status of a user. The optional until-time
+// ends a status other than active, e.g. a temporary suspension.
+type SetUserStatusRequest struct {
+	Status string     `json:"status" validate:"required,oneof=active pending suspended disabled"`
+	Reason string     `json:"reason" validate:"max=256"`
+	Until  *time.Time `json:"until"`
+}
+
+// CreateInviteRequest request to create a registration invite. The invite is bound
+// to the email if it is set. The invited user gets the roles and joins
+// the organization with the org_role, member by default.
+type CreateInviteRequest struct {
+	Email     string     `json:"email" validate:"omitempty,email"`
+	Roles     []string   `json:"roles" validate:"dive,required"`
+	OrgID     *uuid.UUID `json:"org_id"`
+	OrgRole   string     `json:"org_role" validate:"omitempty,oneof=owner admin member"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// InviteCreated the created registration invite with the token, which is shown only once,
+// and the registration link with it
+type InviteCreated struct {
+	models.RegistrationInvite
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// ImpersonateRequest request to act as the user with the reason recorded in the audit log
+type ImpersonateRequest struct {
+	Reason string `json:"reason" validate:"required,max=256"`
+}
+
+// UserIdentities the login methods of the user
+type UserIdentities struct {
+	HasPassword bool                  `json:"has_password"`
+	Identities  []models.UserIdentity `json:"identities"`
+}
+
+// IdentityLink the login page of the provider to link the identity with
+type IdentityLink struct {
+	URL string `json:"url"`
+}
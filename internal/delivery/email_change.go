@@ -0,0 +1,89 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/service"
+)
+
+// writeEmailChangeError maps email change errors to HTTP responses
+func writeEmailChangeError(w http.ResponseWriter, err error) {
+	if writeConflict(w, err) || writeImpersonating(w, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, service.ErrInvalidCredentials):
+		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+	case errors.Is(err, service.ErrInvalidEmailChange):
+		http.Error(w, "invalid or expired link", http.StatusBadRequest)
+	case errors.Is(err, service.ErrEmailDomainNotAllowed), errors.Is(err, service.ErrDisposableEmail):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "email change failed", http.StatusInternalServerError)
+	}
+}
+
+// ChangeEmail starts the email change of the authenticated user
+func (h *Handler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.ChangeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RequestEmailChange(r.Context(), userID, &req); err != nil {
+		writeEmailChangeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	io.WriteString(w, "a confirmation link has been sent to the new email")
+}
+
+// ConfirmEmailChange sets the new email with the token from the confirmation link
+func (h *Handler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	h.handleEmailChangeToken(w, r, h.service.ConfirmEmailChange)
+}
+
+// RevertEmailChange restores the old email with the token from the notification link
+func (h *Handler) RevertEmailChange(w http.ResponseWriter, r *http.Request) {
+	h.handleEmailChangeToken(w, r, h.service.RevertEmailChange)
+}
+
+func (h *Handler) handleEmailChangeToken(w http.ResponseWriter, r *http.Request,
+	apply func(context.Context, *dto.EmailChangeTokenRequest) error) {
+	var req dto.EmailChangeTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := apply(r.Context(), &req); err != nil {
+		writeEmailChangeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
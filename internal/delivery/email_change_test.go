@@ -0,0 +1,67 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlerChangeEmail(t *testing.T) {
+	handler := NewHandler(service.New(new(mockrepo.MockRepository), "secret", time.Hour))
+
+	tests := []struct {
+		name           string
+		userID         string
+		requestBody    any
+		expectedStatus int
+	}{
+		{
+			name:           "unauthorized",
+			requestBody:    dto.ChangeEmailRequest{NewEmail: "new@example.com", Password: "password123"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "validation error",
+			userID:         uuid.NewString(),
+			requestBody:    dto.ChangeEmailRequest{NewEmail: "invalid", Password: "password123"},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest("POST", "/me/email", bytes.NewReader(body))
+			if tt.userID != "" {
+				req = req.WithContext(context.WithValue(req.Context(), contextKeyUserID, tt.userID))
+			}
+			w := httptest.NewRecorder()
+
+			handler.ChangeEmail(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestHandlerConfirmEmailChange(t *testing.T) {
+	handler := NewHandler(service.New(new(mockrepo.MockRepository), "secret", time.Hour))
+
+	body, _ := json.Marshal(dto.EmailChangeTokenRequest{Token: "invalid"})
+	req := httptest.NewRequest("POST", "/me/email/confirm", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.ConfirmEmailChange(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,58 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/service"
+)
+
+// VerifyEmail confirms the user's email with the token from the verification link
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req dto.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.VerifyEmail(r.Context(), &req); err != nil {
+		if errors.Is(err, service.ErrInvalidVerificationToken) {
+			http.Error(w, "invalid or expired token", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "email verification failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResendEmailVerification sends the verification link again
+func (h *Handler) ResendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	var req dto.ResendVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ResendEmailVerification(r.Context(), &req); err != nil {
+		http.Error(w, "failed to send verification link", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	io.WriteString(w, "if the account exists and is not verified, a verification link has been sent")
+}
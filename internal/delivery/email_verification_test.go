@@ -0,0 +1,94 @@
+package delivery
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandlerVerifyEmail(t *testing.T) {
+	handler := NewHandler(service.New(new(mockrepo.MockRepository), "secret", time.Hour))
+
+	tests := []struct {
+		name           string
+		requestBody    any
+		expectedStatus int
+	}{
+		{
+			name:           "invalid token",
+			requestBody:    dto.VerifyEmailRequest{Token: "invalid"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "validation error",
+			requestBody:    dto.VerifyEmailRequest{},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest("POST", "/email/verify", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			handler.VerifyEmail(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByID", mock.Anything, "", mock.Anything).Return(&models.User{Status: models.UserStatusActive}, nil)
+	service := service.New(mockRepo, "secret", time.Hour)
+	handler := NewHandler(service)
+
+	tests := []struct {
+		name           string
+		emailVerified  bool
+		expectedStatus int
+	}{
+		{
+			name:           "verified email",
+			emailVerified:  true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unverified email",
+			emailVerified:  false,
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &models.User{ID: uuid.New(), EmailVerified: tt.emailVerified}
+			tokenString, _ := token.GenerateToken(user, service.JwtSecret(), time.Hour)
+
+			req := httptest.NewRequest("GET", "/protected", nil)
+			req.Header.Set("Authorization", "Bearer "+tokenString)
+			w := httptest.NewRecorder()
+
+			testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			handler.RequireVerifiedEmail(testHandler).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
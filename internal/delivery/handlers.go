@@ -9,12 +9,10 @@ import (
 	"github.com/go-playground/validator/v10"
 
 	"github.com/AlexFox86/auth-service/internal/delivery/dto"
-	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/AlexFox86/auth-service/internal/models"
 	"github.com/AlexFox86/auth-service/internal/service"
 )
 
-var errEmailExists = errors.New("email already exists")
-
 // Handler provides HTTP handlers for authentication
 type Handler struct {
 	service  *service.Service
@@ -43,12 +41,29 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user, err := h.service.Register(r.Context(), &req)
+	if h.service.EnumerationProtection() && (err == nil || errors.Is(err, service.ErrEmailExists)) {
+		// the owner of a taken email is notified instead
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
 	if err != nil {
-		if errors.Is(err, errEmailExists) {
-			http.Error(w, "email already exists", http.StatusConflict)
+		if writeConflict(w, err) {
 			return
 		}
-		http.Error(w, "registration failed", http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, service.ErrUsernameReserved):
+			writeFieldError(w, err, "username")
+		case errors.Is(err, service.ErrWeakPassword):
+			writeFieldError(w, err, "password")
+		case errors.Is(err, service.ErrEmailDomainNotAllowed), errors.Is(err, service.ErrDisposableEmail):
+			writeFieldError(w, err, "email")
+		case errors.Is(err, service.ErrInvalidInvite):
+			writeFieldError(w, err, "invite_token")
+		case errors.Is(err, service.ErrInviteRequired):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, "registration failed", http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -56,6 +71,32 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(user)
 }
 
+// writeFieldError writes 400 with the name of the invalid field
+func writeFieldError(w http.ResponseWriter, err error, field string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(dto.ErrorResponse{
+		Error: err.Error(),
+		Field: field,
+	})
+}
+
+// writeConflict writes 409 with the name of the taken field if the error is a conflict
+func writeConflict(w http.ResponseWriter, err error) bool {
+	var conflict *models.ConflictError
+	if !errors.As(err, &conflict) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(dto.ErrorResponse{
+		Error: conflict.Error(),
+		Field: conflict.Field,
+	})
+	return true
+}
+
 // Login processes the login request
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	var req dto.LoginRequest
@@ -71,10 +112,33 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 
 	resp, err := h.service.Login(r.Context(), &req)
 	if err != nil {
+		if writeMFAChallenge(w, err) {
+			return
+		}
+		if writeLocked(w, err) {
+			return
+		}
+		if writeAccountBlocked(w, err) {
+			return
+		}
 		if errors.Is(err, service.ErrInvalidCredentials) {
 			http.Error(w, "invalid credentials", http.StatusUnauthorized)
 			return
 		}
+		if errors.Is(err, service.ErrEmailNotVerified) {
+			http.Error(w, "email not verified", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, service.ErrNotOrgMember) {
+			http.Error(w, "not a member of the organization", http.StatusForbidden)
+			return
+		}
+		// the first login of a directory user creates an account, which the registration rules can refuse
+		if errors.Is(err, service.ErrExternalEmailNotVerified) || errors.Is(err, service.ErrInviteRequired) ||
+			errors.Is(err, service.ErrEmailDomainNotAllowed) || errors.Is(err, service.ErrDisposableEmail) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, "login failed", http.StatusInternalServerError)
 		return
 	}
@@ -85,21 +149,7 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 
 // Validate validates the token
 func (h *Handler) Validate(w http.ResponseWriter, r *http.Request) {
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		http.Error(w, "authorization header required", http.StatusUnauthorized)
-		return
-	}
-
-	claims, err := token.ValidateToken(authHeader, h.service.JwtSecret())
-	if err != nil {
-		http.Error(w, "invalid token", http.StatusUnauthorized)
-		return
-	}
-
-	_, ok := claims["sub"].(string)
-	if !ok {
-		http.Error(w, "invalid token claims", http.StatusUnauthorized)
+	if _, ok := h.authenticate(w, r); !ok {
 		return
 	}
 
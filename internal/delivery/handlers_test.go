@@ -3,7 +3,6 @@ package delivery
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -12,6 +11,7 @@ import (
 	"github.com/AlexFox86/auth-service/internal/delivery/dto"
 	"github.com/AlexFox86/auth-service/internal/models"
 	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
 	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
 	"github.com/AlexFox86/auth-service/internal/service"
 	"github.com/google/uuid"
@@ -19,7 +19,7 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
-var errUserNotFound = errors.New("user not found")
+var errUserNotFound = models.ErrNotFound
 
 func TestHandlerRegister(t *testing.T) {
 	mockRepo := new(mockrepo.MockRepository)
@@ -31,6 +31,7 @@ func TestHandlerRegister(t *testing.T) {
 		requestBody    any
 		mockSetup      func()
 		expectedStatus int
+		expectedField  string
 	}{
 		{
 			name: "successful registration",
@@ -65,6 +66,27 @@ func TestHandlerRegister(t *testing.T) {
 			mockSetup:      func() {},
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name: "username looks like email",
+			requestBody: dto.RegisterRequest{
+				Username: "test@user",
+				Email:    "test@example.com",
+				Password: "password123",
+			},
+			mockSetup:      func() {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "reserved username",
+			requestBody: dto.RegisterRequest{
+				Username: "admin",
+				Email:    "test@example.com",
+				Password: "password123",
+			},
+			mockSetup:      func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedField:  "username",
+		},
 		{
 			name: "email already exists",
 			requestBody: dto.RegisterRequest{
@@ -74,9 +96,24 @@ func TestHandlerRegister(t *testing.T) {
 			},
 			mockSetup: func() {
 				mockRepo.On("CreateUser", mock.Anything, mock.AnythingOfType("*models.User")).
-					Return(errEmailExists).Once()
+					Return(models.ErrEmailExists).Once()
+			},
+			expectedStatus: http.StatusConflict,
+			expectedField:  "email",
+		},
+		{
+			name: "username already exists",
+			requestBody: dto.RegisterRequest{
+				Username: "exists",
+				Email:    "test@example.com",
+				Password: "password123",
+			},
+			mockSetup: func() {
+				mockRepo.On("CreateUser", mock.Anything, mock.AnythingOfType("*models.User")).
+					Return(models.ErrUsernameExists).Once()
 			},
 			expectedStatus: http.StatusConflict,
+			expectedField:  "username",
 		},
 	}
 
@@ -92,11 +129,42 @@ func TestHandlerRegister(t *testing.T) {
 			handler.Register(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedField != "" {
+				var resp dto.ErrorResponse
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+				assert.Equal(t, tt.expectedField, resp.Field)
+			}
 			mockRepo.AssertExpectations(t)
 		})
 	}
 }
 
+func TestHandlerRegisterEnumerationProtection(t *testing.T) {
+	mockRepo := new(mockrepo.MockRepository)
+	service := service.New(mockRepo, "secret", time.Hour,
+		service.WithMailer(mailer.NewMemoryMailer()), service.WithEnumerationProtection())
+	handler := NewHandler(service)
+
+	register := func(email string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(dto.RegisterRequest{Username: "testuser", Email: email, Password: "password123"})
+		req := httptest.NewRequest("POST", "/register", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.Register(w, req)
+		return w
+	}
+
+	mockRepo.On("CreateUser", mock.Anything, mock.AnythingOfType("*models.User")).Return(nil).Once()
+	created := register("new@example.com")
+
+	mockRepo.On("CreateUser", mock.Anything, mock.AnythingOfType("*models.User")).Return(models.ErrEmailExists).Once()
+	exists := register("exists@example.com")
+
+	assert.Equal(t, http.StatusAccepted, created.Code)
+	assert.Equal(t, created.Code, exists.Code)
+	assert.Equal(t, created.Body.String(), exists.Body.String())
+	mockRepo.AssertExpectations(t)
+}
+
 func TestHandlerLogin(t *testing.T) {
 	mockRepo := new(mockrepo.MockRepository)
 	service := service.New(mockRepo, "secret", time.Hour)
@@ -117,13 +185,17 @@ func TestHandlerLogin(t *testing.T) {
 				Password: "password123",
 			},
 			mockSetup: func() {
-				mockRepo.On("GetUserByEmail", mock.Anything, "test@example.com").
+				mockRepo.On("GetUserByEmail", mock.Anything, "", "test@example.com").
 					Return(&models.User{
 						ID:       uuid.New(),
 						Username: "testuser",
 						Email:    "test@example.com",
 						Password: hashedPassword,
 					}, nil)
+				mockRepo.On("GetMFAMethods", mock.Anything, mock.AnythingOfType("uuid.UUID")).
+					Return([]models.MFAMethod{}, nil).Once()
+				mockRepo.On("GetUserRoles", mock.Anything, mock.AnythingOfType("uuid.UUID")).
+					Return([]models.Role{}, nil).Once()
 			},
 			expectedStatus: http.StatusOK,
 		},
@@ -134,7 +206,7 @@ func TestHandlerLogin(t *testing.T) {
 				Password: "wrongpassword",
 			},
 			mockSetup: func() {
-				mockRepo.On("GetUserByEmail", mock.Anything, "test@example.com").
+				mockRepo.On("GetUserByEmail", mock.Anything, "", "test@example.com").
 					Return(&models.User{
 						ID:       uuid.New(),
 						Username: "testuser",
@@ -144,6 +216,33 @@ func TestHandlerLogin(t *testing.T) {
 			},
 			expectedStatus: http.StatusUnauthorized,
 		},
+		{
+			name: "successful login by username",
+			requestBody: dto.LoginRequest{
+				Identifier: "testuser",
+				Password:   "password123",
+			},
+			mockSetup: func() {
+				mockRepo.On("GetUserByUsername", mock.Anything, "", "testuser").
+					Return(&models.User{
+						ID:       uuid.New(),
+						Username: "testuser",
+						Email:    "test@example.com",
+						Password: hashedPassword,
+					}, nil)
+				mockRepo.On("GetMFAMethods", mock.Anything, mock.AnythingOfType("uuid.UUID")).
+					Return([]models.MFAMethod{}, nil).Once()
+				mockRepo.On("GetUserRoles", mock.Anything, mock.AnythingOfType("uuid.UUID")).
+					Return([]models.Role{}, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing identifier",
+			requestBody:    dto.LoginRequest{Password: "password123"},
+			mockSetup:      func() {},
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name: "user not found",
 			requestBody: dto.LoginRequest{
@@ -151,7 +250,7 @@ func TestHandlerLogin(t *testing.T) {
 				Password: "password123",
 			},
 			mockSetup: func() {
-				mockRepo.On("GetUserByEmail", mock.Anything, "notfound@example.com").
+				mockRepo.On("GetUserByEmail", mock.Anything, "", "notfound@example.com").
 					Return(nil, errUserNotFound)
 			},
 			expectedStatus: http.StatusUnauthorized,
@@ -0,0 +1,92 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+)
+
+// writeIdentityError maps errors of linked identities to HTTP responses
+func writeIdentityError(w http.ResponseWriter, err error) {
+	if writeImpersonating(w, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, "user not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrProviderNotFound):
+		http.Error(w, "identity provider not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrIdentityNotFound):
+		http.Error(w, "identity not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrLastLoginMethod):
+		http.Error(w, "the last login method can't be unlinked", http.StatusConflict)
+	default:
+		http.Error(w, "identity operation failed", http.StatusInternalServerError)
+	}
+}
+
+// ListIdentities returns the login methods of the authenticated user
+func (h *Handler) ListIdentities(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	identities, err := h.service.Identities(r.Context(), userID)
+	if err != nil {
+		writeIdentityError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(identities)
+}
+
+// LinkIdentity starts linking the identity at the provider in the provider path value
+// to the authenticated user. The client opens the returned URL in the browser, and
+// the identity is linked when the provider redirects back to the login callback.
+func (h *Handler) LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	authURL, nonce, err := h.service.StartIdentityLink(r.Context(), userID, r.PathValue("provider"))
+	if err != nil {
+		writeIdentityError(w, err)
+		return
+	}
+
+	setOIDCCookie(w, nonce)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.IdentityLink{URL: authURL})
+}
+
+// UnlinkIdentity unlinks the identity in the id path value from the authenticated user
+func (h *Handler) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	identityID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid identity id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.UnlinkIdentity(r.Context(), userID, identityID); err != nil {
+		writeIdentityError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
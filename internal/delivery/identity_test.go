@@ -0,0 +1,149 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/oidc"
+	"github.com/AlexFox86/auth-service/internal/pkg/oidc/oidctest"
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandlerListIdentities(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID}, nil)
+	mockRepo.On("ListUserIdentities", mock.Anything, userID).Return([]models.UserIdentity{
+		{ID: uuid.New(), UserID: userID, Provider: "oidc:google", Subject: "12345"},
+	}, nil)
+	handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+	req := httptest.NewRequest("GET", "/me/identities", nil)
+	req = req.WithContext(context.WithValue(req.Context(), contextKeyUserID, userID.String()))
+	w := httptest.NewRecorder()
+
+	handler.ListIdentities(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var identities dto.UserIdentities
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&identities))
+	assert.False(t, identities.HasPassword)
+	assert.Len(t, identities.Identities, 1)
+}
+
+func TestHandlerLinkIdentity(t *testing.T) {
+	stub, err := oidctest.NewServer("client", "secret")
+	assert.NoError(t, err)
+	defer stub.Close()
+
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID}, nil)
+	handler := NewHandler(service.New(mockRepo, "secret", time.Hour,
+		service.WithOIDCProviders(oidc.NewProvider(stub.Config("stub"), nil))))
+
+	tests := []struct {
+		name           string
+		provider       string
+		expectedStatus int
+	}{
+		{name: "success", provider: "stub", expectedStatus: http.StatusOK},
+		{name: "unknown provider", provider: "other", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/me/identities/"+tt.provider, nil)
+			req.SetPathValue("provider", tt.provider)
+			req = req.WithContext(context.WithValue(req.Context(), contextKeyUserID, userID.String()))
+			w := httptest.NewRecorder()
+
+			handler.LinkIdentity(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if w.Code == http.StatusOK {
+				var link dto.IdentityLink
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&link))
+				assert.Contains(t, link.URL, stub.URL)
+				if cookies := w.Result().Cookies(); assert.Len(t, cookies, 1) {
+					assert.Equal(t, oidcCookie, cookies[0].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlerUnlinkIdentity(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	identity := models.UserIdentity{ID: uuid.MustParse("00000000-0000-0000-0000-000000000011"), UserID: userID, Provider: "oidc:google"}
+
+	tests := []struct {
+		name           string
+		identityID     string
+		mockSetup      func(*mockrepo.MockRepository)
+		expectedStatus int
+	}{
+		{
+			name:       "success",
+			identityID: identity.ID.String(),
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID, Password: "hash"}, nil)
+				m.On("ListUserIdentities", mock.Anything, userID).Return([]models.UserIdentity{identity}, nil)
+				m.On("DeleteUserIdentity", mock.Anything, userID, identity.ID).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:       "last login method",
+			identityID: identity.ID.String(),
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID}, nil)
+				m.On("ListUserIdentities", mock.Anything, userID).Return([]models.UserIdentity{identity}, nil)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:       "not found",
+			identityID: uuid.New().String(),
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID}, nil)
+				m.On("ListUserIdentities", mock.Anything, userID).Return([]models.UserIdentity{identity}, nil)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid id",
+			identityID:     "abc",
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+			req := httptest.NewRequest("DELETE", "/me/identities/"+tt.identityID, nil)
+			req.SetPathValue("id", tt.identityID)
+			req = req.WithContext(context.WithValue(req.Context(), contextKeyUserID, userID.String()))
+			w := httptest.NewRecorder()
+
+			handler.UnlinkIdentity(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+)
+
+// writeImpersonating writes 403 if the action isn't allowed with an impersonation token
+func writeImpersonating(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, service.ErrImpersonating) {
+		return false
+	}
+
+	http.Error(w, "not allowed while impersonating", http.StatusForbidden)
+	return true
+}
+
+// Impersonate returns a short-lived token of the user in the id path value
+// for the authenticated admin
+func (h *Handler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.ImpersonateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.Impersonate(r.Context(), adminID, userID, &req)
+	if err != nil {
+		if writeAccountBlocked(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrUserNotFound):
+			http.Error(w, "user not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrImpersonationNotAllowed):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, "impersonation failed", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,154 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+	"github.com/AlexFox86/auth-service/internal/repository/postgres"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandlerImpersonate(t *testing.T) {
+	adminID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	user := &models.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000002"), Username: "alex"}
+
+	tests := []struct {
+		name           string
+		userID         string
+		requestBody    any
+		mockSetup      func(*mockrepo.MockRepository)
+		expectedStatus int
+	}{
+		{
+			name:        "success",
+			userID:      user.ID.String(),
+			requestBody: dto.ImpersonateRequest{Reason: "ticket #42"},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+				m.On("GetUserRoles", mock.Anything, mock.Anything).Return([]models.Role{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing reason",
+			userID:         user.ID.String(),
+			requestBody:    dto.ImpersonateRequest{},
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "self",
+			userID:         adminID.String(),
+			requestBody:    dto.ImpersonateRequest{Reason: "ticket #42"},
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "invalid user id",
+			userID:         "invalid",
+			requestBody:    dto.ImpersonateRequest{Reason: "ticket #42"},
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest("POST", "/admin/users/"+tt.userID+"/impersonate", bytes.NewReader(body))
+			req = req.WithContext(context.WithValue(req.Context(), contextKeyUserID, adminID.String()))
+			req.SetPathValue("id", tt.userID)
+			w := httptest.NewRecorder()
+
+			handler.Impersonate(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestImpersonationToken(t *testing.T) {
+	adminID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	user := &models.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000002"), Username: "alex"}
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+	mockRepo.On("CreateAuditEvent", mock.Anything, mock.MatchedBy(func(event models.AuditEvent) bool {
+		return event.Type == models.AuditImpersonatedRequest && *event.ActorID == adminID && *event.UserID == user.ID
+	})).Return(nil)
+	svc := service.New(mockRepo, "secret", time.Hour, service.WithMailer(mailer.NewMemoryMailer()),
+		service.WithAuditSink(postgres.NewAuditSink(mockRepo)))
+	handler := NewHandler(svc)
+
+	impersonationToken, _ := token.GenerateToken(user, svc.JwtSecret(), time.Minute, token.WithActor(adminID.String()))
+
+	t.Run("request is audited", func(t *testing.T) {
+		var gotActor uuid.UUID
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotActor, _ = service.ActorFromContext(r.Context())
+		})
+
+		req := httptest.NewRequest("GET", "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+impersonationToken)
+		w := httptest.NewRecorder()
+
+		handler.AuthMiddleware(next).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, adminID, gotActor)
+		mockRepo.AssertNumberOfCalls(t, "CreateAuditEvent", 1)
+	})
+
+	t.Run("email change is blocked", func(t *testing.T) {
+		body, _ := json.Marshal(dto.ChangeEmailRequest{NewEmail: "new@example.com", Password: "password123"})
+		req := httptest.NewRequest("POST", "/me/email", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+impersonationToken)
+		w := httptest.NewRecorder()
+
+		handler.AuthMiddleware(http.HandlerFunc(handler.ChangeEmail)).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockRepo.AssertNumberOfCalls(t, "CreateAuditEvent", 2)
+	})
+
+	t.Run("profile update is blocked", func(t *testing.T) {
+		req := httptest.NewRequest("PATCH", "/me", bytes.NewBufferString(`{"display_name":"Alex"}`))
+		req.Header.Set("Authorization", "Bearer "+impersonationToken)
+		req.Header.Set("If-Match", `"1"`)
+		w := httptest.NewRecorder()
+
+		handler.AuthMiddleware(http.HandlerFunc(handler.UpdateProfile)).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("authentication level isn't satisfied", func(t *testing.T) {
+		impersonated, _ := token.GenerateToken(user, svc.JwtSecret(), time.Minute, token.WithActor(adminID.String()),
+			token.WithAuthContext([]string{token.AMRImpersonation}, time.Time{}))
+		req := httptest.NewRequest("GET", "/me/export", nil)
+		req.Header.Set("Authorization", "Bearer "+impersonated)
+		w := httptest.NewRecorder()
+
+		handler.RequireAuthLevel(token.ACRSingleFactor, 0)(http.HandlerFunc(handler.ExportData)).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
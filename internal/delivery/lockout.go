@@ -0,0 +1,44 @@
+package delivery
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+)
+
+// UnlockUser removes the login lockout of the user
+func (h *Handler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.UnlockUser(r.Context(), userID); err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "unlock failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeLocked writes 429 with Retry-After if logins are blocked
+func writeLocked(w http.ResponseWriter, err error) bool {
+	var locked *service.LockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+
+	retryAfter := ceilSeconds(time.Until(locked.Until))
+	w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+	http.Error(w, "too many failed login attempts, try again later", http.StatusTooManyRequests)
+	return true
+}
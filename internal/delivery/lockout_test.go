@@ -0,0 +1,99 @@
+package delivery
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandlerLoginLocked(t *testing.T) {
+	mockRepo := new(mockrepo.MockRepository)
+	until := time.Now().Add(time.Minute)
+	mockRepo.On("GetUserByEmail", mock.Anything, "", "test@example.com").Return(nil, assert.AnError)
+	mockRepo.On("GetLoginFailure", mock.Anything, mock.AnythingOfType("string")).
+		Return(&models.LoginFailure{Attempts: 10, LockedUntil: &until}, nil)
+	handler := NewHandler(service.New(mockRepo, "secret", time.Hour,
+		service.WithLockoutPolicy(service.DefaultLockoutPolicy())))
+
+	body, _ := json.Marshal(dto.LoginRequest{Email: "test@example.com", Password: "password123"})
+	req := httptest.NewRequest("POST", "/login", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Login(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	assert.NoError(t, err)
+	assert.InDelta(t, 60, retryAfter, 1)
+}
+
+func TestHandlerUnlockUser(t *testing.T) {
+	admin := &models.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001")}
+	user := &models.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000002")}
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByID", mock.Anything, "", admin.ID).Return(admin, nil)
+	mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+	mockRepo.On("ResetLoginFailures", mock.Anything, "user:"+user.ID.String()).Return(nil)
+	svc := service.New(mockRepo, "secret", time.Hour)
+	handler := NewHandler(svc)
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /admin/users/{id}/unlock",
+		handler.RequirePermission(models.PermissionUsersWrite)(http.HandlerFunc(handler.UnlockUser)))
+
+	adminToken, _ := token.GenerateToken(admin, svc.JwtSecret(), svc.TokenExpiry(),
+		token.WithAccess([]string{models.RoleAdmin}, []string{models.PermissionUsersWrite}))
+	userToken, _ := token.GenerateToken(user, svc.JwtSecret(), svc.TokenExpiry(), token.WithAccess(nil, nil))
+
+	tests := []struct {
+		name           string
+		token          string
+		userID         string
+		expectedStatus int
+	}{
+		{
+			name:           "success",
+			token:          adminToken,
+			userID:         user.ID.String(),
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "without permission",
+			token:          userToken,
+			userID:         user.ID.String(),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "invalid user id",
+			token:          adminToken,
+			userID:         "invalid",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/admin/users/"+tt.userID+"/unlock", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
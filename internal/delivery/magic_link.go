@@ -0,0 +1,87 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/service"
+)
+
+const magicLinkCookie = "magic_link_nonce"
+
+// RequestMagicLink sends a passwordless login link and binds it to the browser
+func (h *Handler) RequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	var req dto.MagicLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	nonce, err := h.service.RequestMagicLink(r.Context(), &req)
+	if err != nil {
+		http.Error(w, "failed to send login link", http.StatusInternalServerError)
+		return
+	}
+
+	// the path is "/" as the link of a tenant is under its /t/{tenant} prefix
+	http.SetCookie(w, &http.Cookie{
+		Name:     magicLinkCookie,
+		Value:    nonce,
+		Path:     "/",
+		MaxAge:   int(h.service.MagicLinkExpiry().Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.WriteHeader(http.StatusAccepted)
+	io.WriteString(w, "if the account exists, a login link has been sent")
+}
+
+// VerifyMagicLink logs the user in with the magic link
+func (h *Handler) VerifyMagicLink(w http.ResponseWriter, r *http.Request) {
+	linkToken := r.URL.Query().Get("token")
+	if linkToken == "" {
+		http.Error(w, "token required", http.StatusBadRequest)
+		return
+	}
+
+	var nonce string
+	if cookie, err := r.Cookie(magicLinkCookie); err == nil {
+		nonce = cookie.Value
+	}
+
+	resp, err := h.service.VerifyMagicLink(r.Context(), linkToken, nonce)
+	if err != nil {
+		if writeMFAChallenge(w, err) {
+			return
+		}
+		if writeAccountBlocked(w, err) {
+			return
+		}
+		if errors.Is(err, service.ErrInvalidMagicLink) {
+			http.Error(w, "invalid or expired link", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "login failed", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     magicLinkCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,92 @@
+package delivery
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandlerMagicLink(t *testing.T) {
+	user := &models.User{
+		ID:       uuid.New(),
+		Username: "testuser",
+		Email:    "test@example.com",
+	}
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+	mockRepo.On("CreateMagicLink", mock.Anything, mock.AnythingOfType("models.MagicLink")).Return(nil)
+	mockRepo.On("UseMagicLink", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(nil).Once()
+	mockRepo.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{}, nil).Once()
+	mockRepo.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{}, nil).Once()
+	mockRepo.On("SetEmailVerified", mock.Anything, user.ID).Return(nil).Once()
+	m := mailer.NewMemoryMailer()
+	service := service.New(mockRepo, "secret", time.Hour, service.WithMailer(m))
+	handler := NewHandler(service)
+
+	body, _ := json.Marshal(dto.MagicLinkRequest{Email: user.Email})
+	req := httptest.NewRequest("POST", "/login/magic-link", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.RequestMagicLink(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	cookies := w.Result().Cookies()
+	if !assert.Len(t, cookies, 1) {
+		return
+	}
+	assert.Equal(t, magicLinkCookie, cookies[0].Name)
+	assert.Equal(t, "/", cookies[0].Path)
+	assert.True(t, cookies[0].HttpOnly)
+
+	assert.Eventually(t, func() bool {
+		_, ok := m.Last()
+		return ok
+	}, time.Second, 10*time.Millisecond)
+	msg, _ := m.Last()
+	link := msg.Body[strings.Index(msg.Body, "/login/magic-link/verify"):]
+	link = strings.Fields(link)[0]
+	u, _ := url.Parse(link)
+
+	t.Run("missing token", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/login/magic-link/verify", nil)
+		w := httptest.NewRecorder()
+		handler.VerifyMagicLink(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("forwarded link", func(t *testing.T) {
+		req := httptest.NewRequest("GET", u.String(), nil)
+		w := httptest.NewRecorder()
+		handler.VerifyMagicLink(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("successful login", func(t *testing.T) {
+		req := httptest.NewRequest("GET", u.String(), nil)
+		req.AddCookie(cookies[0])
+		w := httptest.NewRecorder()
+		handler.VerifyMagicLink(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp dto.Response
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, user.ID, resp.User.ID)
+		assert.NotEmpty(t, resp.Token)
+	})
+
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,196 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+)
+
+// writeMFAChallenge writes the login challenge if the error requires a second factor
+func writeMFAChallenge(w http.ResponseWriter, err error) bool {
+	var mfaErr *service.MFARequiredError
+	if !errors.As(err, &mfaErr) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mfaErr.Challenge)
+	return true
+}
+
+// writeMFAError maps MFA errors to HTTP responses
+func writeMFAError(w http.ResponseWriter, err error) {
+	if writeAccountBlocked(w, err) || writeImpersonating(w, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, service.ErrInvalidMFAChallenge):
+		http.Error(w, "invalid or expired challenge", http.StatusUnauthorized)
+	case errors.Is(err, service.ErrInvalidOTP):
+		http.Error(w, "invalid code", http.StatusUnauthorized)
+	case errors.Is(err, service.ErrTooManyOTPAttempts):
+		http.Error(w, "too many attempts, request a new code", http.StatusTooManyRequests)
+	case errors.Is(err, service.ErrOTPRateLimited):
+		http.Error(w, "too many codes requested", http.StatusTooManyRequests)
+	case errors.Is(err, service.ErrMFAMethodNotFound):
+		http.Error(w, "mfa method not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrUnsupportedMFAMethod):
+		http.Error(w, "unsupported mfa method", http.StatusBadRequest)
+	case errors.Is(err, service.ErrNotOrgMember):
+		http.Error(w, "not a member of the organization", http.StatusForbidden)
+	default:
+		http.Error(w, "mfa failed", http.StatusInternalServerError)
+	}
+}
+
+// SendMFACode sends a one-time code to pass the login challenge
+func (h *Handler) SendMFACode(w http.ResponseWriter, r *http.Request) {
+	var req dto.MFASendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.SendLoginOTP(r.Context(), &req); err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyMFACode completes the login with a one-time code
+func (h *Handler) VerifyMFACode(w http.ResponseWriter, r *http.Request) {
+	var req dto.MFAVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.VerifyLoginOTP(r.Context(), &req)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ListMFAMethods returns the MFA methods of the authenticated user
+func (h *Handler) ListMFAMethods(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	methods, err := h.service.MFAMethods(r.Context(), userID)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(methods)
+}
+
+// AddMFAMethod enrolls a new MFA method for the authenticated user
+func (h *Handler) AddMFAMethod(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.AddMFAMethodRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	method, err := h.service.AddMFAMethod(r.Context(), userID, &req)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(method)
+}
+
+// ConfirmMFAMethod confirms the MFA method with the code sent to it
+func (h *Handler) ConfirmMFAMethod(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	methodID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid method id", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.OTPCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ConfirmMFAMethod(r.Context(), userID, methodID, req.Code); err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteMFAMethod deletes the MFA method of the authenticated user
+func (h *Handler) DeleteMFAMethod(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	methodID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid method id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteMFAMethod(r.Context(), userID, methodID); err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,140 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/sender"
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandlerLoginMFA(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("password123")
+	user := &models.User{
+		ID:       uuid.New(),
+		Email:    "test@example.com",
+		Password: hashedPassword,
+	}
+	method := models.MFAMethod{ID: uuid.New(), UserID: user.ID, Type: models.MFAMethodSMS, Confirmed: true}
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+	mockRepo.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{method}, nil)
+	mockRepo.On("CountOTPCodesSince", mock.Anything, user.ID, mock.AnythingOfType("time.Time")).Return(0, nil)
+	mockRepo.On("CreateOTPCode", mock.Anything, mock.AnythingOfType("models.OTPCode")).Return(nil)
+	s := sender.NewMemorySender()
+	handler := NewHandler(service.New(mockRepo, "secret", time.Hour, service.WithOTPSender(models.MFAMethodSMS, s)))
+
+	body, _ := json.Marshal(dto.LoginRequest{Email: user.Email, Password: "password123"})
+	req := httptest.NewRequest("POST", "/login", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.Login(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var challenge dto.MFAChallenge
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&challenge))
+	assert.True(t, challenge.MFARequired)
+	assert.Len(t, challenge.Methods, 1)
+
+	tests := []struct {
+		name           string
+		requestBody    any
+		expectedStatus int
+	}{
+		{
+			name:           "send code",
+			requestBody:    dto.MFASendRequest{ChallengeToken: challenge.ChallengeToken, MethodID: method.ID},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "unknown method",
+			requestBody:    dto.MFASendRequest{ChallengeToken: challenge.ChallengeToken, MethodID: uuid.New()},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid challenge",
+			requestBody:    dto.MFASendRequest{ChallengeToken: "invalid", MethodID: method.ID},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "validation error",
+			requestBody:    dto.MFASendRequest{MethodID: method.ID},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest("POST", "/login/mfa/send", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			handler.SendMFACode(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+
+	assert.Len(t, s.Messages(), 1)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestHandlerAddMFAMethod(t *testing.T) {
+	mockRepo := new(mockrepo.MockRepository)
+	handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+	userID := uuid.New()
+
+	tests := []struct {
+		name           string
+		userID         string
+		requestBody    any
+		expectedStatus int
+	}{
+		{
+			name:           "unauthorized",
+			requestBody:    dto.AddMFAMethodRequest{Type: models.MFAMethodEmail},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "phone required",
+			userID:         userID.String(),
+			requestBody:    dto.AddMFAMethodRequest{Type: models.MFAMethodSMS},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unsupported method",
+			userID:         userID.String(),
+			requestBody:    dto.AddMFAMethodRequest{Type: models.MFAMethodSMS, Destination: "+10000000000"},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest("POST", "/me/mfa", bytes.NewReader(body))
+			if tt.userID != "" {
+				req = req.WithContext(context.WithValue(req.Context(), contextKeyUserID, tt.userID))
+			}
+			w := httptest.NewRecorder()
+
+			handler.AddMFAMethod(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+
+	mockRepo.AssertExpectations(t)
+}
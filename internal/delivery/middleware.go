@@ -2,39 +2,236 @@ package delivery
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 )
 
 type contextKey string
 
 const (
 	contextKeyUserID contextKey = "userID"
+	contextKeyOrgID  contextKey = "orgID"
 )
 
 // AuthMiddleware verifies the JWT token in the Authorization header
 func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "authorization header required", http.StatusUnauthorized)
+		claims, ok := h.authenticate(w, r)
+		if !ok {
 			return
 		}
 
-		claims, err := token.ValidateToken(authHeader, h.service.JwtSecret())
-		if err != nil {
-			http.Error(w, "invalid token", http.StatusUnauthorized)
+		ctx := authContext(r.Context(), claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireAuthLevel is a variant of AuthMiddleware which also requires the user
+// to have authenticated with at least the acr level no longer than maxAge ago.
+// Empty acr or zero maxAge disable the corresponding check. Otherwise
+// it responds with the insufficient_user_authentication challenge (RFC 9470),
+// and the client should get an elevated token from /step-up.
+func (h *Handler) RequireAuthLevel(acr string, maxAge time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := h.authenticate(w, r)
+			if !ok {
+				return
+			}
+
+			if acr != "" {
+				claimACR, _ := claims["acr"].(string)
+				if !token.ACRSatisfies(claimACR, acr) {
+					insufficientAuthentication(w, acr, maxAge, "a stronger authentication is required")
+					return
+				}
+			}
+
+			if maxAge > 0 {
+				authTime, ok := token.AuthTime(claims)
+				if !ok || time.Since(authTime) > maxAge {
+					insufficientAuthentication(w, acr, maxAge, "a more recent authentication is required")
+					return
+				}
+			}
+
+			ctx := authContext(r.Context(), claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireVerifiedEmail is a variant of AuthMiddleware which also requires
+// the user's email to be verified
+func (h *Handler) RequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := h.authenticate(w, r)
+		if !ok {
 			return
 		}
 
-		userID, ok := claims["sub"].(string)
+		if verified, _ := claims["email_verified"].(bool); !verified {
+			http.Error(w, "email not verified", http.StatusForbidden)
+			return
+		}
+
+		ctx := authContext(r.Context(), claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequirePermission is a variant of AuthMiddleware which also requires
+// the token to grant all the permissions
+func (h *Handler) RequirePermission(permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := h.authenticate(w, r)
+			if !ok {
+				return
+			}
+
+			if !token.HasPermissions(claims, permissions...) {
+				http.Error(w, "insufficient permissions", http.StatusForbidden)
+				return
+			}
+
+			ctx := authContext(r.Context(), claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireOrg is a variant of AuthMiddleware which also requires the token
+// to be scoped to the organization in the org_id path value
+func (h *Handler) RequireOrg(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := h.authenticate(w, r)
 		if !ok {
-			http.Error(w, "invalid token claims", http.StatusUnauthorized)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), contextKeyUserID, userID)
+		if orgID, _ := claims["org_id"].(string); orgID == "" || orgID != r.PathValue("org_id") {
+			http.Error(w, "token is not scoped to the organization", http.StatusForbidden)
+			return
+		}
+
+		ctx := authContext(r.Context(), claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// authenticate verifies the JWT token or the API key in the Authorization header
+// and that the account can be used, and writes the error response if not
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) (jwt.MapClaims, bool) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		http.Error(w, "authorization header required", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	if service.IsAPIKey(authHeader) {
+		claims, err := h.service.AuthenticateAPIKey(r.Context(), authHeader)
+		if err != nil {
+			http.Error(w, "invalid api key", http.StatusUnauthorized)
+			return nil, false
+		}
+		return claims, true
+	}
+
+	claims, err := token.ValidateToken(authHeader, h.service.SigningKey(r.Context()))
+	if err != nil {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	sub, _ := claims["sub"].(string)
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		http.Error(w, "invalid token claims", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	// the token stays valid until it expires, but the account may be blocked since it was issued
+	if err := h.service.CheckAccount(r.Context(), userID); err != nil {
+		if !writeAccountBlocked(w, err) {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+		}
+		return nil, false
+	}
+
+	if actor, ok := token.Actor(claims); ok {
+		actorID, err := uuid.Parse(actor)
+		if err != nil {
+			http.Error(w, "invalid token claims", http.StatusUnauthorized)
+			return nil, false
+		}
+		// everything done on behalf of the user is recorded
+		h.service.AuditImpersonatedRequest(service.ContextWithActor(r.Context(), actorID), userID, r.Method+" "+r.URL.Path)
+	}
+
+	return claims, true
+}
+
+// authContext returns the context with the user authenticated by the claims
+// and the admin impersonating the user, if any
+func authContext(ctx context.Context, claims jwt.MapClaims) context.Context {
+	ctx = context.WithValue(ctx, contextKeyUserID, claims["sub"])
+	if orgID, ok := claims["org_id"].(string); ok {
+		ctx = context.WithValue(ctx, contextKeyOrgID, orgID)
+	}
+	if actor, ok := token.Actor(claims); ok {
+		if actorID, err := uuid.Parse(actor); err == nil {
+			ctx = service.ContextWithActor(ctx, actorID)
+		}
+	}
+	return ctx
+}
+
+// insufficientAuthentication writes the step-up authentication challenge
+func insufficientAuthentication(w http.ResponseWriter, acr string, maxAge time.Duration, description string) {
+	params := []string{
+		`error="insufficient_user_authentication"`,
+		fmt.Sprintf("error_description=%q", description),
+	}
+	if acr != "" {
+		params = append(params, fmt.Sprintf("acr_values=%q", acr))
+	}
+	if maxAge > 0 {
+		params = append(params, fmt.Sprintf("max_age=%d", int(maxAge.Seconds())))
+	}
+
+	w.Header().Set("WWW-Authenticate", "Bearer "+strings.Join(params, ", "))
+	http.Error(w, description, http.StatusUnauthorized)
+}
+
+// orgIDFromContext returns the organization the token authenticated by AuthMiddleware
+// is scoped to, uuid.Nil if it isn't
+func orgIDFromContext(ctx context.Context) uuid.UUID {
+	orgID, _ := ctx.Value(contextKeyOrgID).(string)
+	id, err := uuid.Parse(orgID)
+	if err != nil {
+		return uuid.Nil
+	}
+	return id
+}
+
+// userIDFromContext returns the ID of the user authenticated by AuthMiddleware
+func userIDFromContext(ctx context.Context) (uuid.UUID, bool) {
+	userID, ok := ctx.Value(contextKeyUserID).(string)
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return id, true
+}
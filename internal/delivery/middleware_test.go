@@ -10,21 +10,31 @@ import (
 	"github.com/AlexFox86/auth-service/internal/service"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
 	"github.com/AlexFox86/auth-service/internal/pkg/token"
 	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
 )
 
 func TestAuthMiddleware(t *testing.T) {
-	mockRepo := new(mockrepo.MockRepository)
-	service := service.New(mockRepo, "secret", time.Hour)
-	handler := NewHandler(service)
-
 	// Создаем тестовый токен
 	user := &models.User{
 		ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
 		Username: "testuser",
 	}
+	suspended := &models.User{
+		ID:     uuid.MustParse("00000000-0000-0000-0000-000000000002"),
+		Status: models.UserStatusSuspended,
+	}
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+	mockRepo.On("GetUserByID", mock.Anything, "", suspended.ID).Return(suspended, nil)
+	service := service.New(mockRepo, "secret", time.Hour)
+	handler := NewHandler(service)
+
+	suspendedToken, _ := token.GenerateToken(suspended, service.JwtSecret(), service.TokenExpiry())
 	token, _ := token.GenerateToken(user, service.JwtSecret(), service.TokenExpiry())
 
 	tests := []struct {
@@ -53,6 +63,13 @@ func TestAuthMiddleware(t *testing.T) {
 			},
 			expectedStatus: http.StatusUnauthorized,
 		},
+		{
+			name: "suspended account",
+			setupRequest: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer "+suspendedToken)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
 	}
 
 	for _, tt := range tests {
@@ -80,3 +97,257 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestRequireAuthLevel(t *testing.T) {
+	user := &models.User{
+		ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		Username: "testuser",
+	}
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+	service := service.New(mockRepo, "secret", time.Hour)
+	handler := NewHandler(service)
+	newToken := func(amr []string, authTime time.Time) string {
+		tokenString, _ := token.GenerateToken(user, service.JwtSecret(), time.Hour, token.WithAuthContext(amr, authTime))
+		return tokenString
+	}
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+		expectedHeader string
+	}{
+		{
+			name:           "recent mfa",
+			token:          newToken([]string{token.AMRPassword, token.AMROTP, token.AMRMFA}, time.Now()),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "single factor",
+			token:          newToken([]string{token.AMRPassword}, time.Now()),
+			expectedStatus: http.StatusUnauthorized,
+			expectedHeader: `Bearer error="insufficient_user_authentication", ` +
+				`error_description="a stronger authentication is required", acr_values="aal2", max_age=300`,
+		},
+		{
+			name:           "old authentication",
+			token:          newToken([]string{token.AMRPassword, token.AMROTP, token.AMRMFA}, time.Now().Add(-time.Hour)),
+			expectedStatus: http.StatusUnauthorized,
+			expectedHeader: `Bearer error="insufficient_user_authentication", ` +
+				`error_description="a more recent authentication is required", acr_values="aal2", max_age=300`,
+		},
+		{
+			name: "no auth context",
+			token: func() string {
+				tokenString, _ := token.GenerateToken(user, service.JwtSecret(), time.Hour)
+				return tokenString
+			}(),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "invalid token",
+			token:          "invalid.token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/payments", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+
+			testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userID, ok := r.Context().Value(contextKeyUserID).(string)
+				assert.True(t, ok)
+				assert.Equal(t, user.ID.String(), userID)
+				w.WriteHeader(http.StatusOK)
+			})
+
+			handler.RequireAuthLevel(token.ACRMultiFactor, 5*time.Minute)(testHandler).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedHeader != "" {
+				assert.Equal(t, tt.expectedHeader, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	user := &models.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001")}
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+	service := service.New(mockRepo, "secret", time.Hour)
+	handler := NewHandler(service)
+	newToken := func(permissions ...string) string {
+		tokenString, _ := token.GenerateToken(user, service.JwtSecret(), time.Hour, token.WithAccess(nil, permissions))
+		return tokenString
+	}
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{
+			name:           "all permissions",
+			token:          newToken(models.PermissionUsersRead, models.PermissionUsersWrite, models.PermissionAuditRead),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing permission",
+			token:          newToken(models.PermissionUsersRead),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "no permissions claim",
+			token:          func() string { s, _ := token.GenerateToken(user, service.JwtSecret(), time.Hour); return s }(),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "invalid token",
+			token:          "invalid.token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("DELETE", "/admin/users/1", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+
+			testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userID, _ := userIDFromContext(r.Context())
+				assert.Equal(t, user.ID, userID)
+				w.WriteHeader(http.StatusOK)
+			})
+
+			handler.RequirePermission(models.PermissionUsersRead, models.PermissionUsersWrite)(testHandler).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestRequireOrg(t *testing.T) {
+	user := &models.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001")}
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+	service := service.New(mockRepo, "secret", time.Hour)
+	handler := NewHandler(service)
+	orgID := uuid.MustParse("00000000-0000-0000-0000-0000000000a1")
+	newToken := func(opts ...token.Option) string {
+		tokenString, _ := token.GenerateToken(user, service.JwtSecret(), time.Hour, opts...)
+		return tokenString
+	}
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{
+			name:           "scoped to the organization",
+			token:          newToken(token.WithOrg(orgID.String(), models.OrgRoleMember)),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "scoped to another organization",
+			token:          newToken(token.WithOrg(uuid.NewString(), models.OrgRoleOwner)),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "unscoped token",
+			token:          newToken(),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "invalid token",
+			token:          "invalid.token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.Handle("GET /orgs/{org_id}/members", handler.RequireOrg(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userID, _ := userIDFromContext(r.Context())
+				assert.Equal(t, user.ID, userID)
+				w.WriteHeader(http.StatusOK)
+			})))
+
+			req := httptest.NewRequest("GET", "/orgs/"+orgID.String()+"/members", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestAuthMiddlewareAPIKey(t *testing.T) {
+	user := &models.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001")}
+	key := service.APIKeyPrefix + "secret"
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetAPIKeyByHash", mock.Anything, crypto.HashToken(key)).
+		Return(&models.APIKey{UserID: user.ID, Scopes: []string{}}, nil)
+	mockRepo.On("GetAPIKeyByHash", mock.Anything, mock.Anything).Return(nil, models.ErrNotFound)
+	mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+	mockRepo.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{}, nil)
+	mockRepo.On("TouchAPIKey", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := userIDFromContext(r.Context())
+		assert.Equal(t, user.ID, userID)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		key            string
+		middleware     func(http.Handler) http.Handler
+		expectedStatus int
+	}{
+		{
+			name:           "valid key",
+			key:            key,
+			middleware:     handler.AuthMiddleware,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown key",
+			key:            service.APIKeyPrefix + "unknown",
+			middleware:     handler.AuthMiddleware,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "session required",
+			key:            key,
+			middleware:     handler.RequireAuthLevel(token.ACRSingleFactor, 0),
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/protected", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.key)
+			w := httptest.NewRecorder()
+
+			tt.middleware(testHandler).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
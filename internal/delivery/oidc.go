@@ -0,0 +1,101 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/AlexFox86/auth-service/internal/service"
+)
+
+const oidcCookie = "oidc_nonce"
+
+// writeExternalLoginError maps errors of logins with external identity providers to HTTP responses
+func writeExternalLoginError(w http.ResponseWriter, err error) {
+	if writeMFAChallenge(w, err) || writeAccountBlocked(w, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, service.ErrProviderNotFound):
+		http.Error(w, "identity provider not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidOIDCLogin), errors.Is(err, service.ErrInvalidSAMLLogin):
+		http.Error(w, "invalid or expired login", http.StatusUnauthorized)
+	case errors.Is(err, service.ErrIdentityExists):
+		writeConflict(w, err)
+	case errors.Is(err, service.ErrExternalEmailNotVerified), errors.Is(err, service.ErrInviteRequired),
+		errors.Is(err, service.ErrEmailDomainNotAllowed), errors.Is(err, service.ErrDisposableEmail):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, "login failed", http.StatusInternalServerError)
+	}
+}
+
+// ListOIDCProviders returns the names of the identity providers users can log in with
+func (h *Handler) ListOIDCProviders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.service.OIDCProviders())
+}
+
+// StartOIDCLogin redirects to the login page of the provider in the provider path value
+// and binds the login to the browser
+func (h *Handler) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	authURL, nonce, err := h.service.StartOIDCLogin(r.Context(), r.PathValue("provider"))
+	if err != nil {
+		writeExternalLoginError(w, err)
+		return
+	}
+
+	setOIDCCookie(w, nonce)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// setOIDCCookie binds the login with the provider to the browser, the cookie
+// is sent with the provider's callback, which may be under a tenant's prefix
+func setOIDCCookie(w http.ResponseWriter, nonce string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookie,
+		Value:    nonce,
+		Path:     "/",
+		MaxAge:   int(service.OIDCLoginExpiry.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// OIDCCallback logs the user in with the code the provider redirected back with
+func (h *Handler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if errCode := query.Get("error"); errCode != "" {
+		http.Error(w, "login with the identity provider failed: "+errCode, http.StatusUnauthorized)
+		return
+	}
+	state, code := query.Get("state"), query.Get("code")
+	if state == "" || code == "" {
+		http.Error(w, "state and code required", http.StatusBadRequest)
+		return
+	}
+
+	var nonce string
+	if cookie, err := r.Cookie(oidcCookie); err == nil {
+		nonce = cookie.Value
+	}
+
+	resp, err := h.service.CompleteOIDCLogin(r.Context(), r.PathValue("provider"), state, code, nonce)
+	if err != nil {
+		writeExternalLoginError(w, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
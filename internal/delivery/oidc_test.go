@@ -0,0 +1,90 @@
+package delivery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/oidc"
+	"github.com/AlexFox86/auth-service/internal/pkg/oidc/oidctest"
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandlerOIDCLogin(t *testing.T) {
+	stub, err := oidctest.NewServer("client", "secret")
+	assert.NoError(t, err)
+	defer stub.Close()
+
+	user := &models.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), Email: "alex@example.com"}
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserIdentity", mock.Anything, "", "oidc:stub", "12345").Return(&models.UserIdentity{UserID: user.ID}, nil)
+	mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+	mockRepo.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{}, nil)
+	mockRepo.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{}, nil)
+	handler := NewHandler(service.New(mockRepo, "secret", time.Hour,
+		service.WithOIDCProviders(oidc.NewProvider(stub.Config("stub"), nil))))
+
+	start := func(provider string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/login/oidc/"+provider, nil)
+		req.SetPathValue("provider", provider)
+		w := httptest.NewRecorder()
+		handler.StartOIDCLogin(w, req)
+		return w
+	}
+	callback := func(query string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/login/oidc/stub/callback?"+query, nil)
+		req.SetPathValue("provider", "stub")
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		handler.OIDCCallback(w, req)
+		return w
+	}
+
+	t.Run("success", func(t *testing.T) {
+		w := start("stub")
+		assert.Equal(t, http.StatusFound, w.Code)
+		if cookies := w.Result().Cookies(); assert.Len(t, cookies, 1) {
+			assert.Equal(t, "/", cookies[0].Path)
+		}
+		authURL := w.Header().Get("Location")
+		u, _ := url.Parse(authURL)
+
+		code, err := stub.Authorize(authURL, map[string]any{"sub": "12345", "email": user.Email, "email_verified": true})
+		assert.NoError(t, err)
+
+		query := url.Values{"state": {u.Query().Get("state")}, "code": {code}}
+		w = callback(query.Encode(), w.Result().Cookies())
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"token"`)
+	})
+
+	t.Run("without the browser's cookie", func(t *testing.T) {
+		authURL := start("stub").Header().Get("Location")
+		u, _ := url.Parse(authURL)
+		code, _ := stub.Authorize(authURL, map[string]any{"sub": "12345", "email": user.Email, "email_verified": true})
+
+		query := url.Values{"state": {u.Query().Get("state")}, "code": {code}}
+		assert.Equal(t, http.StatusUnauthorized, callback(query.Encode(), nil).Code)
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, callback("error=access_denied", nil).Code)
+	})
+
+	t.Run("missing code", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, callback("state=abc", nil).Code)
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, start("unknown").Code)
+	})
+}
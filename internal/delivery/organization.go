@@ -0,0 +1,293 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+)
+
+// writeOrgError maps organization errors to HTTP responses
+func writeOrgError(w http.ResponseWriter, err error) {
+	if writeConflict(w, err) || writeAccountBlocked(w, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, service.ErrOrgNotFound):
+		http.Error(w, "organization not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrMemberNotFound):
+		http.Error(w, "member not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrOrgAccessDenied):
+		http.Error(w, "organization access denied", http.StatusForbidden)
+	case errors.Is(err, service.ErrNotOrgMember):
+		http.Error(w, "not a member of the organization", http.StatusForbidden)
+	case errors.Is(err, service.ErrLastOwner):
+		http.Error(w, "organization must have at least one owner", http.StatusConflict)
+	case errors.Is(err, service.ErrInvalidSlug):
+		http.Error(w, "slug must be lowercase letters and digits separated by hyphens", http.StatusBadRequest)
+	case errors.Is(err, service.ErrInvalidInvitation):
+		http.Error(w, "invalid or expired invitation", http.StatusBadRequest)
+	case errors.Is(err, service.ErrInvalidCredentials):
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+	default:
+		http.Error(w, "organization operation failed", http.StatusInternalServerError)
+	}
+}
+
+// CreateOrganization creates an organization owned by the authenticated user
+func (h *Handler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.CreateOrgRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	org, err := h.service.CreateOrganization(r.Context(), userID, &req)
+	if err != nil {
+		writeOrgError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(org)
+}
+
+// ListUserOrganizations returns the organizations of the authenticated user
+func (h *Handler) ListUserOrganizations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	orgs, err := h.service.UserOrganizations(r.Context(), userID)
+	if err != nil {
+		writeOrgError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(orgs)
+}
+
+// SwitchOrg reissues the token from the Authorization header scoped to another organization
+func (h *Handler) SwitchOrg(w http.ResponseWriter, r *http.Request) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		http.Error(w, "authorization header required", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.SwitchOrgRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.SwitchOrg(r.Context(), authHeader, req.OrgID)
+	if err != nil {
+		writeOrgError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ListMembers returns the members of the organization
+func (h *Handler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	actorID, orgID, ok := orgRequest(w, r)
+	if !ok {
+		return
+	}
+
+	members, err := h.service.Members(r.Context(), actorID, orgID)
+	if err != nil {
+		writeOrgError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
+
+// UpdateMember changes the role of the organization member
+func (h *Handler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	actorID, orgID, ok := orgRequest(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("user_id"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.UpdateMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.UpdateMemberRole(r.Context(), actorID, orgID, userID, req.Role); err != nil {
+		writeOrgError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveMember removes the member from the organization
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	actorID, orgID, ok := orgRequest(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("user_id"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RemoveMember(r.Context(), actorID, orgID, userID); err != nil {
+		writeOrgError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// InviteMember sends an invitation to join the organization
+func (h *Handler) InviteMember(w http.ResponseWriter, r *http.Request) {
+	actorID, orgID, ok := orgRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.InviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	invitation, err := h.service.InviteMember(r.Context(), actorID, orgID, &req)
+	if err != nil {
+		writeOrgError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invitation)
+}
+
+// ListInvitations returns the pending invitations to the organization
+func (h *Handler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	actorID, orgID, ok := orgRequest(w, r)
+	if !ok {
+		return
+	}
+
+	invitations, err := h.service.Invitations(r.Context(), actorID, orgID)
+	if err != nil {
+		writeOrgError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitations)
+}
+
+// RevokeInvitation revokes the pending invitation
+func (h *Handler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	actorID, orgID, ok := orgRequest(w, r)
+	if !ok {
+		return
+	}
+
+	invitationID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid invitation id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RevokeInvitation(r.Context(), actorID, orgID, invitationID); err != nil {
+		writeOrgError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AcceptInvitation adds the authenticated user to the organization of the invitation
+func (h *Handler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.AcceptInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	invitation, err := h.service.AcceptInvitation(r.Context(), userID, &req)
+	if err != nil {
+		writeOrgError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitation)
+}
+
+// orgRequest returns the authenticated user and the organization from the path
+func orgRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	actorID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	orgID, err := uuid.Parse(r.PathValue("org_id"))
+	if err != nil {
+		http.Error(w, "invalid organization id", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return actorID, orgID, true
+}
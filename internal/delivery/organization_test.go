@@ -0,0 +1,191 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandlerCreateOrganization(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+	tests := []struct {
+		name           string
+		requestBody    any
+		mockSetup      func(*mockrepo.MockRepository)
+		expectedStatus int
+	}{
+		{
+			name:        "success",
+			requestBody: dto.CreateOrgRequest{Name: "Acme", Slug: "acme"},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("CreateOrganization", mock.Anything, mock.AnythingOfType("models.Organization"), userID).Return(nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:        "slug exists",
+			requestBody: dto.CreateOrgRequest{Name: "Acme", Slug: "acme"},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("CreateOrganization", mock.Anything, mock.AnythingOfType("models.Organization"), userID).
+					Return(models.ErrSlugExists)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "invalid slug",
+			requestBody:    dto.CreateOrgRequest{Name: "Acme", Slug: "acme/inc"},
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing name",
+			requestBody:    dto.CreateOrgRequest{Slug: "acme"},
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest("POST", "/orgs", bytes.NewReader(body))
+			req = req.WithContext(context.WithValue(req.Context(), contextKeyUserID, userID.String()))
+			w := httptest.NewRecorder()
+
+			handler.CreateOrganization(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandlerUpdateMember(t *testing.T) {
+	orgID := uuid.MustParse("00000000-0000-0000-0000-0000000000a1")
+	actorID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000002")
+
+	tests := []struct {
+		name           string
+		userID         string
+		requestBody    any
+		mockSetup      func(*mockrepo.MockRepository)
+		expectedStatus int
+	}{
+		{
+			name:        "success",
+			userID:      userID.String(),
+			requestBody: dto.UpdateMemberRequest{Role: models.OrgRoleAdmin},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetMembership", mock.Anything, orgID, actorID).
+					Return(&models.Membership{OrgID: orgID, UserID: actorID, Role: models.OrgRoleOwner}, nil)
+				m.On("GetMembership", mock.Anything, orgID, userID).
+					Return(&models.Membership{OrgID: orgID, UserID: userID, Role: models.OrgRoleMember}, nil)
+				m.On("UpdateMemberRole", mock.Anything, orgID, userID, models.OrgRoleAdmin).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:        "access denied",
+			userID:      userID.String(),
+			requestBody: dto.UpdateMemberRequest{Role: models.OrgRoleAdmin},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetMembership", mock.Anything, orgID, actorID).
+					Return(&models.Membership{OrgID: orgID, UserID: actorID, Role: models.OrgRoleMember}, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:        "last owner",
+			userID:      actorID.String(),
+			requestBody: dto.UpdateMemberRequest{Role: models.OrgRoleMember},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetMembership", mock.Anything, orgID, actorID).
+					Return(&models.Membership{OrgID: orgID, UserID: actorID, Role: models.OrgRoleOwner}, nil)
+				m.On("CountMembersWithRole", mock.Anything, orgID, models.OrgRoleOwner).Return(1, nil)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:        "member not found",
+			userID:      userID.String(),
+			requestBody: dto.UpdateMemberRequest{Role: models.OrgRoleAdmin},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetMembership", mock.Anything, orgID, actorID).
+					Return(&models.Membership{OrgID: orgID, UserID: actorID, Role: models.OrgRoleOwner}, nil)
+				m.On("GetMembership", mock.Anything, orgID, userID).Return(nil, models.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid role",
+			userID:         userID.String(),
+			requestBody:    dto.UpdateMemberRequest{Role: "superuser"},
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid user id",
+			userID:         "invalid",
+			requestBody:    dto.UpdateMemberRequest{Role: models.OrgRoleAdmin},
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest("PUT", "/orgs/"+orgID.String()+"/members/"+tt.userID, bytes.NewReader(body))
+			req.SetPathValue("org_id", orgID.String())
+			req.SetPathValue("user_id", tt.userID)
+			req = req.WithContext(context.WithValue(req.Context(), contextKeyUserID, actorID.String()))
+			w := httptest.NewRecorder()
+
+			handler.UpdateMember(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandlerListMembersNotMember(t *testing.T) {
+	orgID := uuid.MustParse("00000000-0000-0000-0000-0000000000a1")
+	actorID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetMembership", mock.Anything, orgID, actorID).Return(nil, models.ErrNotFound)
+	handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+	req := httptest.NewRequest("GET", "/orgs/"+orgID.String()+"/members", nil)
+	req.SetPathValue("org_id", orgID.String())
+	req = req.WithContext(context.WithValue(req.Context(), contextKeyUserID, actorID.String()))
+	w := httptest.NewRecorder()
+
+	handler.ListMembers(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,115 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/service"
+)
+
+// writeProfileError maps profile errors to HTTP responses
+func writeProfileError(w http.ResponseWriter, err error) {
+	if writeConflict(w, err) || writeImpersonating(w, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, "user not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrUserModified):
+		http.Error(w, "profile was modified", http.StatusPreconditionFailed)
+	case errors.Is(err, service.ErrInvalidMetadata):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrUsernameReserved):
+		writeFieldError(w, err, "username")
+	default:
+		http.Error(w, "profile update failed", http.StatusInternalServerError)
+	}
+}
+
+// profileETag returns the ETag of the profile version
+func profileETag(user *models.User) string {
+	return `"` + strconv.FormatInt(user.UpdatedAt.UnixMicro(), 10) + `"`
+}
+
+// parseProfileETag returns the profile version of the If-Match ETag
+func parseProfileETag(etag string) (time.Time, bool) {
+	etag = strings.Trim(strings.TrimPrefix(strings.TrimSpace(etag), "W/"), `"`)
+	micros, err := strconv.ParseInt(etag, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMicro(micros), true
+}
+
+// writeProfile writes the profile with its ETag
+func writeProfile(w http.ResponseWriter, user *models.User) {
+	w.Header().Set("ETag", profileETag(user))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// GetProfile returns the profile of the authenticated user
+func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.service.Profile(r.Context(), userID)
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+
+	writeProfile(w, user)
+}
+
+// UpdateProfile changes the profile of the authenticated user. The version the changes
+// are based on is taken from the If-Match header or the updated_at field of the body.
+func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var version time.Time
+	if etag := r.Header.Get("If-Match"); etag != "" {
+		if version, ok = parseProfileETag(etag); !ok {
+			http.Error(w, "invalid If-Match", http.StatusBadRequest)
+			return
+		}
+	} else if req.UpdatedAt != nil {
+		version = *req.UpdatedAt
+	} else {
+		http.Error(w, "If-Match or updated_at is required", http.StatusPreconditionRequired)
+		return
+	}
+
+	user, err := h.service.UpdateProfile(r.Context(), userID, version, &req)
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+
+	writeProfile(w, user)
+}
@@ -0,0 +1,144 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandlerGetProfile(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	updatedAt := time.UnixMicro(1704110400123456)
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByID", mock.Anything, "", userID).
+		Return(&models.User{ID: userID, Username: "alex", UpdatedAt: updatedAt}, nil)
+	handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+	req := httptest.NewRequest("GET", "/me", nil)
+	req = req.WithContext(context.WithValue(req.Context(), contextKeyUserID, userID.String()))
+	w := httptest.NewRecorder()
+
+	handler.GetProfile(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"1704110400123456"`, w.Header().Get("ETag"))
+	assert.Contains(t, w.Body.String(), `"username":"alex"`)
+}
+
+func TestHandlerUpdateProfile(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	updatedAt := time.UnixMicro(1704110400123456)
+	newUser := func() *models.User {
+		return &models.User{ID: userID, Username: "alex", UpdatedAt: updatedAt}
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		ifMatch        string
+		mockSetup      func(*mockrepo.MockRepository)
+		expectedStatus int
+	}{
+		{
+			name:    "if-match",
+			body:    `{"display_name":"Alex Fox","locale":"de-DE"}`,
+			ifMatch: `W/"1704110400123456"`,
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByID", mock.Anything, "", userID).Return(newUser(), nil)
+				m.On("UpdateProfile", mock.Anything, mock.AnythingOfType("*models.User"), updatedAt).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "updated_at in body",
+			body: `{"timezone":"Europe/Berlin","updated_at":"` + updatedAt.Format(time.RFC3339Nano) + `"}`,
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByID", mock.Anything, "", userID).Return(newUser(), nil)
+				m.On("UpdateProfile", mock.Anything, mock.AnythingOfType("*models.User"), mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "precondition required",
+			body:           `{"display_name":"Alex Fox"}`,
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusPreconditionRequired,
+		},
+		{
+			name:    "stale version",
+			body:    `{"display_name":"Alex Fox"}`,
+			ifMatch: `"1704110400000000"`,
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByID", mock.Anything, "", userID).Return(newUser(), nil)
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:    "username taken",
+			body:    `{"username":"alexander"}`,
+			ifMatch: `"1704110400123456"`,
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByID", mock.Anything, "", userID).Return(newUser(), nil)
+				m.On("UpdateProfile", mock.Anything, mock.Anything, updatedAt).Return(models.ErrUsernameExists)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:    "invalid metadata",
+			body:    `{"metadata":[1,2]}`,
+			ifMatch: `"1704110400123456"`,
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByID", mock.Anything, "", userID).Return(newUser(), nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid timezone",
+			body:           `{"timezone":"Mars/Olympus"}`,
+			ifMatch:        `"1704110400123456"`,
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid if-match",
+			body:           `{"display_name":"Alex Fox"}`,
+			ifMatch:        `"abc"`,
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+			req := httptest.NewRequest("PATCH", "/me", bytes.NewBufferString(tt.body))
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), contextKeyUserID, userID.String()))
+			w := httptest.NewRecorder()
+
+			handler.UpdateProfile(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if w.Code == http.StatusOK {
+				assert.NotEmpty(t, w.Header().Get("ETag"))
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package delivery
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/ratelimit"
+)
+
+// maxKeyBodySize limits how much of the body ByEmail reads
+const maxKeyBodySize = 1 << 20
+
+// KeyFunc returns the key requests are limited by. An empty key skips the limit.
+type KeyFunc func(r *http.Request) string
+
+// RateLimitRule limits the requests with the same key
+type RateLimitRule struct {
+	// Name separates the buckets of the rules, e.g. "login:ip"
+	Name  string
+	Key   KeyFunc
+	Limit ratelimit.Limit
+}
+
+// RateLimit limits the requests by the rules, each with its own token bucket.
+// It sets the RateLimit-* headers of the most restrictive rule and responds
+// with 429 and Retry-After when any of the buckets is empty.
+func RateLimit(store ratelimit.Store, rules ...RateLimitRule) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var (
+				current ratelimit.Result
+				found   bool
+			)
+
+			for _, rule := range rules {
+				key := rule.Key(r)
+				if key == "" {
+					continue
+				}
+
+				res, err := store.Take(r.Context(), rule.Name+":"+key, rule.Limit)
+				if err != nil {
+					// an unavailable store shouldn't make the service unavailable
+					log.Printf("rate limit %s: %v", rule.Name, err)
+					continue
+				}
+
+				if !found || moreRestrictive(res, current) {
+					current = res
+				}
+				found = true
+			}
+
+			if found {
+				w.Header().Set("RateLimit-Limit", strconv.Itoa(current.Limit))
+				w.Header().Set("RateLimit-Remaining", strconv.Itoa(current.Remaining))
+				w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(current.Reset)))
+			}
+			if found && !current.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(current.RetryAfter)))
+				http.Error(w, "too many requests", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ByIP returns the IP address of the client. Behind a proxy, the proxy
+// should set RemoteAddr of the requests, e.g. with a real IP middleware.
+func ByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ByEmail returns the email or the login identifier from the JSON body,
+// which is left for the next handler. Emails of other tenants than
+// the default one are prefixed with the tenant.
+func ByEmail(r *http.Request) string {
+	if r.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxKeyBodySize))
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+	if err != nil {
+		return ""
+	}
+
+	var req struct {
+		Email      string `json:"email"`
+		Identifier string `json:"identifier"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return ""
+	}
+
+	key := req.Email
+	if req.Identifier != "" {
+		key = req.Identifier
+	}
+	key = strings.ToLower(strings.TrimSpace(key))
+	if id := tenantID(r); id != models.DefaultTenantID && key != "" {
+		key = id + ":" + key
+	}
+	return key
+}
+
+// ByClientID returns the client ID from the X-Client-ID header
+func ByClientID(r *http.Request) string {
+	return r.Header.Get("X-Client-ID")
+}
+
+// moreRestrictive reports whether the result a should be reported instead of b
+func moreRestrictive(a, b ratelimit.Result) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+	if !a.Allowed {
+		return a.RetryAfter > b.RetryAfter
+	}
+	return a.Remaining < b.Remaining
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,86 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store unavailable")
+}
+
+func TestRateLimit(t *testing.T) {
+	var body string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler := RateLimit(ratelimit.NewMemoryStore(),
+		RateLimitRule{Name: "login:ip", Key: ByIP, Limit: ratelimit.Limit{Requests: 3, Per: time.Minute}},
+		RateLimitRule{Name: "login:email", Key: ByEmail, Limit: ratelimit.Limit{Requests: 1, Per: time.Minute}},
+	)(next)
+
+	request := func(email string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/login", bytes.NewBufferString(`{"email":"`+email+`"}`))
+		req.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("a@example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"email":"a@example.com"}`, body, "the body is left for the handler")
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+
+	w = request("A@example.com")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "emails are case-insensitive")
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	w = request("b@example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = request("c@example.com")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "the IP limit is exhausted")
+	assert.Equal(t, "3", w.Header().Get("RateLimit-Limit"))
+}
+
+func TestRateLimitStoreError(t *testing.T) {
+	handler := RateLimit(failingStore{},
+		RateLimitRule{Name: "ip", Key: ByIP, Limit: ratelimit.Limit{Requests: 1, Per: time.Minute}},
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/validate", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+}
+
+func TestKeyFuncs(t *testing.T) {
+	req := httptest.NewRequest("POST", "/login", bytes.NewBufferString(`{"identifier":" Alex "}`))
+	req.RemoteAddr = "[2001:db8::1]:443"
+	req.Header.Set("X-Client-ID", "mobile-app")
+
+	assert.Equal(t, "2001:db8::1", ByIP(req))
+	assert.Equal(t, "alex", ByEmail(req))
+	assert.Equal(t, "mobile-app", ByClientID(req))
+
+	invalid := httptest.NewRequest("POST", "/login", bytes.NewBufferString("invalid"))
+	assert.Empty(t, ByEmail(invalid))
+}
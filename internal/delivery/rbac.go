@@ -0,0 +1,168 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+)
+
+// writeRBACError maps role errors to HTTP responses
+func writeRBACError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrRoleNotFound):
+		http.Error(w, "role not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, "user not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrUnknownPermission):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "role operation failed", http.StatusInternalServerError)
+	}
+}
+
+// ListRoles returns all roles with their permissions
+func (h *Handler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.service.Roles(r.Context())
+	if err != nil {
+		writeRBACError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(roles)
+}
+
+// ListPermissions returns the catalogue of permissions
+func (h *Handler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	permissions, err := h.service.Permissions(r.Context())
+	if err != nil {
+		writeRBACError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(permissions)
+}
+
+// CreateRole creates a role
+func (h *Handler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	var req dto.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	role, err := h.service.CreateRole(r.Context(), &req)
+	if err != nil {
+		if writeConflict(w, err) {
+			return
+		}
+		writeRBACError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(role)
+}
+
+// UpdateRole replaces the description and the permissions of the role
+func (h *Handler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	var req dto.UpdateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.UpdateRole(r.Context(), r.PathValue("name"), &req); err != nil {
+		writeRBACError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteRole deletes the role
+func (h *Handler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteRole(r.Context(), r.PathValue("name")); err != nil {
+		writeRBACError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListUserRoles returns the roles assigned to the user
+func (h *Handler) ListUserRoles(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	roles, err := h.service.UserRoles(r.Context(), userID)
+	if err != nil {
+		writeRBACError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(roles)
+}
+
+// AssignRole assigns the role to the user
+func (h *Handler) AssignRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.AssignRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.AssignRole(r.Context(), userID, req.Role); err != nil {
+		writeRBACError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeRole removes the role from the user
+func (h *Handler) RevokeRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RevokeRole(r.Context(), userID, r.PathValue("role")); err != nil {
+		writeRBACError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
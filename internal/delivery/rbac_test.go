@@ -0,0 +1,144 @@
+package delivery
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandlerCreateRole(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    any
+		mockSetup      func(*mockrepo.MockRepository)
+		expectedStatus int
+	}{
+		{
+			name:        "success",
+			requestBody: dto.RoleRequest{Name: "support", Permissions: []string{"users:read"}},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("ListPermissions", mock.Anything).Return([]models.Permission{{Name: "users:read"}}, nil)
+				m.On("CreateRole", mock.Anything, mock.AnythingOfType("models.Role")).Return(nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:        "unknown permission",
+			requestBody: dto.RoleRequest{Name: "support", Permissions: []string{"users:reed"}},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("ListPermissions", mock.Anything).Return([]models.Permission{{Name: "users:read"}}, nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "role exists",
+			requestBody: dto.RoleRequest{Name: "admin"},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("CreateRole", mock.Anything, mock.AnythingOfType("models.Role")).Return(models.ErrRoleExists)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "invalid name",
+			requestBody:    dto.RoleRequest{Name: "support team"},
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "empty permission",
+			requestBody:    dto.RoleRequest{Name: "support", Permissions: []string{""}},
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest("POST", "/admin/roles", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			handler.CreateRole(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandlerRevokeRole(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+	tests := []struct {
+		name           string
+		userID         string
+		mockSetup      func(*mockrepo.MockRepository)
+		expectedStatus int
+	}{
+		{
+			name:   "success",
+			userID: userID.String(),
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID}, nil)
+				m.On("RevokeRole", mock.Anything, "", userID, "admin").Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:   "not assigned",
+			userID: userID.String(),
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID}, nil)
+				m.On("RevokeRole", mock.Anything, "", userID, "admin").Return(models.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "unknown user",
+			userID: userID.String(),
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByID", mock.Anything, "", userID).Return(nil, models.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid user id",
+			userID:         "invalid",
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("DELETE /admin/users/{id}/roles/{role}", handler.RevokeRole)
+			req := httptest.NewRequest("DELETE", "/admin/users/"+tt.userID+"/roles/admin", nil)
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
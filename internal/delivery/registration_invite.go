@@ -0,0 +1,83 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+)
+
+// writeInviteError maps registration invite errors to HTTP responses
+func writeInviteError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInviteNotFound):
+		http.Error(w, "invite not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrRoleNotFound), errors.Is(err, service.ErrOrgNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrInvalidExpiry):
+		http.Error(w, "expiration time is in the past", http.StatusBadRequest)
+	default:
+		http.Error(w, "invite operation failed", http.StatusInternalServerError)
+	}
+}
+
+// CreateInvite creates a registration invite on behalf of the authenticated admin
+func (h *Handler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.CreateInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	invite, err := h.service.CreateInvite(r.Context(), userID, &req)
+	if err != nil {
+		writeInviteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invite)
+}
+
+// ListInvites returns the registration invites of the tenant
+func (h *Handler) ListInvites(w http.ResponseWriter, r *http.Request) {
+	invites, err := h.service.Invites(r.Context())
+	if err != nil {
+		writeInviteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invites)
+}
+
+// RevokeInvite deletes the registration invite
+func (h *Handler) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	inviteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid invite id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RevokeInvite(r.Context(), inviteID); err != nil {
+		writeInviteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,205 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandlerRegisterModes(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           []service.Option
+		requestBody    dto.RegisterRequest
+		mockSetup      func(*mockrepo.MockRepository)
+		expectedStatus int
+		expectedField  string
+	}{
+		{
+			name:           "invite required",
+			opts:           []service.Option{service.WithRegistrationMode(service.RegistrationInviteOnly)},
+			requestBody:    dto.RegisterRequest{Username: "testuser", Email: "test@example.com", Password: "password123"},
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "invalid invite",
+			opts: []service.Option{service.WithRegistrationMode(service.RegistrationInviteOnly)},
+			requestBody: dto.RegisterRequest{
+				Username: "testuser", Email: "test@example.com", Password: "password123", InviteToken: "token",
+			},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetRegistrationInviteByHash", mock.Anything, mock.Anything).
+					Return(nil, models.ErrNotFound)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedField:  "invite_token",
+		},
+		{
+			name: "invite already used",
+			requestBody: dto.RegisterRequest{
+				Username: "testuser", Email: "test@example.com", Password: "password123", InviteToken: "token",
+			},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetRegistrationInviteByHash", mock.Anything, mock.Anything).
+					Return(&models.RegistrationInvite{ExpiresAt: time.Now().Add(time.Hour)}, nil)
+				m.On("CreateInvitedUser", mock.Anything, mock.Anything, mock.Anything).
+					Return(models.User{}, models.ErrInvalidInvite)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedField:  "invite_token",
+		},
+		{
+			name:           "domain not allowed",
+			opts:           []service.Option{service.WithRegistrationMode(service.RegistrationDomainRestricted, "corp.com")},
+			requestBody:    dto.RegisterRequest{Username: "testuser", Email: "test@example.com", Password: "password123"},
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedField:  "email",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			handler := NewHandler(service.New(mockRepo, "secret", time.Hour, tt.opts...))
+
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest("POST", "/register", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			handler.Register(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedField != "" {
+				var resp dto.ErrorResponse
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+				assert.Equal(t, tt.expectedField, resp.Field)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandlerCreateInvite(t *testing.T) {
+	adminID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+	tests := []struct {
+		name           string
+		requestBody    any
+		mockSetup      func(*mockrepo.MockRepository)
+		expectedStatus int
+	}{
+		{
+			name:        "success",
+			requestBody: dto.CreateInviteRequest{Email: "new@example.com", Roles: []string{"editor"}},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("ListRoles", mock.Anything, "").Return([]models.Role{{Name: "editor"}}, nil)
+				m.On("CreateRegistrationInvite", mock.Anything, mock.AnythingOfType("models.RegistrationInvite")).Return(nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:        "unknown role",
+			requestBody: dto.CreateInviteRequest{Roles: []string{"owner"}},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("ListRoles", mock.Anything, "").Return([]models.Role{{Name: "editor"}}, nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid org role",
+			requestBody:    dto.CreateInviteRequest{OrgRole: "superuser"},
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest("POST", "/admin/invites", bytes.NewReader(body))
+			req = req.WithContext(context.WithValue(req.Context(), contextKeyUserID, adminID.String()))
+			w := httptest.NewRecorder()
+
+			handler.CreateInvite(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if w.Code == http.StatusCreated {
+				var created dto.InviteCreated
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+				assert.NotEmpty(t, created.Token)
+				assert.NotContains(t, w.Body.String(), "token_hash")
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandlerRevokeInvite(t *testing.T) {
+	inviteID := uuid.MustParse("00000000-0000-0000-0000-0000000000c1")
+
+	tests := []struct {
+		name           string
+		inviteID       string
+		mockSetup      func(*mockrepo.MockRepository)
+		expectedStatus int
+	}{
+		{
+			name:     "success",
+			inviteID: inviteID.String(),
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("DeleteRegistrationInvite", mock.Anything, "", inviteID).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:     "not found",
+			inviteID: inviteID.String(),
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("DeleteRegistrationInvite", mock.Anything, "", inviteID).Return(models.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid id",
+			inviteID:       "invalid",
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+			req := httptest.NewRequest("DELETE", "/admin/invites/"+tt.inviteID, nil)
+			req.SetPathValue("id", tt.inviteID)
+			w := httptest.NewRecorder()
+
+			handler.RevokeInvite(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
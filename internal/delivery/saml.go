@@ -0,0 +1,86 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/AlexFox86/auth-service/internal/service"
+)
+
+const (
+	samlCookie = "saml_state"
+
+	// maxSAMLResponseSize the maximum size of the form the identity provider posts
+	maxSAMLResponseSize = 1 << 20
+)
+
+// ListSAMLProviders returns the names of the SAML identity providers of the tenant
+func (h *Handler) ListSAMLProviders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.service.SAMLProviders(r.Context()))
+}
+
+// SAMLMetadata returns the service provider metadata for the identity provider in the provider path value
+func (h *Handler) SAMLMetadata(w http.ResponseWriter, r *http.Request) {
+	metadata, err := h.service.SAMLMetadata(r.Context(), r.PathValue("provider"))
+	if err != nil {
+		writeExternalLoginError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	w.Write(metadata)
+}
+
+// StartSAMLLogin redirects to the login page of the identity provider in the provider
+// path value with an authentication request and binds the login to the browser
+func (h *Handler) StartSAMLLogin(w http.ResponseWriter, r *http.Request) {
+	authURL, state, err := h.service.StartSAMLLogin(r.Context(), r.PathValue("provider"))
+	if err != nil {
+		writeExternalLoginError(w, err)
+		return
+	}
+
+	setSAMLCookie(w, state, int(service.SAMLLoginExpiry.Seconds()))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// setSAMLCookie sets the state of the login in the browser. The identity provider
+// posts the response cross-site, so the cookie must allow it. The path covers
+// the tenant prefix of the login URLs.
+func setSAMLCookie(w http.ResponseWriter, state string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     samlCookie,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+}
+
+// SAMLAssertionConsumer logs the user in with the response the identity provider posted
+func (h *Handler) SAMLAssertionConsumer(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxSAMLResponseSize)
+	samlResponse := r.PostFormValue("SAMLResponse")
+	if samlResponse == "" {
+		http.Error(w, "SAMLResponse required", http.StatusBadRequest)
+		return
+	}
+
+	var state string
+	if cookie, err := r.Cookie(samlCookie); err == nil {
+		state = cookie.Value
+	}
+
+	resp, err := h.service.CompleteSAMLLogin(r.Context(), r.PathValue("provider"), samlResponse, state)
+	if err != nil {
+		writeExternalLoginError(w, err)
+		return
+	}
+
+	setSAMLCookie(w, "", -1)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,95 @@
+package delivery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/saml"
+	"github.com/AlexFox86/auth-service/internal/pkg/saml/samltest"
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandlerSAMLLogin(t *testing.T) {
+	stub, err := samltest.NewIdP()
+	assert.NoError(t, err)
+	idp, err := saml.NewIdentityProvider(stub.Config("okta", ""))
+	assert.NoError(t, err)
+
+	user := &models.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), Email: "alex@example.com"}
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("UseSAMLAssertion", mock.Anything, "", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("GetUserIdentity", mock.Anything, "", "saml:okta", "00u1abcd").Return(&models.UserIdentity{UserID: user.ID}, nil)
+	mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+	mockRepo.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{}, nil)
+	mockRepo.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{}, nil)
+	handler := NewHandler(service.New(mockRepo, "secret", time.Hour,
+		service.WithBaseURL("https://auth.example.com"), service.WithSAMLIdPs(idp)))
+
+	start := func(provider string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/login/saml/"+provider, nil)
+		req.SetPathValue("provider", provider)
+		w := httptest.NewRecorder()
+		handler.StartSAMLLogin(w, req)
+		return w
+	}
+	acs := func(samlResponse string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		form := url.Values{"SAMLResponse": {samlResponse}}
+		req := httptest.NewRequest("POST", "/login/saml/okta/acs", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetPathValue("provider", "okta")
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		handler.SAMLAssertionConsumer(w, req)
+		return w
+	}
+
+	t.Run("success", func(t *testing.T) {
+		w := start("okta")
+		assert.Equal(t, http.StatusFound, w.Code)
+		cookies := w.Result().Cookies()
+		if assert.Len(t, cookies, 1) {
+			assert.Equal(t, http.SameSiteNoneMode, cookies[0].SameSite)
+		}
+
+		samlResponse, err := stub.Respond(w.Header().Get("Location"), "00u1abcd", nil)
+		assert.NoError(t, err)
+		w = acs(samlResponse, cookies)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"token"`)
+	})
+
+	t.Run("without the browser's cookie", func(t *testing.T) {
+		samlResponse, err := stub.Respond(start("okta").Header().Get("Location"), "00u1abcd", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, acs(samlResponse, nil).Code)
+	})
+
+	t.Run("missing response", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, acs("", nil).Code)
+	})
+
+	t.Run("unknown idp", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, start("unknown").Code)
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/login/saml/okta/metadata", nil)
+		req.SetPathValue("provider", "okta")
+		w := httptest.NewRecorder()
+		handler.SAMLMetadata(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/samlmetadata+xml", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), `Location="https://auth.example.com/login/saml/okta/acs"`)
+	})
+}
@@ -0,0 +1,54 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/service"
+)
+
+// StepUp re-authenticates the user and returns a short-lived elevated token
+// scoped to the organization of the current one
+func (h *Handler) StepUp(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.StepUpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.StepUp(r.Context(), userID, orgIDFromContext(r.Context()), &req)
+	if err != nil {
+		if writeMFAChallenge(w, err) {
+			return
+		}
+		if writeLocked(w, err) || writeAccountBlocked(w, err) || writeImpersonating(w, err) {
+			return
+		}
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, service.ErrNotOrgMember) {
+			http.Error(w, "not a member of the organization", http.StatusForbidden)
+			return
+		}
+		http.Error(w, "step-up failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,55 @@
+package delivery
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/service"
+)
+
+// tenantPathPrefix the path prefix which selects the tenant: /t/{tenant}/login
+const tenantPathPrefix = "/t/"
+
+// ResolveTenant selects the tenant of the request by the /t/{tenant} path prefix,
+// which is stripped before routing, or else by the Host header. Requests which match
+// no tenant are served by the default tenant, an unknown tenant in the path is 404.
+func (h *Handler) ResolveTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.service.MultiTenant() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if rest, ok := strings.CutPrefix(r.URL.Path, tenantPathPrefix); ok {
+			id, _, _ := strings.Cut(rest, "/")
+			tenant, ok := h.service.TenantByID(id)
+			if !ok {
+				http.Error(w, "unknown tenant", http.StatusNotFound)
+				return
+			}
+
+			ctx := service.ContextWithTenant(r.Context(), tenant)
+			http.StripPrefix(tenantPathPrefix+id, next).ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if tenant, ok := h.service.TenantByHost(host); ok {
+			r = r.WithContext(service.ContextWithTenant(r.Context(), tenant))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tenantID returns the ID of the tenant the request is served by
+func tenantID(r *http.Request) string {
+	if tenant := service.TenantFromContext(r.Context()); tenant != nil {
+		return tenant.ID
+	}
+	return models.DefaultTenantID
+}
@@ -0,0 +1,93 @@
+package delivery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveTenant(t *testing.T) {
+	acme := models.Tenant{ID: "acme", Hosts: []string{"auth.acme.com"}}
+	globex := models.Tenant{ID: "globex"}
+
+	tests := []struct {
+		name           string
+		tenants        []models.Tenant
+		host           string
+		path           string
+		expectedStatus int
+		expectedTenant string
+		expectedPath   string
+	}{
+		{
+			name:           "path prefix",
+			tenants:        []models.Tenant{acme, globex},
+			host:           "auth.acme.com",
+			path:           "/t/globex/login",
+			expectedStatus: http.StatusOK,
+			expectedTenant: "globex",
+			expectedPath:   "/login",
+		},
+		{
+			name:           "host",
+			tenants:        []models.Tenant{acme, globex},
+			host:           "auth.acme.com:8080",
+			path:           "/login",
+			expectedStatus: http.StatusOK,
+			expectedTenant: "acme",
+			expectedPath:   "/login",
+		},
+		{
+			name:           "default tenant",
+			tenants:        []models.Tenant{acme, globex},
+			host:           "auth.example.com",
+			path:           "/login",
+			expectedStatus: http.StatusOK,
+			expectedTenant: models.DefaultTenantID,
+			expectedPath:   "/login",
+		},
+		{
+			name:           "unknown tenant",
+			tenants:        []models.Tenant{acme, globex},
+			host:           "auth.example.com",
+			path:           "/t/initech/login",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "single tenant",
+			host:           "auth.acme.com",
+			path:           "/t/acme/login",
+			expectedStatus: http.StatusOK,
+			expectedTenant: models.DefaultTenantID,
+			expectedPath:   "/t/acme/login",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []service.Option
+			if tt.tenants != nil {
+				opts = append(opts, service.WithTenants(tt.tenants...))
+			}
+			handler := NewHandler(service.New(new(mockrepo.MockRepository), "secret", time.Hour, opts...))
+
+			req := httptest.NewRequest("POST", tt.path, nil)
+			req.Host = tt.host
+			w := httptest.NewRecorder()
+
+			handler.ResolveTenant(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tt.expectedTenant, tenantID(r))
+				assert.Equal(t, tt.expectedPath, r.URL.Path)
+				w.WriteHeader(http.StatusOK)
+			})).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
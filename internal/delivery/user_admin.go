@@ -0,0 +1,197 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+)
+
+// writeUserError maps user management errors to HTTP responses
+func writeUserError(w http.ResponseWriter, err error) {
+	if writeConflict(w, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, "user not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidSort):
+		http.Error(w, "invalid sort", http.StatusBadRequest)
+	case errors.Is(err, service.ErrMailerNotConfigured):
+		http.Error(w, "password reset is not available", http.StatusNotImplemented)
+	case errors.Is(err, service.ErrInvalidStatusUntil):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "user operation failed", http.StatusInternalServerError)
+	}
+}
+
+// writeAccountBlocked writes 403 if the account can't log in
+func writeAccountBlocked(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrAccountDisabled):
+		http.Error(w, "account disabled", http.StatusForbidden)
+	case errors.Is(err, service.ErrAccountSuspended):
+		http.Error(w, "account suspended", http.StatusForbidden)
+	case errors.Is(err, service.ErrAccountPending):
+		http.Error(w, "account pending", http.StatusForbidden)
+	case errors.Is(err, service.ErrAccountDeleted):
+		http.Error(w, "account deleted", http.StatusForbidden)
+	case errors.Is(err, service.ErrPasswordResetRequired):
+		http.Error(w, "password reset required", http.StatusForbidden)
+	default:
+		return false
+	}
+	return true
+}
+
+// ListUsers returns a page of users. The sort field is prefixed with '-'
+// for the descending order, e.g. ?search=alex&sort=-created_at&limit=20&offset=40
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.UserFilter{Search: query.Get("search")}
+
+	sort := query.Get("sort")
+	filter.Sort = strings.TrimPrefix(sort, "-")
+	filter.Desc = strings.HasPrefix(sort, "-")
+
+	for name, field := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				http.Error(w, "invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*field = n
+		}
+	}
+
+	page, err := h.service.Users(r.Context(), filter)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// GetUser returns the user
+func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.service.User(r.Context(), userID)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// UpdateUser changes the username or email of the user
+func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.service.UpdateUser(r.Context(), userID, &req)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// SetUserStatus changes the status of the user
+func (h *Handler) SetUserStatus(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.SetUserStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.service.SetUserStatus(r.Context(), userID, &req)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// DisableUser blocks the user
+func (h *Handler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	h.userAction(w, r, h.service.DisableUser)
+}
+
+// EnableUser allows the disabled user to log in again
+func (h *Handler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	h.userAction(w, r, h.service.EnableUser)
+}
+
+// ForcePasswordReset requires the user to set a new password with the emailed link
+func (h *Handler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	h.userAction(w, r, h.service.ForcePasswordReset)
+}
+
+// DeleteUser deletes the user
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	h.userAction(w, r, h.service.DeleteUser)
+}
+
+// userAction performs the action on the user in the id path value
+func (h *Handler) userAction(w http.ResponseWriter, r *http.Request, action func(context.Context, uuid.UUID) error) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	if err := action(r.Context(), userID); err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,260 @@
+package delivery
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+	"github.com/AlexFox86/auth-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandlerListUsers(t *testing.T) {
+	users := []models.User{{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), Username: "alex"}}
+
+	tests := []struct {
+		name           string
+		query          string
+		mockSetup      func(*mockrepo.MockRepository)
+		expectedStatus int
+	}{
+		{
+			name:  "success",
+			query: "?search=alex&sort=-username&limit=10&offset=20",
+			mockSetup: func(m *mockrepo.MockRepository) {
+				filter := models.UserFilter{Search: "alex", Sort: models.UserSortUsername, Desc: true, Limit: 10, Offset: 20}
+				m.On("ListUsers", mock.Anything, filter).Return(users, nil)
+				m.On("CountUsers", mock.Anything, filter).Return(21, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid sort",
+			query:          "?sort=password",
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid limit",
+			query:          "?limit=-1",
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+			req := httptest.NewRequest("GET", "/admin/users"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.ListUsers(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if w.Code == http.StatusOK {
+				var page dto.UserPage
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&page))
+				assert.Equal(t, 21, page.Total)
+				assert.Len(t, page.Users, 1)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandlerUpdateUser(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	user := models.User{ID: userID, Username: "alex", Email: "alex@example.com"}
+
+	tests := []struct {
+		name           string
+		requestBody    string
+		mockSetup      func(*mockrepo.MockRepository)
+		expectedStatus int
+	}{
+		{
+			name:        "success",
+			requestBody: `{"username": "alexander"}`,
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByID", mock.Anything, "", userID).Return(&user, nil)
+				m.On("UpdateUser", mock.Anything, mock.AnythingOfType("*models.User")).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "email taken",
+			requestBody: `{"email": "bob@example.com"}`,
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByID", mock.Anything, "", userID).Return(&user, nil)
+				m.On("UpdateUser", mock.Anything, mock.AnythingOfType("*models.User")).Return(models.ErrEmailExists)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "invalid username",
+			requestBody:    `{"username": "a@b"}`,
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "not found",
+			requestBody: `{"username": "alexander"}`,
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByID", mock.Anything, "", userID).Return(nil, models.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+			req := httptest.NewRequest("PATCH", "/admin/users/"+userID.String(), bytes.NewBufferString(tt.requestBody))
+			req.SetPathValue("id", userID.String())
+			w := httptest.NewRecorder()
+
+			handler.UpdateUser(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandlerUserActions(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+	tests := []struct {
+		name           string
+		userID         string
+		action         func(*Handler) http.HandlerFunc
+		mockSetup      func(*mockrepo.MockRepository)
+		expectedStatus int
+	}{
+		{
+			name:   "disable",
+			userID: userID.String(),
+			action: func(h *Handler) http.HandlerFunc { return h.DisableUser },
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID}, nil)
+				m.On("SetUserStatus", mock.Anything, userID, models.UserStatusDisabled, "", (*time.Time)(nil)).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:   "delete",
+			userID: userID.String(),
+			action: func(h *Handler) http.HandlerFunc { return h.DeleteUser },
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID}, nil)
+				m.On("DeleteUser", mock.Anything, userID).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:   "not found",
+			userID: userID.String(),
+			action: func(h *Handler) http.HandlerFunc { return h.EnableUser },
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByID", mock.Anything, "", userID).Return(nil, models.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "password reset without mailer",
+			userID:         userID.String(),
+			action:         func(h *Handler) http.HandlerFunc { return h.ForcePasswordReset },
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusNotImplemented,
+		},
+		{
+			name:           "invalid id",
+			userID:         "invalid",
+			action:         func(h *Handler) http.HandlerFunc { return h.DisableUser },
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+			req := httptest.NewRequest("POST", "/admin/users/"+tt.userID, nil)
+			req.SetPathValue("id", tt.userID)
+			w := httptest.NewRecorder()
+
+			tt.action(handler)(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandlerSetUserStatus(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+	tests := []struct {
+		name           string
+		body           string
+		mockSetup      func(*mockrepo.MockRepository)
+		expectedStatus int
+	}{
+		{
+			name: "suspend",
+			body: `{"status":"suspended","reason":"chargeback","until":"` + time.Now().Add(time.Hour).Format(time.RFC3339) + `"}`,
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID, Status: models.UserStatusActive}, nil)
+				m.On("SetUserStatus", mock.Anything, userID, models.UserStatusSuspended, "chargeback",
+					mock.AnythingOfType("*time.Time")).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown status",
+			body:           `{"status":"banned"}`,
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "until in the past",
+			body:           `{"status":"suspended","until":"2020-01-01T00:00:00Z"}`,
+			mockSetup:      func(m *mockrepo.MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			handler := NewHandler(service.New(mockRepo, "secret", time.Hour))
+
+			req := httptest.NewRequest("PUT", "/admin/users/"+userID.String()+"/status", bytes.NewBufferString(tt.body))
+			req.SetPathValue("id", userID.String())
+			w := httptest.NewRecorder()
+
+			handler.SetUserStatus(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit event types
+const (
+	AuditLoginSucceeded      = "login.succeeded"
+	AuditLoginFailed         = "login.failed"
+	AuditRegistered          = "user.registered"
+	AuditPasswordChanged     = "password.changed"
+	AuditEmailChanged        = "email.changed"
+	AuditEmailChangeReverted = "email.change_reverted"
+	AuditMFAMethodAdded      = "mfa.method_added"
+	AuditMFAMethodRemoved    = "mfa.method_removed"
+	AuditStepUp              = "step_up.succeeded"
+	AuditAccountLocked       = "account.locked"
+	AuditAccountUnlocked     = "account.unlocked"
+	AuditAPIKeyCreated       = "api_key.created"
+	AuditAPIKeyRevoked       = "api_key.revoked"
+	AuditUserUpdated         = "user.updated"
+	AuditProfileUpdated      = "user.profile_updated"
+	AuditStatusChanged       = "user.status_changed"
+	AuditUserDeleted         = "user.deleted"
+	AuditDeletionRequested   = "user.deletion_requested"
+	AuditUserRestored        = "user.restored"
+	AuditDataExported        = "user.data_exported"
+	AuditInviteCreated       = "invite.created"
+	AuditInviteRevoked       = "invite.revoked"
+	AuditPasswordResetForced = "password.reset_forced"
+	AuditImpersonationStart  = "impersonation.started"
+	AuditImpersonatedRequest = "impersonation.request"
+	AuditIdentityLinked      = "identity.linked"
+	AuditIdentityUnlinked    = "identity.unlinked"
+)
+
+// Reasons of failed logins
+const (
+	AuditReasonUnknownUser      = "unknown_user"
+	AuditReasonInvalidPassword  = "invalid_password"
+	AuditReasonLocked           = "locked"
+	AuditReasonEmailNotVerified = "email_not_verified"
+	AuditReasonInvalidOTP       = "invalid_otp"
+	AuditReasonInvalidMagicLink = "invalid_magic_link"
+	AuditReasonDisabled         = "disabled"
+	AuditReasonSuspended        = "suspended"
+	AuditReasonPending          = "pending"
+	AuditReasonPasswordReset    = "password_reset_required"
+	AuditReasonDeleted          = "deleted"
+	AuditReasonInvalidExternal  = "invalid_external_login"
+)
+
+// AuditEvent the record of a security-relevant action
+type AuditEvent struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	TenantID   string     `json:"-" db:"tenant_id"`
+	Type       string     `json:"type" db:"type"`
+	UserID     *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
+	ActorID    *uuid.UUID `json:"actor_id,omitempty" db:"actor_id"`
+	Identifier string     `json:"identifier,omitempty" db:"identifier"`
+	Method     string     `json:"method,omitempty" db:"method"`
+	Reason     string     `json:"reason,omitempty" db:"reason"`
+	IP         string     `json:"ip,omitempty" db:"ip"`
+	UserAgent  string     `json:"user_agent,omitempty" db:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// AuditFilter selects audit events. Zero fields don't filter,
+// except the tenant which is always matched.
+type AuditFilter struct {
+	TenantID string
+	UserID   *uuid.UUID
+	Type     string
+	From     time.Time
+	To       time.Time
+	Limit    int
+	Offset   int
+}
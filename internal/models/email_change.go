@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Email change statuses
+const (
+	EmailChangePending   = "pending"
+	EmailChangeConfirmed = "confirmed"
+	EmailChangeReverted  = "reverted"
+)
+
+// EmailChange the request to change the user's email.
+// The records are kept as the audit trail of email changes.
+type EmailChange struct {
+	ID          uuid.UUID  `db:"id"`
+	UserID      uuid.UUID  `db:"user_id"`
+	OldEmail    string     `db:"old_email"`
+	NewEmail    string     `db:"new_email"`
+	Status      string     `db:"status"`
+	ExpiresAt   time.Time  `db:"expires_at"`
+	ConfirmedAt *time.Time `db:"confirmed_at"`
+	RevertedAt  *time.Time `db:"reverted_at"`
+	CreatedAt   time.Time  `db:"created_at"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "errors"
+
+// ConflictError returned when a unique field is already taken
+type ConflictError struct {
+	Field string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Field + " already exists"
+}
+
+// Is reports whether the target is a conflict on the same field
+func (e *ConflictError) Is(target error) bool {
+	t, ok := target.(*ConflictError)
+	return ok && t.Field == e.Field
+}
+
+var (
+	// ErrEmailExists returned when the email belongs to another user
+	ErrEmailExists = &ConflictError{Field: "email"}
+
+	// ErrUsernameExists returned when the username belongs to another user
+	ErrUsernameExists = &ConflictError{Field: "username"}
+
+	// ErrRoleExists returned when a role with the name already exists
+	ErrRoleExists = &ConflictError{Field: "role"}
+
+	// ErrSlugExists returned when an organization with the slug already exists
+	ErrSlugExists = &ConflictError{Field: "slug"}
+
+	// ErrIdentityExists returned when the external identity is linked to another user
+	ErrIdentityExists = &ConflictError{Field: "identity"}
+)
+
+// ErrNotFound wrapped by the errors returned when the requested record doesn't exist
+var ErrNotFound = errors.New("not found")
+
+// ErrUserModified returned when the user was modified since the version the update is based on
+var ErrUserModified = errors.New("user was modified")
+
+// ErrInvalidInvite returned when the registration invite is unknown, used or expired
+var ErrInvalidInvite = errors.New("invalid or expired invite")
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// LoginFailure the failed login attempts for a key: a user or an unknown identifier
+type LoginFailure struct {
+	Key         string     `db:"key"`
+	Attempts    int        `db:"attempts"`
+	LockedUntil *time.Time `db:"locked_until"`
+	UpdatedAt   time.Time  `db:"updated_at"`
+}
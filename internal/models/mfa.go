@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MFA method types
+const (
+	MFAMethodEmail = "email"
+	MFAMethodSMS   = "sms"
+)
+
+// MFAMethod the second authentication factor of the user
+type MFAMethod struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	UserID      uuid.UUID `json:"-" db:"user_id"`
+	Type        string    `json:"type" db:"type"`
+	Destination string    `json:"destination" db:"destination"`
+	Confirmed   bool      `json:"confirmed" db:"confirmed"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// OTPCode the one-time code sent to the MFA method
+type OTPCode struct {
+	ID        uuid.UUID  `db:"id"`
+	UserID    uuid.UUID  `db:"user_id"`
+	MethodID  uuid.UUID  `db:"method_id"`
+	CodeHash  string     `db:"code_hash"`
+	Attempts  int        `db:"attempts"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RegistrationInvite the single-use invite to register, required when registration
+// is invite-only. Only the hash of the token is stored. The invited user gets the roles
+// and joins the organization with the role if it is set.
+type RegistrationInvite struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	TenantID  string     `json:"-" db:"tenant_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	Email     string     `json:"email,omitempty" db:"email"`
+	Roles     []string   `json:"roles" db:"-"`
+	OrgID     *uuid.UUID `json:"org_id,omitempty" db:"org_id"`
+	OrgRole   string     `json:"org_role,omitempty" db:"org_role"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedBy    *uuid.UUID `json:"used_by,omitempty" db:"used_by"`
+	UsedAt    *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
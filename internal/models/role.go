@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// RoleAdmin the role with all permissions of the admin API
+const RoleAdmin = "admin"
+
+// Permissions of the admin API
+const (
+	PermissionUsersRead   = "users:read"
+	PermissionUsersWrite  = "users:write"
+	PermissionAuditRead   = "audit:read"
+	PermissionRolesManage = "roles:manage"
+	PermissionImpersonate = "users:impersonate"
+)
+
+// Role the named set of permissions assigned to users
+type Role struct {
+	TenantID    string    `json:"-" db:"tenant_id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	Permissions []string  `json:"permissions" db:"-"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// Permission the permission roles can grant
+type Permission struct {
+	Name        string `json:"name" db:"name"`
+	Description string `json:"description" db:"description"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// DefaultTenantID the ID of the tenant used when no tenant is resolved
+const DefaultTenantID = ""
+
+// Tenant an isolated pool of users with its own signing key and policies.
+// Zero fields fall back to the settings of the service.
+type Tenant struct {
+	ID             string
+	Name           string
+	Hosts          []string
+	BaseURL        string
+	JWTSecret      string
+	TokenExpiry    time.Duration
+	PasswordPolicy PasswordPolicy
+}
+
+// PasswordPolicy requirements for new passwords
+type PasswordPolicy struct {
+	MinLength        int  `json:"min_length"`
+	RequireUppercase bool `json:"require_uppercase"`
+	RequireLowercase bool `json:"require_lowercase"`
+	RequireDigit     bool `json:"require_digit"`
+	RequireSymbol    bool `json:"require_symbol"`
+}
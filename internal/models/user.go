@@ -1,17 +1,70 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// Fields users can be sorted by
+const (
+	UserSortCreatedAt = "created_at"
+	UserSortUsername  = "username"
+	UserSortEmail     = "email"
+)
+
+// Statuses of user accounts. Only active users can authenticate.
+const (
+	UserStatusActive    = "active"
+	UserStatusPending   = "pending"
+	UserStatusSuspended = "suspended"
+	UserStatusDisabled  = "disabled"
+)
+
 // User the user's model
 type User struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	Username  string    `json:"username" db:"username"`
-	Email     string    `json:"email" db:"email"`
-	Password  string    `json:"-" db:"password"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID                    uuid.UUID       `json:"id" db:"id"`
+	TenantID              string          `json:"-" db:"tenant_id"`
+	Username              string          `json:"username" db:"username"`
+	Email                 string          `json:"email" db:"email"`
+	EmailVerified         bool            `json:"email_verified" db:"email_verified"`
+	Password              string          `json:"-" db:"password"`
+	DisplayName           string          `json:"display_name,omitempty" db:"display_name"`
+	Locale                string          `json:"locale,omitempty" db:"locale"`
+	Timezone              string          `json:"timezone,omitempty" db:"timezone"`
+	Metadata              json.RawMessage `json:"metadata,omitempty" db:"metadata"`
+	Status                string          `json:"status" db:"status"`
+	StatusReason          string          `json:"status_reason,omitempty" db:"status_reason"`
+	StatusUntil           *time.Time      `json:"status_until,omitempty" db:"status_until"`
+	PasswordResetRequired bool            `json:"password_reset_required,omitempty" db:"password_reset_required"`
+	DeletedAt             *time.Time      `json:"deleted_at,omitempty" db:"deleted_at"`
+	CreatedAt             time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt             time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// HasPassword reports whether the user can log in with a password.
+// Users created by an external identity provider have none until they set one.
+func (u *User) HasPassword() bool {
+	return u.Password != ""
+}
+
+// CurrentStatus returns the status of the user at the time. A status with
+// the until-time set ends then and the user is active again.
+func (u *User) CurrentStatus(now time.Time) string {
+	if u.Status == "" || (u.StatusUntil != nil && !u.StatusUntil.After(now)) {
+		return UserStatusActive
+	}
+	return u.Status
+}
+
+// UserFilter selects users of the tenant. The search matches
+// the username or email case-insensitively, empty matches all.
+type UserFilter struct {
+	TenantID string
+	Search   string
+	Sort     string
+	Desc     bool
+	Limit    int
+	Offset   int
 }
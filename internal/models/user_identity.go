@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserIdentity the identity of the user at an external identity provider.
+// The subject is unique per provider in the tenant, so the identity
+// belongs to at most one local user.
+type UserIdentity struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	TenantID  string    `json:"-" db:"tenant_id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	Provider  string    `json:"provider" db:"provider"`
+	Subject   string    `json:"subject" db:"subject"`
+	Email     string    `json:"email,omitempty" db:"email"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+)
+
+type contextKey struct{}
+
+// Client describes where the request came from
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+// ContextWithClient returns a copy of the context with the client
+func ContextWithClient(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, contextKey{}, client)
+}
+
+// ClientFromContext returns the client of the request, empty if unknown
+func ClientFromContext(ctx context.Context) Client {
+	client, _ := ctx.Value(contextKey{}).(Client)
+	return client
+}
+
+// WriterSink writes audit events as JSON lines
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink creates a new object of 'WriterSink' type
+// and returns a pointer to it.
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// NewStdoutSink creates a sink which writes to the standard output
+func NewStdoutSink() *WriterSink {
+	return NewWriterSink(os.Stdout)
+}
+
+// Write writes the event as a line of JSON
+func (s *WriterSink) Write(_ context.Context, event models.AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit event: %w", err)
+	}
+	return nil
+}
+
+// FileSink appends audit events to a JSON lines file
+type FileSink struct {
+	*WriterSink
+	file *os.File
+}
+
+// NewFileSink opens the file for appending, creating it if needed
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	return &FileSink{
+		WriterSink: NewWriterSink(file),
+		file:       file,
+	}, nil
+}
+
+// Close closes the file
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
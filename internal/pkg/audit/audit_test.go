@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientContext(t *testing.T) {
+	assert.Equal(t, Client{}, ClientFromContext(context.Background()))
+
+	client := Client{IP: "192.0.2.1", UserAgent: "curl/8.0"}
+	ctx := ContextWithClient(context.Background(), client)
+	assert.Equal(t, client, ClientFromContext(ctx))
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf)
+	userID := uuid.New()
+
+	events := []models.AuditEvent{
+		{ID: uuid.New(), Type: models.AuditLoginSucceeded, UserID: &userID, Method: "pwd", CreatedAt: time.Now().UTC()},
+		{ID: uuid.New(), Type: models.AuditLoginFailed, Identifier: "alex", Reason: models.AuditReasonUnknownUser, CreatedAt: time.Now().UTC()},
+	}
+	for _, event := range events {
+		assert.NoError(t, sink.Write(context.Background(), event))
+	}
+
+	scanner := bufio.NewScanner(&buf)
+	for _, expected := range events {
+		assert.True(t, scanner.Scan())
+		var event models.AuditEvent
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		assert.Equal(t, expected.ID, event.ID)
+		assert.Equal(t, expected.Type, event.Type)
+		assert.Equal(t, expected.UserID, event.UserID)
+		assert.Equal(t, expected.Reason, event.Reason)
+	}
+	assert.False(t, scanner.Scan())
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	for range 2 {
+		sink, err := NewFileSink(path)
+		assert.NoError(t, err)
+		assert.NoError(t, sink.Write(context.Background(), models.AuditEvent{Type: models.AuditRegistered}))
+		assert.NoError(t, sink.Close())
+	}
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, bytes.Count(data, []byte("\n")), "the file is appended to")
+}
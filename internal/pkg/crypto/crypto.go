@@ -2,8 +2,11 @@ package crypto
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"math/big"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -34,3 +37,24 @@ func GenerateRandomString(length int) (string, error) {
 
 	return base64.RawURLEncoding.EncodeToString(b), nil
 }
+
+// HashToken creates a SHA-256 hash of a high-entropy token.
+// Unlike passwords, random tokens don't need a slow hash.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateNumericCode creates a random numeric code of the given length
+func GenerateNumericCode(length int) (string, error) {
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate code: %w", err)
+		}
+		code[i] = byte('0' + n.Int64())
+	}
+
+	return string(code), nil
+}
@@ -42,3 +42,17 @@ func TestGenerateRandomString(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, str, 43)
 }
+
+func TestHashToken(t *testing.T) {
+	hash := HashToken("token")
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, HashToken("token"))
+	assert.NotEqual(t, hash, HashToken("other"))
+}
+
+func TestGenerateNumericCode(t *testing.T) {
+	code, err := GenerateNumericCode(6)
+	assert.NoError(t, err)
+	assert.Len(t, code, 6)
+	assert.Regexp(t, `^[0-9]{6}$`, code)
+}
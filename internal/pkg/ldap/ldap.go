@@ -0,0 +1,282 @@
+// Package ldap authenticates users against an LDAP directory such as Active Directory
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+// defaultTimeout the default time to wait for the directory
+const defaultTimeout = 10 * time.Second
+
+// ErrInvalidCredentials returned when no single user matches the username
+// or the password is wrong
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Config the configuration of a directory
+type Config struct {
+	// Tenant the ID of the tenant the directory's users log in to, empty for the default tenant
+	Tenant string `json:"tenant"`
+	// Name the name of the directory in the login methods, e.g. corp
+	Name string `json:"name"`
+	// URL the address of the server, ldap://host:389 or ldaps://host:636
+	URL      string `json:"url"`
+	StartTLS bool   `json:"start_tls"`
+	// InsecureSkipVerify disables the verification of the server's certificate
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+	// BindDN and BindPassword the service account the users are searched with
+	BindDN       string `json:"bind_dn"`
+	BindPassword string `json:"bind_password"`
+	BaseDN       string `json:"base_dn"`
+	// UserFilter the filter of the user search, {username} is replaced
+	// with the escaped username, e.g. (&(objectClass=user)(sAMAccountName={username}))
+	UserFilter string     `json:"user_filter"`
+	Attributes Attributes `json:"attributes"`
+	// TrustEmail whether the email attribute is verified, i.e. users can't change it.
+	// Otherwise the users aren't linked to local users by email.
+	TrustEmail bool `json:"trust_email"`
+	// GroupRoles maps the DNs of groups to the roles their members get
+	GroupRoles map[string][]string `json:"group_roles"`
+	// Timeout the time to wait for the directory, 10 seconds by default
+	Timeout time.Duration `json:"-"`
+}
+
+// Attributes the names of the attributes the user is mapped from,
+// the Active Directory ones by default
+type Attributes struct {
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	DisplayName string `json:"display_name"`
+	Groups      string `json:"groups"`
+}
+
+// Entry the user found in the directory
+type Entry struct {
+	DN          string
+	Username    string
+	Email       string
+	DisplayName string
+	Groups      []string
+	// Roles the roles mapped from the groups
+	Roles []string
+}
+
+// Directory an LDAP directory. Every authentication uses a new connection.
+type Directory struct {
+	config Config
+}
+
+// NewDirectory creates a new object of 'Directory' type
+// and returns a pointer to it.
+func NewDirectory(config Config) *Directory {
+	if config.Attributes.Username == "" {
+		config.Attributes.Username = "sAMAccountName"
+	}
+	if config.Attributes.Email == "" {
+		config.Attributes.Email = "mail"
+	}
+	if config.Attributes.DisplayName == "" {
+		config.Attributes.DisplayName = "displayName"
+	}
+	if config.Attributes.Groups == "" {
+		config.Attributes.Groups = "memberOf"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+
+	// group DNs are compared case-insensitively
+	groupRoles := make(map[string][]string, len(config.GroupRoles))
+	for group, roles := range config.GroupRoles {
+		groupRoles[strings.ToLower(group)] = roles
+	}
+	config.GroupRoles = groupRoles
+	return &Directory{config: config}
+}
+
+// Name returns the name of the directory
+func (d *Directory) Name() string {
+	return d.config.Name
+}
+
+// TrustsEmail reports whether the directory vouches for the emails of its users
+func (d *Directory) TrustsEmail() bool {
+	return d.config.TrustEmail
+}
+
+// MappedRoles returns all roles the groups are mapped to
+func (d *Directory) MappedRoles() []string {
+	var roles []string
+	for _, groupRoles := range d.config.GroupRoles {
+		roles = append(roles, groupRoles...)
+	}
+	slices.Sort(roles)
+	return slices.Compact(roles)
+}
+
+// Authenticate finds the user with the service account and verifies the password
+// by binding as the user
+func (d *Directory) Authenticate(ctx context.Context, username, password string) (*Entry, error) {
+	// an empty password would be an unauthenticated bind, which servers accept
+	if strings.TrimSpace(username) == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, closeConn, err := d.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeConn()
+
+	entry, err := d.search(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to bind user: %w", err)
+	}
+	return d.entry(entry), nil
+}
+
+// Lookup finds the user with the service account without verifying a password.
+// It returns ErrInvalidCredentials if no single user matches the username.
+func (d *Directory) Lookup(ctx context.Context, username string) (*Entry, error) {
+	if strings.TrimSpace(username) == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, closeConn, err := d.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeConn()
+
+	entry, err := d.search(conn, username)
+	if err != nil {
+		return nil, err
+	}
+	return d.entry(entry), nil
+}
+
+// connect dials the server and binds as the service account if configured.
+// The connection is closed when the context is done or by the returned function.
+func (d *Directory) connect(ctx context.Context) (*goldap.Conn, func(), error) {
+	conn, err := d.dial()
+	if err != nil {
+		return nil, nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	closeConn := func() {
+		stop()
+		conn.Close()
+	}
+
+	if d.config.BindDN != "" {
+		if err := conn.Bind(d.config.BindDN, d.config.BindPassword); err != nil {
+			closeConn()
+			return nil, nil, fmt.Errorf("failed to bind service account: %w", err)
+		}
+	}
+	return conn, closeConn, nil
+}
+
+// search finds the single user matching the username
+func (d *Directory) search(conn *goldap.Conn, username string) (*goldap.Entry, error) {
+	attributes := []string{d.config.Attributes.Username, d.config.Attributes.Email,
+		d.config.Attributes.DisplayName, d.config.Attributes.Groups}
+	filter := strings.ReplaceAll(d.config.UserFilter, "{username}", goldap.EscapeFilter(strings.TrimSpace(username)))
+	res, err := conn.Search(goldap.NewSearchRequest(d.config.BaseDN, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases,
+		2, int(d.config.Timeout.Seconds()), false, filter, attributes, nil))
+	if err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultSizeLimitExceeded) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to search user: %w", err)
+	}
+	if len(res.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	return res.Entries[0], nil
+}
+
+// entry maps the directory entry to the user
+func (d *Directory) entry(entry *goldap.Entry) *Entry {
+	groups := entry.GetAttributeValues(d.config.Attributes.Groups)
+	return &Entry{
+		DN:          entry.DN,
+		Username:    entry.GetAttributeValue(d.config.Attributes.Username),
+		Email:       entry.GetAttributeValue(d.config.Attributes.Email),
+		DisplayName: entry.GetAttributeValue(d.config.Attributes.DisplayName),
+		Groups:      groups,
+		Roles:       d.roles(groups),
+	}
+}
+
+// dial connects to the server, upgrading the connection to TLS if configured
+func (d *Directory) dial() (*goldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: d.config.InsecureSkipVerify}
+	conn, err := goldap.DialURL(d.config.URL,
+		goldap.DialWithDialer(&net.Dialer{Timeout: d.config.Timeout}),
+		goldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	conn.SetTimeout(d.config.Timeout)
+
+	if d.config.StartTLS {
+		if u, err := url.Parse(d.config.URL); err == nil {
+			tlsConfig.ServerName = u.Hostname()
+		}
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// roles returns the roles the groups are mapped to
+func (d *Directory) roles(groups []string) []string {
+	roles := []string{}
+	for _, group := range groups {
+		roles = append(roles, d.config.GroupRoles[strings.ToLower(group)]...)
+	}
+	slices.Sort(roles)
+	return slices.Compact(roles)
+}
+
+// LoadConfigs reads the configurations of the directories from the JSON file
+func LoadConfigs(path string) ([]Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directories file: %w", err)
+	}
+
+	var configs []Config
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse directories file: %w", err)
+	}
+	for _, config := range configs {
+		if config.Name == "" || config.URL == "" || config.BaseDN == "" ||
+			!strings.Contains(config.UserFilter, "{username}") {
+			return nil, fmt.Errorf("invalid directory %q: name, url, base_dn and user_filter with {username} are required",
+				config.Name)
+		}
+	}
+	return configs, nil
+}
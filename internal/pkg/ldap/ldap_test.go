@@ -0,0 +1,124 @@
+package ldap_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AlexFox86/auth-service/internal/pkg/ldap"
+	"github.com/AlexFox86/auth-service/internal/pkg/ldap/ldaptest"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	baseDN     = "dc=corp,dc=example,dc=com"
+	serviceDN  = "cn=svc-auth,ou=service," + baseDN
+	userFilter = "(&(objectClass=user)(sAMAccountName={username}))"
+	adminsDN   = "cn=Admins,ou=groups," + baseDN
+	auditorsDN = "cn=Auditors,ou=groups," + baseDN
+)
+
+func newStubDirectory(t *testing.T) (*ldaptest.Server, *ldap.Directory) {
+	t.Helper()
+
+	stub, err := ldaptest.NewServer()
+	assert.NoError(t, err)
+	stub.AddEntry(serviceDN, "svc-secret", map[string][]string{"objectClass": {"user"}, "sAMAccountName": {"svc-auth"}})
+	stub.AddEntry("cn=Alex Fox,ou=users,"+baseDN, "password123", map[string][]string{
+		"objectClass":    {"user"},
+		"sAMAccountName": {"afox"},
+		"mail":           {"alex@corp.example.com"},
+		"displayName":    {"Alex Fox"},
+		"memberOf":       {"CN=Admins,OU=Groups," + baseDN},
+	})
+
+	config := stub.Config("corp", serviceDN, "svc-secret", baseDN, userFilter)
+	config.GroupRoles = map[string][]string{adminsDN: {"admin"}, auditorsDN: {"auditor"}}
+	return stub, ldap.NewDirectory(config)
+}
+
+func TestDirectoryAuthenticate(t *testing.T) {
+	stub, directory := newStubDirectory(t)
+	defer stub.Close()
+
+	t.Run("success", func(t *testing.T) {
+		entry, err := directory.Authenticate(context.Background(), "AFox", "password123")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "cn=Alex Fox,ou=users,"+baseDN, entry.DN)
+			assert.Equal(t, "afox", entry.Username)
+			assert.Equal(t, "alex@corp.example.com", entry.Email)
+			assert.Equal(t, "Alex Fox", entry.DisplayName)
+			assert.Equal(t, []string{"admin"}, entry.Roles)
+		}
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		_, err := directory.Authenticate(context.Background(), "afox", "wrong")
+		assert.ErrorIs(t, err, ldap.ErrInvalidCredentials)
+	})
+
+	t.Run("empty password", func(t *testing.T) {
+		_, err := directory.Authenticate(context.Background(), "afox", "")
+		assert.ErrorIs(t, err, ldap.ErrInvalidCredentials)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		_, err := directory.Authenticate(context.Background(), "nobody", "password123")
+		assert.ErrorIs(t, err, ldap.ErrInvalidCredentials)
+	})
+
+	t.Run("filter injection", func(t *testing.T) {
+		_, err := directory.Authenticate(context.Background(), "*)(sAMAccountName=afox", "password123")
+		assert.ErrorIs(t, err, ldap.ErrInvalidCredentials)
+	})
+
+	t.Run("wrong service account password", func(t *testing.T) {
+		config := stub.Config("corp", serviceDN, "wrong", baseDN, userFilter)
+		_, err := ldap.NewDirectory(config).Authenticate(context.Background(), "afox", "password123")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ldap.ErrInvalidCredentials)
+	})
+}
+
+func TestDirectoryLookup(t *testing.T) {
+	stub, directory := newStubDirectory(t)
+	defer stub.Close()
+
+	entry, err := directory.Lookup(context.Background(), "AFox")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "cn=Alex Fox,ou=users,"+baseDN, entry.DN)
+		assert.Equal(t, "afox", entry.Username)
+	}
+
+	_, err = directory.Lookup(context.Background(), "nobody")
+	assert.ErrorIs(t, err, ldap.ErrInvalidCredentials)
+	_, err = directory.Lookup(context.Background(), " ")
+	assert.ErrorIs(t, err, ldap.ErrInvalidCredentials)
+}
+
+func TestDirectoryMappedRoles(t *testing.T) {
+	directory := ldap.NewDirectory(ldap.Config{GroupRoles: map[string][]string{
+		adminsDN:   {"admin", "auditor"},
+		auditorsDN: {"auditor"},
+	}})
+	assert.Equal(t, []string{"admin", "auditor"}, directory.MappedRoles())
+}
+
+func TestLoadConfigs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "directories.json")
+
+	valid := `[{"name": "corp", "url": "ldaps://dc.corp.example.com", "base_dn": "dc=corp",
+		"user_filter": "(sAMAccountName={username})"}]`
+	assert.NoError(t, os.WriteFile(path, []byte(valid), 0o600))
+	configs, err := ldap.LoadConfigs(path)
+	if assert.NoError(t, err) && assert.Len(t, configs, 1) {
+		assert.Equal(t, "corp", configs[0].Name)
+	}
+
+	invalid := `[{"name": "corp", "url": "ldaps://dc.corp.example.com", "base_dn": "dc=corp",
+		"user_filter": "(sAMAccountName=alex)"}]`
+	assert.NoError(t, os.WriteFile(path, []byte(invalid), 0o600))
+	_, err = ldap.LoadConfigs(path)
+	assert.Error(t, err)
+}
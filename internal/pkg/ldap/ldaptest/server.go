@@ -0,0 +1,262 @@
+// Package ldaptest provides an in-process LDAP server for tests
+package ldaptest
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/AlexFox86/auth-service/internal/pkg/ldap"
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+// LDAP operations and result codes the server uses
+const (
+	opBindRequest       = 0
+	opBindResponse      = 1
+	opUnbindRequest     = 2
+	opSearchRequest     = 3
+	opSearchResultEntry = 4
+	opSearchResultDone  = 5
+
+	resultSuccess                 = 0
+	resultProtocolError           = 2
+	resultInvalidCredentials      = 49
+	resultInsufficientAccessRight = 50
+	resultUnwillingToPerform      = 53
+)
+
+// filter choices of the search request
+const (
+	filterAnd           = 0
+	filterOr            = 1
+	filterNot           = 2
+	filterEqualityMatch = 3
+	filterPresent       = 7
+)
+
+// Server a minimal in-process LDAP server for tests. It supports simple binds
+// and subtree searches with and, or, not, equality and presence filters over
+// the entries added with AddEntry. Searches require a bound connection.
+type Server struct {
+	URL string
+
+	listener net.Listener
+	mu       sync.Mutex
+	entries  map[string]storedEntry
+}
+
+// storedEntry the entry of the server with the password of its simple bind
+type storedEntry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+// NewServer starts a new server on a local port. It must be closed after use.
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	s := &Server{
+		URL:      "ldap://" + listener.Addr().String(),
+		listener: listener,
+		entries:  make(map[string]storedEntry),
+	}
+	go s.serve()
+	return s, nil
+}
+
+// AddEntry adds the entry the password binds as. The attributes are matched case-insensitively.
+func (s *Server) AddEntry(dn, password string, attributes map[string][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attrs := make(map[string][]string, len(attributes))
+	for name, values := range attributes {
+		attrs[strings.ToLower(name)] = values
+	}
+	s.entries[strings.ToLower(dn)] = storedEntry{dn: dn, password: password, attributes: attrs}
+}
+
+// Config returns the configuration of a directory at the server
+func (s *Server) Config(name, bindDN, bindPassword, baseDN, userFilter string) ldap.Config {
+	return ldap.Config{Name: name, URL: s.URL, BindDN: bindDN, BindPassword: bindPassword,
+		BaseDN: baseDN, UserFilter: userFilter}
+}
+
+// Close stops the server
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle serves the requests of the connection until it is closed or unbound
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	bound := false
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		var responses []*ber.Packet
+		switch op.Tag {
+		case opBindRequest:
+			code := s.bind(op)
+			bound = code == resultSuccess
+			responses = append(responses, result(opBindResponse, code))
+		case opSearchRequest:
+			if !bound {
+				responses = append(responses, result(opSearchResultDone, resultInsufficientAccessRight))
+				break
+			}
+			responses = s.search(op)
+		case opUnbindRequest:
+			return
+		default:
+			responses = append(responses, result(opSearchResultDone, resultUnwillingToPerform))
+		}
+
+		for _, response := range responses {
+			message := ber.NewSequence("LDAP Response")
+			message.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+			message.AppendChild(response)
+			if _, err := conn.Write(message.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// bind checks the simple bind request
+func (s *Server) bind(op *ber.Packet) int {
+	if len(op.Children) < 3 || op.Children[2].Tag != 0 {
+		return resultProtocolError
+	}
+	dn := strings.ToLower(stringValue(op.Children[1]))
+	password := op.Children[2].Data.String()
+
+	s.mu.Lock()
+	entry, ok := s.entries[dn]
+	s.mu.Unlock()
+	if !ok || password == "" || entry.password != password {
+		return resultInvalidCredentials
+	}
+	return resultSuccess
+}
+
+// search returns the entries under the base object matching the filter
+func (s *Server) search(op *ber.Packet) []*ber.Packet {
+	if len(op.Children) < 8 {
+		return []*ber.Packet{result(opSearchResultDone, resultProtocolError)}
+	}
+	base := strings.ToLower(stringValue(op.Children[0]))
+	filter := op.Children[6]
+	var attributes []string
+	for _, attr := range op.Children[7].Children {
+		attributes = append(attributes, stringValue(attr))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var responses []*ber.Packet
+	for dn, entry := range s.entries {
+		if dn != base && !strings.HasSuffix(dn, ","+base) || !matches(filter, entry) {
+			continue
+		}
+
+		resultEntry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, opSearchResultEntry, nil, "Search Result Entry")
+		resultEntry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "DN"))
+		attrs := ber.NewSequence("Attributes")
+		for _, name := range attributes {
+			values, ok := entry.attributes[strings.ToLower(name)]
+			if !ok {
+				continue
+			}
+			attr := ber.NewSequence("Attribute")
+			attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+			for _, value := range values {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+			}
+			attr.AppendChild(set)
+			attrs.AppendChild(attr)
+		}
+		resultEntry.AppendChild(attrs)
+		responses = append(responses, resultEntry)
+	}
+	return append(responses, result(opSearchResultDone, resultSuccess))
+}
+
+// matches evaluates the search filter on the entry
+func matches(filter *ber.Packet, entry storedEntry) bool {
+	switch filter.Tag {
+	case filterAnd:
+		for _, child := range filter.Children {
+			if !matches(child, entry) {
+				return false
+			}
+		}
+		return true
+	case filterOr:
+		for _, child := range filter.Children {
+			if matches(child, entry) {
+				return true
+			}
+		}
+		return false
+	case filterNot:
+		return len(filter.Children) == 1 && !matches(filter.Children[0], entry)
+	case filterEqualityMatch:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		value := stringValue(filter.Children[1])
+		for _, v := range entry.attributes[strings.ToLower(stringValue(filter.Children[0]))] {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	case filterPresent:
+		_, ok := entry.attributes[strings.ToLower(filter.Data.String())]
+		return ok
+	default:
+		return false
+	}
+}
+
+// result returns the LDAP result of the operation with the result code
+func result(op ber.Tag, code int) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, op, nil, "Result")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return packet
+}
+
+// stringValue returns the octet string of the packet
+func stringValue(packet *ber.Packet) string {
+	if value, ok := packet.Value.(string); ok {
+		return value
+	}
+	return packet.Data.String()
+}
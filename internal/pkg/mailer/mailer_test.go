@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryMailer(t *testing.T) {
+	m := NewMemoryMailer()
+
+	_, ok := m.Last()
+	assert.False(t, ok)
+
+	msg := Message{To: "test@example.com", Subject: "subject", Body: "body"}
+	err := m.Send(context.Background(), msg)
+	assert.NoError(t, err)
+
+	last, ok := m.Last()
+	assert.True(t, ok)
+	assert.Equal(t, msg, last)
+	assert.Len(t, m.Messages(), 1)
+}
+
+func TestNewSMTPMailer(t *testing.T) {
+	t.Run("without auth", func(t *testing.T) {
+		m := NewSMTPMailer("localhost", "25", "", "", "noreply@example.com")
+		assert.Equal(t, "localhost:25", m.addr)
+		assert.Nil(t, m.auth)
+	})
+
+	t.Run("with auth", func(t *testing.T) {
+		m := NewSMTPMailer("localhost", "587", "user", "pass", "noreply@example.com")
+		assert.NotNil(t, m.auth)
+	})
+}
@@ -0,0 +1,352 @@
+// Package oidc implements the relying party of the OpenID Connect
+// authorization code flow with upstream identity providers
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// minKeysRefresh the minimum time between fetches of the provider's keys,
+// so tokens with unknown key IDs can't make us flood the provider
+const minKeysRefresh = time.Minute
+
+// ErrInvalidIDToken returned when the ID token isn't signed by the provider,
+// is expired, or is issued for another client or login
+var ErrInvalidIDToken = errors.New("invalid id token")
+
+// Config the configuration of an upstream provider
+type Config struct {
+	// Name the name of the provider in URLs, e.g. google
+	Name         string   `json:"name"`
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Scopes       []string `json:"scopes"`
+}
+
+// Claims the identity claims of a validated ID token
+type Claims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+	Nonce             string
+}
+
+// metadata the fields of the provider's discovery document we use
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider an upstream OpenID provider. The discovery document and the keys
+// are fetched on first use and the keys again when a token is signed with an unknown key.
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu          sync.Mutex
+	metadata    *metadata
+	keys        map[string]*rsa.PublicKey
+	keysFetched time.Time
+}
+
+// NewProvider creates a new object of 'Provider' type
+// and returns a pointer to it.
+func NewProvider(config Config, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+	return &Provider{config: config, client: client}
+}
+
+// Name returns the name of the provider
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// AuthCodeURL returns the URL of the provider's login page which redirects
+// back to the redirectURL with the code and the state
+func (p *Provider) AuthCodeURL(ctx context.Context, redirectURL, state, nonce string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"response_type": {"code"},
+		"client_id":     {p.config.ClientID},
+		"redirect_uri":  {redirectURL},
+		"scope":         {strings.Join(p.config.Scopes, " ")},
+		"state":         {state},
+		"nonce":         {nonce},
+	}
+	separator := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return meta.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems the authorization code and returns the claims of the ID token,
+// which must be issued for the login with the nonce
+func (p *Provider) Exchange(ctx context.Context, redirectURL, code, nonce string) (*Claims, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {redirectURL},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	var resp struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := p.doJSON(req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+	if resp.IDToken == "" {
+		return nil, fmt.Errorf("%w: no id_token in the token response", ErrInvalidIDToken)
+	}
+
+	return p.VerifyIDToken(ctx, resp.IDToken, nonce)
+}
+
+// VerifyIDToken checks the signature of the ID token with the provider's keys,
+// its issuer, audience, expiration time and nonce
+func (p *Provider) VerifyIDToken(ctx context.Context, rawToken, nonce string) (*Claims, error) {
+	parsed, err := jwt.Parse(rawToken, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	mapClaims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok || !parsed.Valid {
+		return nil, ErrInvalidIDToken
+	}
+
+	if iss, _ := mapClaims["iss"].(string); strings.TrimSuffix(iss, "/") != p.config.Issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, iss)
+	}
+	if !hasAudience(mapClaims["aud"], p.config.ClientID) {
+		return nil, fmt.Errorf("%w: issued for another client", ErrInvalidIDToken)
+	}
+	if _, ok := mapClaims["exp"]; !ok {
+		return nil, fmt.Errorf("%w: no expiration time", ErrInvalidIDToken)
+	}
+
+	claims := Claims{
+		Subject:           stringClaim(mapClaims, "sub"),
+		Email:             stringClaim(mapClaims, "email"),
+		Name:              stringClaim(mapClaims, "name"),
+		PreferredUsername: stringClaim(mapClaims, "preferred_username"),
+		Nonce:             stringClaim(mapClaims, "nonce"),
+	}
+	// some providers send email_verified as a string
+	switch verified := mapClaims["email_verified"].(type) {
+	case bool:
+		claims.EmailVerified = verified
+	case string:
+		claims.EmailVerified = verified == "true"
+	}
+
+	if claims.Subject == "" || claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: unexpected nonce", ErrInvalidIDToken)
+	}
+	return &claims, nil
+}
+
+func stringClaim(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// hasAudience reports whether the aud claim, a string or a list, contains the client
+func hasAudience(aud any, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []any:
+		for _, item := range v {
+			if item == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// discover fetches the discovery document once
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery request: %w", err)
+	}
+	var meta metadata
+	if err := p.doJSON(req, &meta); err != nil {
+		return nil, fmt.Errorf("failed to discover provider %s: %w", p.config.Name, err)
+	}
+	if strings.TrimSuffix(meta.Issuer, "/") != p.config.Issuer {
+		return nil, fmt.Errorf("provider %s: discovered issuer %q doesn't match", p.config.Name, meta.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("provider %s: incomplete discovery document", p.config.Name)
+	}
+
+	p.metadata = &meta
+	return p.metadata, nil
+}
+
+// key returns the provider's signing key with the ID, fetching the keys
+// if it is unknown, e.g. after a key rotation
+func (p *Provider) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetched) < minKeysRefresh {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	keys, err := p.fetchKeys(ctx, meta.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	p.keys = keys
+	p.keysFetched = time.Now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// lookupKey returns the key with the ID, or the only key if the token has no key ID
+func (p *Provider) lookupKey(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// fetchKeys fetches the RSA signing keys of the JWK set
+func (p *Provider) fetchKeys(ctx context.Context, jwksURI string) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jwks request: %w", err)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.doJSON(req, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch keys of provider %s: %w", p.config.Name, err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	return keys, nil
+}
+
+// doJSON sends the request and decodes the JSON response
+func (p *Provider) doJSON(req *http.Request, v any) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// LoadConfigs reads the JSON file with the list of provider configurations
+func LoadConfigs(path string) ([]Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read providers file: %w", err)
+	}
+
+	var configs []Config
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse providers file: %w", err)
+	}
+	for _, config := range configs {
+		if config.Name == "" || strings.ContainsAny(config.Name, "/ ") || config.Issuer == "" || config.ClientID == "" {
+			return nil, fmt.Errorf("invalid provider %q: name, issuer and client_id are required", config.Name)
+		}
+	}
+	return configs, nil
+}
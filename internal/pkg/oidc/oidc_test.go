@@ -0,0 +1,130 @@
+package oidc_test
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/pkg/oidc"
+	"github.com/AlexFox86/auth-service/internal/pkg/oidc/oidctest"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+const redirectURL = "https://auth.example.com/login/oidc/stub/callback"
+
+func TestProviderFlow(t *testing.T) {
+	stub, err := oidctest.NewServer("client", "secret")
+	assert.NoError(t, err)
+	defer stub.Close()
+	provider := oidc.NewProvider(stub.Config("stub"), nil)
+
+	authURL, err := provider.AuthCodeURL(context.Background(), redirectURL, "state", "nonce")
+	assert.NoError(t, err)
+	u, _ := url.Parse(authURL)
+	assert.Equal(t, stub.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	assert.Equal(t, "openid email profile", u.Query().Get("scope"))
+	assert.Equal(t, redirectURL, u.Query().Get("redirect_uri"))
+	assert.Equal(t, "state", u.Query().Get("state"))
+
+	code, err := stub.Authorize(authURL, map[string]any{
+		"sub":            "12345",
+		"email":          "alex@example.com",
+		"email_verified": "true",
+		"name":           "Alex",
+	})
+	assert.NoError(t, err)
+
+	claims, err := provider.Exchange(context.Background(), redirectURL, code, "nonce")
+	assert.NoError(t, err)
+	assert.Equal(t, &oidc.Claims{
+		Subject:       "12345",
+		Email:         "alex@example.com",
+		EmailVerified: true,
+		Name:          "Alex",
+		Nonce:         "nonce",
+	}, claims)
+
+	_, err = provider.Exchange(context.Background(), redirectURL, code, "nonce")
+	assert.Error(t, err, "codes are single-use")
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	stub, err := oidctest.NewServer("client", "secret")
+	assert.NoError(t, err)
+	defer stub.Close()
+	provider := oidc.NewProvider(stub.Config("stub"), nil)
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   stub.URL,
+			"aud":   []string{"other", "client"},
+			"sub":   "12345",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": "nonce",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(jwt.MapClaims)
+		ok     bool
+	}{
+		{name: "valid", modify: func(jwt.MapClaims) {}, ok: true},
+		{name: "another issuer", modify: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{name: "another audience", modify: func(c jwt.MapClaims) { c["aud"] = "other" }},
+		{name: "expired", modify: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{name: "no expiration", modify: func(c jwt.MapClaims) { delete(c, "exp") }},
+		{name: "another nonce", modify: func(c jwt.MapClaims) { c["nonce"] = "replayed" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.modify(claims)
+			idToken, err := stub.SignIDToken(claims)
+			assert.NoError(t, err)
+
+			_, err = provider.VerifyIDToken(context.Background(), idToken, "nonce")
+			if tt.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+			}
+		})
+	}
+
+	t.Run("signed with another key", func(t *testing.T) {
+		other, err := oidctest.NewServer("client", "secret")
+		assert.NoError(t, err)
+		defer other.Close()
+
+		idToken, err := other.SignIDToken(valid())
+		assert.NoError(t, err)
+		_, err = provider.VerifyIDToken(context.Background(), idToken, "nonce")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+
+	t.Run("hmac signed", func(t *testing.T) {
+		idToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, valid()).SignedString([]byte("secret"))
+		_, err := provider.VerifyIDToken(context.Background(), idToken, "nonce")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+}
+
+func TestLoadConfigs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "providers.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`[{"name": "google", "issuer": "https://accounts.google.com",
+		"client_id": "id", "client_secret": "secret"}]`), 0o600))
+
+	configs, err := oidc.LoadConfigs(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []oidc.Config{{Name: "google", Issuer: "https://accounts.google.com", ClientID: "id", ClientSecret: "secret"}}, configs)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`[{"name": "no issuer", "client_id": "id"}]`), 0o600))
+	_, err = oidc.LoadConfigs(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Requests: 3, Per: 3 * time.Second}
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		res, err := store.Take(ctx, "key", limit)
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 3, res.Limit)
+		assert.Equal(t, i, res.Remaining)
+	}
+
+	res, err := store.Take(ctx, "key", limit)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 3*time.Second, res.Reset)
+
+	res, _ = store.Take(ctx, "other", limit)
+	assert.True(t, res.Allowed, "keys have separate buckets")
+
+	now = now.Add(time.Second)
+	res, _ = store.Take(ctx, "key", limit)
+	assert.True(t, res.Allowed, "a token is added per second")
+	assert.Equal(t, 0, res.Remaining)
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Requests: 10, Per: time.Second}
+
+	store.Take(context.Background(), "key", limit)
+	assert.Len(t, store.buckets, 1)
+
+	now = now.Add(2 * sweepInterval)
+	store.Take(context.Background(), "other", limit)
+	assert.Len(t, store.buckets, 1)
+	assert.Contains(t, store.buckets, "other")
+}
@@ -0,0 +1,405 @@
+// Package saml implements the service provider of SAML 2.0 web browser SSO.
+// Authentication requests use the HTTP-Redirect binding and responses the HTTP-POST binding.
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+// SAML namespaces, bindings and values
+const (
+	protocolNamespace  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	assertionNamespace = "urn:oasis:names:tc:SAML:2.0:assertion"
+	metadataNamespace  = "urn:oasis:names:tc:SAML:2.0:metadata"
+	postBinding        = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	statusSuccess      = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	bearerMethod       = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+	emailNameIDFormat  = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+)
+
+// maxClockSkew the difference between the clocks of the IdP and ours that is tolerated
+const maxClockSkew = 2 * time.Minute
+
+// ErrInvalidResponse returned when the response isn't signed by the IdP, isn't
+// for this service provider or request, or is expired
+var ErrInvalidResponse = errors.New("invalid saml response")
+
+// Config the configuration of an identity provider
+type Config struct {
+	// Tenant the ID of the tenant the IdP signs in to, empty for the default tenant
+	Tenant string `json:"tenant"`
+	// Name the name of the IdP in URLs, e.g. okta
+	Name string `json:"name"`
+	// EntityID the issuer of the IdP's assertions
+	EntityID string `json:"entity_id"`
+	// SSOURL the single sign-on URL of the HTTP-Redirect binding
+	SSOURL string `json:"sso_url"`
+	// Certificate the PEM of the certificate the IdP signs with
+	Certificate string     `json:"certificate"`
+	Attributes  Attributes `json:"attributes"`
+	// TrustEmail whether the email attribute is verified, i.e. users can't change it.
+	// Otherwise the users aren't linked to local users by email.
+	TrustEmail bool `json:"trust_email"`
+}
+
+// Attributes the names of the attributes the user is mapped from.
+// An attribute matches by its name or friendly name.
+type Attributes struct {
+	Email       string `json:"email"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+}
+
+// ServiceProvider the URLs of the service provider, which differ per IdP and tenant
+type ServiceProvider struct {
+	EntityID string
+	ACSURL   string
+}
+
+// Assertion the validated assertion about the user
+type Assertion struct {
+	ID          string
+	NameID      string
+	Email       string
+	Username    string
+	DisplayName string
+	// ExpiresAt the time the assertion can't be used after
+	ExpiresAt time.Time
+}
+
+// IdentityProvider an upstream SAML identity provider
+type IdentityProvider struct {
+	config Config
+	cert   *x509.Certificate
+}
+
+// NewIdentityProvider creates a new object of 'IdentityProvider' type
+// and returns a pointer to it.
+func NewIdentityProvider(config Config) (*IdentityProvider, error) {
+	block, _ := pem.Decode([]byte(config.Certificate))
+	if block == nil {
+		return nil, fmt.Errorf("invalid certificate of idp %q", config.Name)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate of idp %q: %w", config.Name, err)
+	}
+
+	if config.Attributes.Email == "" {
+		config.Attributes.Email = "email"
+	}
+	if config.Attributes.Username == "" {
+		config.Attributes.Username = "username"
+	}
+	if config.Attributes.DisplayName == "" {
+		config.Attributes.DisplayName = "displayName"
+	}
+	return &IdentityProvider{config: config, cert: cert}, nil
+}
+
+// Name returns the name of the IdP
+func (p *IdentityProvider) Name() string {
+	return p.config.Name
+}
+
+// TrustsEmail reports whether the IdP vouches for the emails it asserts
+func (p *IdentityProvider) TrustsEmail() bool {
+	return p.config.TrustEmail
+}
+
+// Tenant returns the ID of the tenant the IdP signs in to
+func (p *IdentityProvider) Tenant() string {
+	return p.config.Tenant
+}
+
+// NewRequestID returns a random ID of an authentication request
+func NewRequestID() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate request id: %w", err)
+	}
+	// IDs must not start with a digit
+	return "_" + hex.EncodeToString(b), nil
+}
+
+// Metadata returns the metadata document of the service provider
+func Metadata(sp ServiceProvider) ([]byte, error) {
+	doc := etree.NewDocument()
+	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
+	entity := doc.CreateElement("md:EntityDescriptor")
+	entity.CreateAttr("xmlns:md", metadataNamespace)
+	entity.CreateAttr("entityID", sp.EntityID)
+
+	descriptor := entity.CreateElement("md:SPSSODescriptor")
+	descriptor.CreateAttr("AuthnRequestsSigned", "false")
+	descriptor.CreateAttr("WantAssertionsSigned", "true")
+	descriptor.CreateAttr("protocolSupportEnumeration", protocolNamespace)
+
+	acs := descriptor.CreateElement("md:AssertionConsumerService")
+	acs.CreateAttr("Binding", postBinding)
+	acs.CreateAttr("Location", sp.ACSURL)
+	acs.CreateAttr("index", "0")
+	acs.CreateAttr("isDefault", "true")
+
+	doc.Indent(2)
+	return doc.WriteToBytes()
+}
+
+// AuthnRequestURL returns the URL of the IdP's login page with the authentication request
+func (p *IdentityProvider) AuthnRequestURL(sp ServiceProvider, requestID string, now time.Time) (string, error) {
+	doc := etree.NewDocument()
+	req := doc.CreateElement("samlp:AuthnRequest")
+	req.CreateAttr("xmlns:samlp", protocolNamespace)
+	req.CreateAttr("xmlns:saml", assertionNamespace)
+	req.CreateAttr("ID", requestID)
+	req.CreateAttr("Version", "2.0")
+	req.CreateAttr("IssueInstant", now.UTC().Format(time.RFC3339))
+	req.CreateAttr("Destination", p.config.SSOURL)
+	req.CreateAttr("AssertionConsumerServiceURL", sp.ACSURL)
+	req.CreateAttr("ProtocolBinding", postBinding)
+	req.CreateElement("saml:Issuer").SetText(sp.EntityID)
+	req.CreateElement("samlp:NameIDPolicy").CreateAttr("AllowCreate", "true")
+
+	data, err := doc.WriteToBytes()
+	if err != nil {
+		return "", fmt.Errorf("failed to encode authn request: %w", err)
+	}
+	var deflated bytes.Buffer
+	w, _ := flate.NewWriter(&deflated, flate.BestCompression)
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("failed to compress authn request: %w", err)
+	}
+
+	u, err := url.Parse(p.config.SSOURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid sso url: %w", err)
+	}
+	query := u.Query()
+	query.Set("SAMLRequest", base64.StdEncoding.EncodeToString(deflated.Bytes()))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// ParseResponse validates the base64-encoded response to the request and returns
+// its assertion. The response or the assertion must be signed by the IdP. Only
+// the signed XML is read, so elements added around the signed ones are ignored.
+func (p *IdentityProvider) ParseResponse(sp ServiceProvider, samlResponse, requestID string, now time.Time) (*Assertion, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(samlResponse), ""))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	response := doc.Root()
+	if response == nil || response.Tag != "Response" {
+		return nil, fmt.Errorf("%w: not a response", ErrInvalidResponse)
+	}
+
+	validator := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: []*x509.Certificate{p.cert}})
+	validator.Clock = dsig.NewFakeClockAt(now)
+
+	responseSigned := child(response, "Signature") != nil
+	if responseSigned {
+		if response, err = validator.Validate(response); err != nil {
+			return nil, fmt.Errorf("%w: response signature: %v", ErrInvalidResponse, err)
+		}
+	}
+
+	status := child(child(response, "Status"), "StatusCode")
+	if status == nil || status.SelectAttrValue("Value", "") != statusSuccess {
+		return nil, fmt.Errorf("%w: login failed at the idp", ErrInvalidResponse)
+	}
+	if responseSigned {
+		if destination := response.SelectAttrValue("Destination", ""); destination != "" && destination != sp.ACSURL {
+			return nil, fmt.Errorf("%w: wrong destination", ErrInvalidResponse)
+		}
+		if response.SelectAttrValue("InResponseTo", "") != requestID {
+			return nil, fmt.Errorf("%w: not a response to the request", ErrInvalidResponse)
+		}
+	}
+
+	assertions := children(response, "Assertion")
+	if len(assertions) != 1 {
+		return nil, fmt.Errorf("%w: exactly one unencrypted assertion required", ErrInvalidResponse)
+	}
+	assertion := assertions[0]
+	if !responseSigned {
+		if assertion, err = validator.Validate(assertion); err != nil {
+			return nil, fmt.Errorf("%w: assertion signature: %v", ErrInvalidResponse, err)
+		}
+	}
+
+	return p.checkAssertion(sp, assertion, requestID, now)
+}
+
+// checkAssertion checks the issuer, the subject confirmation and the conditions
+// of the signed assertion and maps its attributes
+func (p *IdentityProvider) checkAssertion(sp ServiceProvider, el *etree.Element, requestID string, now time.Time) (*Assertion, error) {
+	if text(child(el, "Issuer")) != p.config.EntityID {
+		return nil, fmt.Errorf("%w: wrong issuer", ErrInvalidResponse)
+	}
+
+	subject := child(el, "Subject")
+	nameID := child(subject, "NameID")
+	if text(nameID) == "" {
+		return nil, fmt.Errorf("%w: name id required", ErrInvalidResponse)
+	}
+
+	// the bearer confirmation binds the assertion to the request and our endpoint
+	var expiresAt time.Time
+	for _, confirmation := range children(subject, "SubjectConfirmation") {
+		data := child(confirmation, "SubjectConfirmationData")
+		if confirmation.SelectAttrValue("Method", "") != bearerMethod || data == nil ||
+			data.SelectAttrValue("Recipient", "") != sp.ACSURL ||
+			data.SelectAttrValue("InResponseTo", "") != requestID {
+			continue
+		}
+		notOnOrAfter, err := time.Parse(time.RFC3339, data.SelectAttrValue("NotOnOrAfter", ""))
+		if err != nil || !now.Before(notOnOrAfter.Add(maxClockSkew)) {
+			continue
+		}
+		expiresAt = notOnOrAfter
+	}
+	if expiresAt.IsZero() {
+		return nil, fmt.Errorf("%w: no valid bearer subject confirmation", ErrInvalidResponse)
+	}
+
+	conditions := child(el, "Conditions")
+	if conditions == nil {
+		return nil, fmt.Errorf("%w: conditions required", ErrInvalidResponse)
+	}
+	if notBefore := conditions.SelectAttrValue("NotBefore", ""); notBefore != "" {
+		t, err := time.Parse(time.RFC3339, notBefore)
+		if err != nil || now.Add(maxClockSkew).Before(t) {
+			return nil, fmt.Errorf("%w: not yet valid", ErrInvalidResponse)
+		}
+	}
+	if notOnOrAfter := conditions.SelectAttrValue("NotOnOrAfter", ""); notOnOrAfter != "" {
+		t, err := time.Parse(time.RFC3339, notOnOrAfter)
+		if err != nil || !now.Before(t.Add(maxClockSkew)) {
+			return nil, fmt.Errorf("%w: expired", ErrInvalidResponse)
+		}
+		if t.After(expiresAt) {
+			expiresAt = t
+		}
+	}
+	restrictions := children(conditions, "AudienceRestriction")
+	if len(restrictions) == 0 {
+		return nil, fmt.Errorf("%w: audience restriction required", ErrInvalidResponse)
+	}
+	for _, restriction := range restrictions {
+		if !hasAudience(restriction, sp.EntityID) {
+			return nil, fmt.Errorf("%w: wrong audience", ErrInvalidResponse)
+		}
+	}
+
+	attributes := make(map[string]string)
+	for _, attr := range children(child(el, "AttributeStatement"), "Attribute") {
+		value := text(child(attr, "AttributeValue"))
+		for _, name := range []string{attr.SelectAttrValue("Name", ""), attr.SelectAttrValue("FriendlyName", "")} {
+			if _, ok := attributes[name]; name != "" && !ok {
+				attributes[name] = value
+			}
+		}
+	}
+
+	assertion := &Assertion{
+		ID:          el.SelectAttrValue("ID", ""),
+		NameID:      text(nameID),
+		Email:       attributes[p.config.Attributes.Email],
+		Username:    attributes[p.config.Attributes.Username],
+		DisplayName: attributes[p.config.Attributes.DisplayName],
+		ExpiresAt:   expiresAt,
+	}
+	if assertion.ID == "" {
+		return nil, fmt.Errorf("%w: assertion id required", ErrInvalidResponse)
+	}
+	if assertion.Email == "" && nameID.SelectAttrValue("Format", "") == emailNameIDFormat {
+		assertion.Email = assertion.NameID
+	}
+	return assertion, nil
+}
+
+// hasAudience reports whether the audience restriction contains the audience
+func hasAudience(restriction *etree.Element, audience string) bool {
+	for _, el := range children(restriction, "Audience") {
+		if text(el) == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// child returns the first child element with the local name, nil for a nil element
+func child(el *etree.Element, tag string) *etree.Element {
+	if el == nil {
+		return nil
+	}
+	for _, c := range el.ChildElements() {
+		if c.Tag == tag {
+			return c
+		}
+	}
+	return nil
+}
+
+// children returns the child elements with the local name
+func children(el *etree.Element, tag string) []*etree.Element {
+	if el == nil {
+		return nil
+	}
+	var elements []*etree.Element
+	for _, c := range el.ChildElements() {
+		if c.Tag == tag {
+			elements = append(elements, c)
+		}
+	}
+	return elements
+}
+
+// text returns the trimmed text of the element, empty for a nil element
+func text(el *etree.Element) string {
+	if el == nil {
+		return ""
+	}
+	return strings.TrimSpace(el.Text())
+}
+
+// LoadConfigs reads the configurations of the identity providers from the JSON file
+func LoadConfigs(path string) ([]Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read idps file: %w", err)
+	}
+
+	var configs []Config
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse idps file: %w", err)
+	}
+	for _, config := range configs {
+		if config.Name == "" || strings.ContainsAny(config.Name, "/ ") || config.EntityID == "" ||
+			config.SSOURL == "" || config.Certificate == "" {
+			return nil, fmt.Errorf("invalid idp %q: name, entity_id, sso_url and certificate are required", config.Name)
+		}
+	}
+	return configs, nil
+}
@@ -0,0 +1,242 @@
+package saml_test
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/pkg/saml"
+	"github.com/AlexFox86/auth-service/internal/pkg/saml/samltest"
+	"github.com/stretchr/testify/assert"
+)
+
+var sp = saml.ServiceProvider{
+	EntityID: "https://auth.example.com/login/saml/okta/metadata",
+	ACSURL:   "https://auth.example.com/login/saml/okta/acs",
+}
+
+// tamper replaces the text in the decoded response
+func tamper(t *testing.T, samlResponse, old, new string) string {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(samlResponse)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), old)
+	return base64.StdEncoding.EncodeToString([]byte(strings.Replace(string(data), old, new, 1)))
+}
+
+func TestParseResponse(t *testing.T) {
+	stub, err := samltest.NewIdP()
+	assert.NoError(t, err)
+	idp, err := saml.NewIdentityProvider(stub.Config("okta", ""))
+	assert.NoError(t, err)
+
+	requestID, err := saml.NewRequestID()
+	assert.NoError(t, err)
+	authURL, err := idp.AuthnRequestURL(sp, requestID, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(authURL, stub.SSOURL+"?SAMLRequest="))
+
+	samlResponse, err := stub.Respond(authURL, "00u1abcd", map[string]string{
+		"email":       "alex@corp.example.com",
+		"username":    "afox",
+		"displayName": "Alex Fox",
+	})
+	assert.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		assertion, err := idp.ParseResponse(sp, samlResponse, requestID, time.Now())
+		if assert.NoError(t, err) {
+			assert.NotEmpty(t, assertion.ID)
+			assert.Equal(t, "00u1abcd", assertion.NameID)
+			assert.Equal(t, "alex@corp.example.com", assertion.Email)
+			assert.Equal(t, "afox", assertion.Username)
+			assert.Equal(t, "Alex Fox", assertion.DisplayName)
+			assert.WithinDuration(t, time.Now().Add(5*time.Minute), assertion.ExpiresAt, time.Minute)
+		}
+	})
+
+	tests := []struct {
+		name         string
+		samlResponse string
+		sp           saml.ServiceProvider
+		requestID    string
+		now          time.Time
+	}{
+		{
+			name:         "tampered attribute",
+			samlResponse: tamper(t, samlResponse, "alex@corp.example.com", "admin@corp.example.com"),
+		},
+		{
+			name: "injected assertion",
+			samlResponse: tamper(t, samlResponse, "</samlp:Status>",
+				`</samlp:Status><saml:Assertion ID="_evil"><saml:Issuer>x</saml:Issuer></saml:Assertion>`),
+		},
+		{
+			name:         "unsigned response",
+			samlResponse: base64.StdEncoding.EncodeToString([]byte(`<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol"/>`)),
+		},
+		{
+			name: "wrong audience",
+			sp:   saml.ServiceProvider{EntityID: "https://other.example.com", ACSURL: sp.ACSURL},
+		},
+		{
+			name: "wrong recipient",
+			sp:   saml.ServiceProvider{EntityID: sp.EntityID, ACSURL: "https://other.example.com/acs"},
+		},
+		{
+			name:      "other request",
+			requestID: "_other",
+		},
+		{
+			name: "expired",
+			now:  time.Now().Add(time.Hour),
+		},
+		{
+			name: "not yet valid",
+			now:  time.Now().Add(-time.Hour),
+		},
+		{
+			name:         "not base64",
+			samlResponse: "<xml>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.samlResponse == "" {
+				tt.samlResponse = samlResponse
+			}
+			if tt.sp == (saml.ServiceProvider{}) {
+				tt.sp = sp
+			}
+			if tt.requestID == "" {
+				tt.requestID = requestID
+			}
+			if tt.now.IsZero() {
+				tt.now = time.Now()
+			}
+
+			_, err := idp.ParseResponse(tt.sp, tt.samlResponse, tt.requestID, tt.now)
+			assert.ErrorIs(t, err, saml.ErrInvalidResponse)
+		})
+	}
+
+	t.Run("other idp", func(t *testing.T) {
+		other, err := samltest.NewIdP()
+		assert.NoError(t, err)
+		otherIdP, err := saml.NewIdentityProvider(other.Config("okta", ""))
+		assert.NoError(t, err)
+
+		_, err = otherIdP.ParseResponse(sp, samlResponse, requestID, time.Now())
+		assert.ErrorIs(t, err, saml.ErrInvalidResponse)
+	})
+}
+
+func TestParseSignedResponse(t *testing.T) {
+	stub, err := samltest.NewIdP()
+	assert.NoError(t, err)
+	idp, err := saml.NewIdentityProvider(stub.Config("okta", ""))
+	assert.NoError(t, err)
+
+	requestID, err := saml.NewRequestID()
+	assert.NoError(t, err)
+	authURL, err := idp.AuthnRequestURL(sp, requestID, time.Now())
+	assert.NoError(t, err)
+	respond := func(opts ...samltest.ResponseOption) string {
+		samlResponse, err := stub.Respond(authURL, "00u1abcd", map[string]string{"email": "alex@corp.example.com"},
+			append([]samltest.ResponseOption{samltest.SignResponse()}, opts...)...)
+		assert.NoError(t, err)
+		return samlResponse
+	}
+	samlResponse := respond()
+
+	t.Run("success", func(t *testing.T) {
+		assertion, err := idp.ParseResponse(sp, samlResponse, requestID, time.Now())
+		if assert.NoError(t, err) {
+			assert.Equal(t, "00u1abcd", assertion.NameID)
+			assert.Equal(t, "alex@corp.example.com", assertion.Email)
+		}
+	})
+
+	// the assertion isn't signed on its own, so the response must stay intact
+	stripped := func() string {
+		data, err := base64.StdEncoding.DecodeString(samlResponse)
+		assert.NoError(t, err)
+		start, end := strings.Index(string(data), "<ds:Signature"), strings.Index(string(data), "</ds:Signature>")
+		assert.True(t, start > 0 && end > start)
+		return base64.StdEncoding.EncodeToString(append(data[:start:start], data[end+len("</ds:Signature>"):]...))
+	}
+
+	tests := []struct {
+		name         string
+		samlResponse string
+		requestID    string
+	}{
+		{
+			name:         "wrong destination",
+			samlResponse: respond(samltest.WithDestination("https://other.example.com/acs")),
+		},
+		{
+			name:         "tampered attribute",
+			samlResponse: tamper(t, samlResponse, "alex@corp.example.com", "admin@corp.example.com"),
+		},
+		{
+			name:         "tampered destination",
+			samlResponse: tamper(t, samlResponse, sp.ACSURL, sp.ACSURL+"?"),
+		},
+		{
+			name:         "stripped signature",
+			samlResponse: stripped(),
+		},
+		{
+			name:         "other request",
+			samlResponse: samlResponse,
+			requestID:    "_other",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.requestID == "" {
+				tt.requestID = requestID
+			}
+
+			_, err := idp.ParseResponse(sp, tt.samlResponse, tt.requestID, time.Now())
+			assert.ErrorIs(t, err, saml.ErrInvalidResponse)
+		})
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	metadata, err := saml.Metadata(sp)
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(metadata), `entityID="`+sp.EntityID+`"`)
+		assert.Contains(t, string(metadata), `Location="`+sp.ACSURL+`"`)
+		assert.Contains(t, string(metadata), `WantAssertionsSigned="true"`)
+	}
+}
+
+func TestLoadConfigs(t *testing.T) {
+	stub, err := samltest.NewIdP()
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "idps.json")
+
+	valid := `[{"tenant": "acme", "name": "okta", "entity_id": "https://idp.example.com",
+		"sso_url": "https://idp.example.com/sso", "certificate": ` + strconv.Quote(stub.Config("okta", "").Certificate) + `}]`
+	assert.NoError(t, os.WriteFile(path, []byte(valid), 0o600))
+	configs, err := saml.LoadConfigs(path)
+	if assert.NoError(t, err) && assert.Len(t, configs, 1) {
+		assert.Equal(t, "acme", configs[0].Tenant)
+		_, err := saml.NewIdentityProvider(configs[0])
+		assert.NoError(t, err)
+	}
+
+	invalid := `[{"name": "okta", "entity_id": "https://idp.example.com"}]`
+	assert.NoError(t, os.WriteFile(path, []byte(invalid), 0o600))
+	_, err = saml.LoadConfigs(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,218 @@
+// Package samltest provides a SAML identity provider for tests
+package samltest
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/pkg/saml"
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+// SAML namespaces and values of the responses
+const (
+	protocolNamespace  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	assertionNamespace = "urn:oasis:names:tc:SAML:2.0:assertion"
+	statusSuccess      = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	bearerMethod       = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+)
+
+// IdP a test identity provider. It answers the authentication requests of
+// the service provider with assertions it signs with a self-signed certificate.
+type IdP struct {
+	EntityID string
+	SSOURL   string
+
+	key  *rsa.PrivateKey
+	cert []byte
+}
+
+// NewIdP creates a new identity provider with a fresh key
+func NewIdP() (*IdP, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test idp"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	return &IdP{
+		EntityID: "https://idp.example.com/metadata",
+		SSOURL:   "https://idp.example.com/sso",
+		key:      key,
+		cert:     cert,
+	}, nil
+}
+
+// Config returns the configuration of the IdP for the tenant
+func (s *IdP) Config(name, tenant string) saml.Config {
+	return saml.Config{
+		Tenant:      tenant,
+		Name:        name,
+		EntityID:    s.EntityID,
+		SSOURL:      s.SSOURL,
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.cert})),
+	}
+}
+
+// ResponseOption changes the response of the IdP
+type ResponseOption func(*responseOptions)
+
+type responseOptions struct {
+	signResponse bool
+	destination  string
+}
+
+// SignResponse signs the whole response instead of the assertion
+func SignResponse() ResponseOption {
+	return func(o *responseOptions) {
+		o.signResponse = true
+	}
+}
+
+// WithDestination sets the destination of the response instead of the assertion
+// consumer service of the request
+func WithDestination(destination string) ResponseOption {
+	return func(o *responseOptions) {
+		o.destination = destination
+	}
+}
+
+// Respond returns the base64-encoded response to the authentication request in
+// the URL. The assertion has the name ID and the attributes and is signed.
+func (s *IdP) Respond(authURL, nameID string, attributes map[string]string, opts ...ResponseOption) (string, error) {
+	var options responseOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+	deflated, err := base64.StdEncoding.DecodeString(u.Query().Get("SAMLRequest"))
+	if err != nil {
+		return "", fmt.Errorf("invalid request: %w", err)
+	}
+	data, err := io.ReadAll(flate.NewReader(bytes.NewReader(deflated)))
+	if err != nil {
+		return "", fmt.Errorf("invalid request: %w", err)
+	}
+	request := etree.NewDocument()
+	if err := request.ReadFromBytes(data); err != nil {
+		return "", fmt.Errorf("invalid request: %w", err)
+	}
+	requestID := request.Root().SelectAttrValue("ID", "")
+	acsURL := request.Root().SelectAttrValue("AssertionConsumerServiceURL", "")
+	destination := acsURL
+	if options.destination != "" {
+		destination = options.destination
+	}
+	audience := request.Root().FindElement("./Issuer")
+	if audience == nil {
+		return "", fmt.Errorf("invalid request: no issuer")
+	}
+
+	now := time.Now().UTC()
+	issued := now.Format(time.RFC3339)
+	expires := now.Add(5 * time.Minute).Format(time.RFC3339)
+	assertionID, _ := saml.NewRequestID()
+	responseID, _ := saml.NewRequestID()
+
+	doc := etree.NewDocument()
+	response := doc.CreateElement("samlp:Response")
+	response.CreateAttr("xmlns:samlp", protocolNamespace)
+	response.CreateAttr("xmlns:saml", assertionNamespace)
+	response.CreateAttr("ID", responseID)
+	response.CreateAttr("Version", "2.0")
+	response.CreateAttr("IssueInstant", issued)
+	response.CreateAttr("Destination", destination)
+	response.CreateAttr("InResponseTo", requestID)
+	response.CreateElement("saml:Issuer").SetText(s.EntityID)
+	response.CreateElement("samlp:Status").CreateElement("samlp:StatusCode").CreateAttr("Value", statusSuccess)
+
+	assertion := etree.NewElement("saml:Assertion")
+	assertion.CreateAttr("xmlns:saml", assertionNamespace)
+	assertion.CreateAttr("ID", assertionID)
+	assertion.CreateAttr("Version", "2.0")
+	assertion.CreateAttr("IssueInstant", issued)
+	assertion.CreateElement("saml:Issuer").SetText(s.EntityID)
+
+	subject := assertion.CreateElement("saml:Subject")
+	subject.CreateElement("saml:NameID").SetText(nameID)
+	confirmation := subject.CreateElement("saml:SubjectConfirmation")
+	confirmation.CreateAttr("Method", bearerMethod)
+	confirmationData := confirmation.CreateElement("saml:SubjectConfirmationData")
+	confirmationData.CreateAttr("InResponseTo", requestID)
+	confirmationData.CreateAttr("NotOnOrAfter", expires)
+	confirmationData.CreateAttr("Recipient", acsURL)
+
+	conditions := assertion.CreateElement("saml:Conditions")
+	conditions.CreateAttr("NotBefore", issued)
+	conditions.CreateAttr("NotOnOrAfter", expires)
+	conditions.CreateElement("saml:AudienceRestriction").CreateElement("saml:Audience").SetText(audience.Text())
+
+	statement := assertion.CreateElement("saml:AttributeStatement")
+	for name, value := range attributes {
+		attr := statement.CreateElement("saml:Attribute")
+		attr.CreateAttr("Name", name)
+		attr.CreateElement("saml:AttributeValue").SetText(value)
+	}
+
+	if options.signResponse {
+		response.AddChild(assertion)
+		signed, err := s.sign(response)
+		if err != nil {
+			return "", err
+		}
+		doc.SetRoot(signed)
+	} else {
+		signed, err := s.sign(assertion)
+		if err != nil {
+			return "", err
+		}
+		response.AddChild(signed)
+	}
+
+	out, err := doc.WriteToBytes()
+	if err != nil {
+		return "", fmt.Errorf("failed to encode response: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// sign returns the element with an enveloped signature
+func (s *IdP) sign(el *etree.Element) (*etree.Element, error) {
+	signer, err := dsig.NewSigningContext(s.key, [][]byte{s.cert})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signer: %w", err)
+	}
+	signer.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	signed, err := signer.SignEnveloped(el)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+	return signed, nil
+}
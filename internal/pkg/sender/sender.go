@@ -0,0 +1,137 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+)
+
+// Sender interface for delivering short text messages such as one-time codes
+type Sender interface {
+	Send(ctx context.Context, to, message string) error
+}
+
+// EmailSender delivers messages by email
+type EmailSender struct {
+	mailer  mailer.Mailer
+	subject string
+}
+
+// NewEmailSender creates a new object of 'EmailSender' type
+// and returns a pointer to it.
+func NewEmailSender(m mailer.Mailer, subject string) *EmailSender {
+	return &EmailSender{mailer: m, subject: subject}
+}
+
+// Send sends the message to the email address
+func (s *EmailSender) Send(ctx context.Context, to, message string) error {
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      to,
+		Subject: s.subject,
+		Body:    message,
+	})
+}
+
+// WebhookSender delivers messages by posting them to an HTTP webhook,
+// e.g. an SMS gateway
+type WebhookSender struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewWebhookSender creates a new object of 'WebhookSender' type
+// and returns a pointer to it. The headers are added to every request,
+// e.g. for authorization.
+func NewWebhookSender(url string, headers map[string]string) *WebhookSender {
+	return &WebhookSender{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send posts the message as JSON: {"to": "...", "message": "..."}
+func (s *WebhookSender) Send(ctx context.Context, to, message string) error {
+	body, err := json.Marshal(map[string]string{
+		"to":      to,
+		"message": message,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("failed to send message: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// MemoryMessage the message stored by MemorySender
+type MemoryMessage struct {
+	To      string
+	Message string
+}
+
+// MemorySender stores messages in memory, used for testing
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []MemoryMessage
+}
+
+// NewMemorySender creates a new object of 'MemorySender' type
+// and returns a pointer to it.
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+// Send stores the message
+func (s *MemorySender) Send(_ context.Context, to, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = append(s.messages, MemoryMessage{To: to, Message: message})
+	return nil
+}
+
+// Messages returns a copy of the sent messages
+func (s *MemorySender) Messages() []MemoryMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := make([]MemoryMessage, len(s.messages))
+	copy(messages, s.messages)
+	return messages
+}
+
+// Last returns the last sent message
+func (s *MemorySender) Last() (MemoryMessage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.messages) == 0 {
+		return MemoryMessage{}, false
+	}
+	return s.messages[len(s.messages)-1], true
+}
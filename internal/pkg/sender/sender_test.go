@@ -0,0 +1,67 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailSender(t *testing.T) {
+	m := mailer.NewMemoryMailer()
+	s := NewEmailSender(m, "Your code")
+
+	err := s.Send(context.Background(), "test@example.com", "123456")
+	assert.NoError(t, err)
+
+	msg, ok := m.Last()
+	assert.True(t, ok)
+	assert.Equal(t, mailer.Message{To: "test@example.com", Subject: "Your code", Body: "123456"}, msg)
+}
+
+func TestWebhookSender(t *testing.T) {
+	t.Run("successful send", func(t *testing.T) {
+		var got map[string]string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			json.NewDecoder(r.Body).Decode(&got)
+		}))
+		defer srv.Close()
+
+		s := NewWebhookSender(srv.URL, map[string]string{"Authorization": "Bearer key"})
+		err := s.Send(context.Background(), "+10000000000", "123456")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"to": "+10000000000", "message": "123456"}, got)
+	})
+
+	t.Run("gateway error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer srv.Close()
+
+		s := NewWebhookSender(srv.URL, nil)
+		err := s.Send(context.Background(), "+10000000000", "123456")
+		assert.Error(t, err)
+	})
+}
+
+func TestMemorySender(t *testing.T) {
+	s := NewMemorySender()
+
+	_, ok := s.Last()
+	assert.False(t, ok)
+
+	err := s.Send(context.Background(), "+10000000000", "123456")
+	assert.NoError(t, err)
+
+	msg, ok := s.Last()
+	assert.True(t, ok)
+	assert.Equal(t, MemoryMessage{To: "+10000000000", Message: "123456"}, msg)
+	assert.Len(t, s.Messages(), 1)
+}
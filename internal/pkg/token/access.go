@@ -0,0 +1,66 @@
+package token
+
+import (
+	"slices"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// WithAccess adds the roles and permissions claims.
+// Empty lists are added too, so the claims are always present.
+func WithAccess(roles, permissions []string) Option {
+	return func(claims jwt.MapClaims) {
+		claims["roles"] = nonNil(roles)
+		claims["permissions"] = nonNil(permissions)
+	}
+}
+
+// HasPermissions reports whether the permissions claim contains all the permissions
+func HasPermissions(claims jwt.MapClaims, permissions ...string) bool {
+	granted := StringsClaim(claims, "permissions")
+	for _, permission := range permissions {
+		if !slices.Contains(granted, permission) {
+			return false
+		}
+	}
+	return true
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// WithOrg scopes the token to the organization with the org_id and org_role claims
+func WithOrg(orgID, role string) Option {
+	return func(claims jwt.MapClaims) {
+		claims["org_id"] = orgID
+		claims["org_role"] = role
+	}
+}
+
+// WithTenant adds the tid claim with the tenant the token is issued in
+func WithTenant(tenantID string) Option {
+	return func(claims jwt.MapClaims) {
+		claims["tid"] = tenantID
+	}
+}
+
+// WithActor adds the act claim (RFC 8693) with the user acting on behalf of the subject
+func WithActor(actorID string) Option {
+	return func(claims jwt.MapClaims) {
+		claims["act"] = map[string]any{"sub": actorID}
+	}
+}
+
+// Actor returns the subject of the act claim, if the token is used on behalf of another user
+func Actor(claims jwt.MapClaims) (string, bool) {
+	act, ok := claims["act"].(map[string]any)
+	if !ok {
+		return "", false
+	}
+	sub, ok := act["sub"].(string)
+	return sub, ok && sub != ""
+}
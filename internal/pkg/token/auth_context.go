@@ -0,0 +1,101 @@
+package token
+
+import (
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Authentication methods (amr claim values, RFC 8176)
+const (
+	AMRPassword = "pwd"
+	AMROTP      = "otp"
+	AMRSMS      = "sms"
+	AMREmail    = "email"
+	AMRMFA      = "mfa"
+	AMRHardware = "hwk"
+
+	// AMRFederated authentication by an external identity provider, not defined by RFC 8176
+	AMRFederated = "fed"
+
+	// AMRImpersonation an admin acting as the user, who didn't authenticate, not defined by RFC 8176
+	AMRImpersonation = "imp"
+)
+
+// Authentication assurance levels (acr claim values), from lowest to highest
+const (
+	ACRSingleFactor = "aal1"
+	ACRMultiFactor  = "aal2"
+	ACRHardware     = "aal3"
+
+	// ACRImpersonation the level of impersonation tokens, it never satisfies a required level
+	ACRImpersonation = "impersonation"
+)
+
+var acrLevels = []string{ACRSingleFactor, ACRMultiFactor, ACRHardware}
+
+// ACRFromAMR returns the assurance level achieved by the authentication methods
+func ACRFromAMR(amr []string) string {
+	switch {
+	case slices.Contains(amr, AMRImpersonation):
+		return ACRImpersonation
+	case slices.Contains(amr, AMRHardware):
+		return ACRHardware
+	case slices.Contains(amr, AMRMFA):
+		return ACRMultiFactor
+	default:
+		return ACRSingleFactor
+	}
+}
+
+// ACRSatisfies reports whether the acr is at least the required level.
+// Unknown values never satisfy the requirement.
+func ACRSatisfies(acr, required string) bool {
+	have := slices.Index(acrLevels, acr)
+	want := slices.Index(acrLevels, required)
+	return have != -1 && want != -1 && have >= want
+}
+
+// Option adds optional claims to the token
+type Option func(jwt.MapClaims)
+
+// WithAuthContext adds the authentication context claims:
+// the methods used (amr), the assurance level (acr) and the time of authentication (auth_time).
+// auth_time is left out if the time is zero.
+func WithAuthContext(amr []string, authTime time.Time) Option {
+	return func(claims jwt.MapClaims) {
+		claims["amr"] = amr
+		claims["acr"] = ACRFromAMR(amr)
+		if !authTime.IsZero() {
+			claims["auth_time"] = authTime.Unix()
+		}
+	}
+}
+
+// AuthTime returns the auth_time claim
+func AuthTime(claims jwt.MapClaims) (time.Time, bool) {
+	authTime, ok := claims["auth_time"].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(authTime), 0), true
+}
+
+// StringsClaim returns the claim which holds a list of strings
+func StringsClaim(claims jwt.MapClaims, name string) []string {
+	switch v := claims[name].(type) {
+	case []string:
+		return v
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
@@ -1,6 +1,8 @@
 package token
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"strings"
@@ -11,12 +13,16 @@ import (
 )
 
 // GenerateToken creates a JWT token
-func GenerateToken(user *models.User, jwtSecret []byte, tokenExpiry time.Duration) (string, error) {
+func GenerateToken(user *models.User, jwtSecret []byte, tokenExpiry time.Duration, opts ...Option) (string, error) {
 	claims := jwt.MapClaims{
-		"sub":      user.ID.String(),
-		"username": user.Username,
-		"exp":      time.Now().Add(tokenExpiry).Unix(),
-		"iat":      time.Now().Unix(),
+		"sub":            user.ID.String(),
+		"username":       user.Username,
+		"email_verified": user.EmailVerified,
+		"exp":            time.Now().Add(tokenExpiry).Unix(),
+		"iat":            time.Now().Unix(),
+	}
+	for _, opt := range opts {
+		opt(claims)
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -43,3 +49,39 @@ func ValidateToken(tokenString string, jwtSecret []byte) (jwt.MapClaims, error)
 
 	return nil, errors.New("invalid token")
 }
+
+// GenerateLinkToken creates a signed single-purpose token, e.g. for links sent by email.
+// The token is signed with a key derived from jwtSecret and the purpose,
+// so it can never be accepted as an access token or used for another purpose.
+func GenerateLinkToken(purpose string, claims jwt.MapClaims, jwtSecret []byte, expiry time.Duration) (string, error) {
+	linkClaims := jwt.MapClaims{
+		"purpose": purpose,
+		"exp":     time.Now().Add(expiry).Unix(),
+		"iat":     time.Now().Unix(),
+	}
+	for k, v := range claims {
+		linkClaims[k] = v
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, linkClaims)
+	return token.SignedString(deriveKey(jwtSecret, purpose))
+}
+
+// ValidateLinkToken checks the token created by GenerateLinkToken
+func ValidateLinkToken(tokenString, purpose string, jwtSecret []byte) (jwt.MapClaims, error) {
+	claims, err := ValidateToken(tokenString, deriveKey(jwtSecret, purpose))
+	if err != nil {
+		return nil, err
+	}
+
+	if p, _ := claims["purpose"].(string); p != purpose {
+		return nil, errors.New("invalid token purpose")
+	}
+	return claims, nil
+}
+
+func deriveKey(jwtSecret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
@@ -55,3 +55,127 @@ func TestValidateToken(t *testing.T) {
 		assert.Nil(t, claims)
 	})
 }
+
+func TestLinkToken(t *testing.T) {
+	secret := []byte("secret")
+
+	t.Run("valid token", func(t *testing.T) {
+		tokenString, err := GenerateLinkToken("magic_link", jwt.MapClaims{"sub": "123"}, secret, time.Minute)
+		assert.NoError(t, err)
+
+		claims, err := ValidateLinkToken(tokenString, "magic_link", secret)
+		assert.NoError(t, err)
+		assert.Equal(t, "123", claims["sub"])
+	})
+
+	t.Run("wrong purpose", func(t *testing.T) {
+		tokenString, _ := GenerateLinkToken("magic_link", jwt.MapClaims{"sub": "123"}, secret, time.Minute)
+
+		claims, err := ValidateLinkToken(tokenString, "email_verify", secret)
+		assert.Error(t, err)
+		assert.Nil(t, claims)
+	})
+
+	t.Run("not an access token", func(t *testing.T) {
+		tokenString, _ := GenerateLinkToken("magic_link", jwt.MapClaims{"sub": "123"}, secret, time.Minute)
+
+		claims, err := ValidateToken(tokenString, secret)
+		assert.Error(t, err)
+		assert.Nil(t, claims)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		tokenString, _ := GenerateLinkToken("magic_link", jwt.MapClaims{"sub": "123"}, secret, -time.Minute)
+
+		claims, err := ValidateLinkToken(tokenString, "magic_link", secret)
+		assert.Error(t, err)
+		assert.Nil(t, claims)
+	})
+}
+
+func TestAuthContext(t *testing.T) {
+	user := &models.User{
+		ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		Username: "testuser",
+	}
+	authTime := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	tokenString, err := GenerateToken(user, []byte("secret"), time.Hour,
+		WithAuthContext([]string{AMRPassword, AMROTP, AMRMFA}, authTime))
+	assert.NoError(t, err)
+
+	claims, err := ValidateToken(tokenString, []byte("secret"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{AMRPassword, AMROTP, AMRMFA}, StringsClaim(claims, "amr"))
+	assert.Equal(t, ACRMultiFactor, claims["acr"])
+
+	got, ok := AuthTime(claims)
+	assert.True(t, ok)
+	assert.True(t, authTime.Equal(got))
+}
+
+func TestACR(t *testing.T) {
+	assert.Equal(t, ACRSingleFactor, ACRFromAMR([]string{AMRPassword}))
+	assert.Equal(t, ACRMultiFactor, ACRFromAMR([]string{AMRPassword, AMRSMS, AMRMFA}))
+	assert.Equal(t, ACRHardware, ACRFromAMR([]string{AMRHardware, AMRMFA}))
+	assert.Equal(t, ACRImpersonation, ACRFromAMR([]string{AMRImpersonation}))
+
+	assert.True(t, ACRSatisfies(ACRMultiFactor, ACRSingleFactor))
+	assert.True(t, ACRSatisfies(ACRMultiFactor, ACRMultiFactor))
+	assert.False(t, ACRSatisfies(ACRSingleFactor, ACRMultiFactor))
+	assert.False(t, ACRSatisfies("", ACRSingleFactor))
+	assert.False(t, ACRSatisfies(ACRMultiFactor, "unknown"))
+	assert.False(t, ACRSatisfies(ACRImpersonation, ACRSingleFactor))
+}
+
+func TestAccess(t *testing.T) {
+	user := &models.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001")}
+
+	tokenString, err := GenerateToken(user, []byte("secret"), time.Hour,
+		WithAccess([]string{"admin"}, []string{"users:read", "audit:read"}))
+	assert.NoError(t, err)
+
+	claims, err := ValidateToken(tokenString, []byte("secret"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"admin"}, StringsClaim(claims, "roles"))
+	assert.True(t, HasPermissions(claims))
+	assert.True(t, HasPermissions(claims, "users:read", "audit:read"))
+	assert.False(t, HasPermissions(claims, "users:read", "users:write"))
+
+	tokenString, _ = GenerateToken(user, []byte("secret"), time.Hour, WithAccess(nil, nil))
+	claims, _ = ValidateToken(tokenString, []byte("secret"))
+	assert.Equal(t, []any{}, claims["roles"])
+	assert.False(t, HasPermissions(claims, "users:read"))
+}
+
+func TestOrg(t *testing.T) {
+	user := &models.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001")}
+	orgID := "00000000-0000-0000-0000-000000000002"
+
+	tokenString, err := GenerateToken(user, []byte("secret"), time.Hour, WithOrg(orgID, "admin"))
+	assert.NoError(t, err)
+
+	claims, err := ValidateToken(tokenString, []byte("secret"))
+	assert.NoError(t, err)
+	assert.Equal(t, orgID, claims["org_id"])
+	assert.Equal(t, "admin", claims["org_role"])
+}
+
+func TestActor(t *testing.T) {
+	user := &models.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001")}
+	actorID := "00000000-0000-0000-0000-000000000002"
+
+	tokenString, err := GenerateToken(user, []byte("secret"), time.Hour, WithActor(actorID))
+	assert.NoError(t, err)
+
+	claims, err := ValidateToken(tokenString, []byte("secret"))
+	assert.NoError(t, err)
+	actor, ok := Actor(claims)
+	assert.True(t, ok)
+	assert.Equal(t, actorID, actor)
+
+	tokenString, _ = GenerateToken(user, []byte("secret"), time.Hour)
+	claims, _ = ValidateToken(tokenString, []byte("secret"))
+	_, ok = Actor(claims)
+	assert.False(t, ok)
+}
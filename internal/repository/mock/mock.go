@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -23,11 +24,506 @@ func (m *MockRepository) CreateUser(ctx context.Context, user models.User) (mode
 	return user, args.Error(0)
 }
 
-// GetUserByEmail gets the user by email
-func (m *MockRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-	args := m.Called(ctx, email)
+// GetUserByEmail gets the user of the tenant by email
+func (m *MockRepository) GetUserByEmail(ctx context.Context, tenantID, email string) (*models.User, error) {
+	args := m.Called(ctx, tenantID, email)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.User), args.Error(1)
 }
+
+// SetEmailVerified marks the user's email as verified
+func (m *MockRepository) SetEmailVerified(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// ListUsers gets a page of the users matching the filter
+func (m *MockRepository) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+// CountUsers counts the users matching the filter
+func (m *MockRepository) CountUsers(ctx context.Context, filter models.UserFilter) (int, error) {
+	args := m.Called(ctx, filter)
+	return args.Int(0), args.Error(1)
+}
+
+// UpdateUser saves the username, email and email verification of the user
+func (m *MockRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+// UpdateProfile saves the profile of the user if it wasn't updated after the version
+func (m *MockRepository) UpdateProfile(ctx context.Context, user *models.User, version time.Time) error {
+	args := m.Called(ctx, user, version)
+	return args.Error(0)
+}
+
+// SetUserStatus sets the status of the user
+func (m *MockRepository) SetUserStatus(ctx context.Context, id uuid.UUID, status, reason string, until *time.Time) error {
+	args := m.Called(ctx, id, status, reason, until)
+	return args.Error(0)
+}
+
+// SetPasswordResetRequired requires the user to reset the password
+func (m *MockRepository) SetPasswordResetRequired(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// UpdatePassword sets the new password hash
+func (m *MockRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	args := m.Called(ctx, id, passwordHash)
+	return args.Error(0)
+}
+
+// DeleteUser deletes the user
+func (m *MockRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// SetUserDeleted marks the user deleted or restores it
+func (m *MockRepository) SetUserDeleted(ctx context.Context, id uuid.UUID, deletedAt *time.Time) error {
+	args := m.Called(ctx, id, deletedAt)
+	return args.Error(0)
+}
+
+// PurgeDeletedUsers deletes the users marked deleted before the time and anonymizes their audit events
+func (m *MockRepository) PurgeDeletedUsers(ctx context.Context, before time.Time) ([]models.User, error) {
+	args := m.Called(ctx, before)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+// CreateMagicLink saves a new magic link
+func (m *MockRepository) CreateMagicLink(ctx context.Context, link models.MagicLink) error {
+	args := m.Called(ctx, link)
+	return args.Error(0)
+}
+
+// UseMagicLink marks the magic link as used
+func (m *MockRepository) UseMagicLink(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// GetUserByUsername gets the user of the tenant by username
+func (m *MockRepository) GetUserByUsername(ctx context.Context, tenantID, username string) (*models.User, error) {
+	args := m.Called(ctx, tenantID, username)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+// GetUserByID gets the user of the tenant by ID
+func (m *MockRepository) GetUserByID(ctx context.Context, tenantID string, id uuid.UUID) (*models.User, error) {
+	args := m.Called(ctx, tenantID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+// CreateMFAMethod creates a new MFA method
+func (m *MockRepository) CreateMFAMethod(ctx context.Context, method models.MFAMethod) (models.MFAMethod, error) {
+	args := m.Called(ctx, &method)
+
+	method.CreatedAt = time.Now()
+
+	return method, args.Error(0)
+}
+
+// GetMFAMethods gets all MFA methods of the user
+func (m *MockRepository) GetMFAMethods(ctx context.Context, userID uuid.UUID) ([]models.MFAMethod, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.MFAMethod), args.Error(1)
+}
+
+// ConfirmMFAMethod marks the MFA method as confirmed
+func (m *MockRepository) ConfirmMFAMethod(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// DeleteMFAMethod deletes the MFA method of the user
+func (m *MockRepository) DeleteMFAMethod(ctx context.Context, userID, id uuid.UUID) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+// CreateOTPCode saves a new one-time code
+func (m *MockRepository) CreateOTPCode(ctx context.Context, code models.OTPCode) error {
+	args := m.Called(ctx, code)
+	return args.Error(0)
+}
+
+// GetActiveOTPCode gets the latest active one-time code of the MFA method
+func (m *MockRepository) GetActiveOTPCode(ctx context.Context, methodID uuid.UUID) (*models.OTPCode, error) {
+	args := m.Called(ctx, methodID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.OTPCode), args.Error(1)
+}
+
+// CountOTPCodesSince counts one-time codes sent to the user since the given time
+func (m *MockRepository) CountOTPCodesSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	args := m.Called(ctx, userID, since)
+	return args.Int(0), args.Error(1)
+}
+
+// IncrementOTPAttempts increments the number of verification attempts of the code
+func (m *MockRepository) IncrementOTPAttempts(ctx context.Context, id uuid.UUID, maxAttempts int) error {
+	args := m.Called(ctx, id, maxAttempts)
+	return args.Error(0)
+}
+
+// UseOTPCode marks the one-time code as used
+func (m *MockRepository) UseOTPCode(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// CreateEmailChange saves a new email change request
+func (m *MockRepository) CreateEmailChange(ctx context.Context, change models.EmailChange) error {
+	args := m.Called(ctx, change)
+	return args.Error(0)
+}
+
+// GetEmailChange gets the email change request by ID
+func (m *MockRepository) GetEmailChange(ctx context.Context, id uuid.UUID) (*models.EmailChange, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.EmailChange), args.Error(1)
+}
+
+// ConfirmEmailChange confirms the email change request
+func (m *MockRepository) ConfirmEmailChange(ctx context.Context, change models.EmailChange) error {
+	args := m.Called(ctx, change)
+	return args.Error(0)
+}
+
+// RevertEmailChange reverts the email change request
+func (m *MockRepository) RevertEmailChange(ctx context.Context, change models.EmailChange) error {
+	args := m.Called(ctx, change)
+	return args.Error(0)
+}
+
+// GetLoginFailure gets the failed login attempts for the key
+func (m *MockRepository) GetLoginFailure(ctx context.Context, key string) (*models.LoginFailure, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LoginFailure), args.Error(1)
+}
+
+// IncrementLoginFailures increments the failed attempts for the key
+func (m *MockRepository) IncrementLoginFailures(ctx context.Context, key string, resetBefore time.Time) (int, error) {
+	args := m.Called(ctx, key, resetBefore)
+	return args.Int(0), args.Error(1)
+}
+
+// LockLogin blocks logins for the key until the given time
+func (m *MockRepository) LockLogin(ctx context.Context, key string, until time.Time) error {
+	args := m.Called(ctx, key, until)
+	return args.Error(0)
+}
+
+// ResetLoginFailures removes the failed attempts and the lock for the key
+func (m *MockRepository) ResetLoginFailures(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+// CreateAuditEvent saves the audit event
+func (m *MockRepository) CreateAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+// ListAuditEvents gets the audit events matching the filter
+func (m *MockRepository) ListAuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.AuditEvent), args.Error(1)
+}
+
+// CreateRole creates the role with its permissions
+func (m *MockRepository) CreateRole(ctx context.Context, role models.Role) error {
+	args := m.Called(ctx, role)
+	return args.Error(0)
+}
+
+// UpdateRole replaces the description and the permissions of the role
+func (m *MockRepository) UpdateRole(ctx context.Context, role models.Role) error {
+	args := m.Called(ctx, role)
+	return args.Error(0)
+}
+
+// DeleteRole deletes the role
+func (m *MockRepository) DeleteRole(ctx context.Context, tenantID, name string) error {
+	args := m.Called(ctx, tenantID, name)
+	return args.Error(0)
+}
+
+// ListRoles gets the roles of the tenant
+func (m *MockRepository) ListRoles(ctx context.Context, tenantID string) ([]models.Role, error) {
+	args := m.Called(ctx, tenantID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Role), args.Error(1)
+}
+
+// ListPermissions gets the catalogue of permissions
+func (m *MockRepository) ListPermissions(ctx context.Context) ([]models.Permission, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Permission), args.Error(1)
+}
+
+// GetUserRoles gets the roles assigned to the user
+func (m *MockRepository) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]models.Role, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Role), args.Error(1)
+}
+
+// AssignRole assigns the role to the user
+func (m *MockRepository) AssignRole(ctx context.Context, userID uuid.UUID, role string) error {
+	args := m.Called(ctx, userID, role)
+	return args.Error(0)
+}
+
+// RevokeRole removes the role from the user of the tenant
+func (m *MockRepository) RevokeRole(ctx context.Context, tenantID string, userID uuid.UUID, role string) error {
+	args := m.Called(ctx, tenantID, userID, role)
+	return args.Error(0)
+}
+
+// CreateOrganization creates the organization with the owner
+func (m *MockRepository) CreateOrganization(ctx context.Context, org models.Organization, ownerID uuid.UUID) error {
+	args := m.Called(ctx, org, ownerID)
+	return args.Error(0)
+}
+
+// GetOrganization gets the organization of the tenant by ID
+func (m *MockRepository) GetOrganization(ctx context.Context, tenantID string, id uuid.UUID) (*models.Organization, error) {
+	args := m.Called(ctx, tenantID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Organization), args.Error(1)
+}
+
+// GetUserOrganizations gets the organizations of the user
+func (m *MockRepository) GetUserOrganizations(ctx context.Context, userID uuid.UUID) ([]models.UserOrganization, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.UserOrganization), args.Error(1)
+}
+
+// GetMembership gets the membership of the user in the organization
+func (m *MockRepository) GetMembership(ctx context.Context, orgID, userID uuid.UUID) (*models.Membership, error) {
+	args := m.Called(ctx, orgID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Membership), args.Error(1)
+}
+
+// ListMembers gets the members of the organization
+func (m *MockRepository) ListMembers(ctx context.Context, orgID uuid.UUID) ([]models.Membership, error) {
+	args := m.Called(ctx, orgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Membership), args.Error(1)
+}
+
+// CountMembersWithRole counts the members of the organization with the role
+func (m *MockRepository) CountMembersWithRole(ctx context.Context, orgID uuid.UUID, role string) (int, error) {
+	args := m.Called(ctx, orgID, role)
+	return args.Int(0), args.Error(1)
+}
+
+// UpdateMemberRole changes the role of the member
+func (m *MockRepository) UpdateMemberRole(ctx context.Context, orgID, userID uuid.UUID, role string) error {
+	args := m.Called(ctx, orgID, userID, role)
+	return args.Error(0)
+}
+
+// RemoveMember removes the user from the organization
+func (m *MockRepository) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	args := m.Called(ctx, orgID, userID)
+	return args.Error(0)
+}
+
+// CreateInvitation saves a new invitation
+func (m *MockRepository) CreateInvitation(ctx context.Context, invitation models.Invitation) error {
+	args := m.Called(ctx, invitation)
+	return args.Error(0)
+}
+
+// GetInvitation gets the invitation to the organization
+func (m *MockRepository) GetInvitation(ctx context.Context, orgID, id uuid.UUID) (*models.Invitation, error) {
+	args := m.Called(ctx, orgID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Invitation), args.Error(1)
+}
+
+// ListInvitations gets the pending invitations to the organization
+func (m *MockRepository) ListInvitations(ctx context.Context, orgID uuid.UUID) ([]models.Invitation, error) {
+	args := m.Called(ctx, orgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Invitation), args.Error(1)
+}
+
+// RevokeInvitation revokes the pending invitation
+func (m *MockRepository) RevokeInvitation(ctx context.Context, orgID, id uuid.UUID) error {
+	args := m.Called(ctx, orgID, id)
+	return args.Error(0)
+}
+
+// AcceptInvitation accepts the invitation and adds the user to the organization
+func (m *MockRepository) AcceptInvitation(ctx context.Context, invitation models.Invitation, userID uuid.UUID) error {
+	args := m.Called(ctx, invitation, userID)
+	return args.Error(0)
+}
+
+// CreateAPIKey saves a new API key
+func (m *MockRepository) CreateAPIKey(ctx context.Context, key models.APIKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+// GetAPIKeyByHash gets the API key by the hash of the key
+func (m *MockRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	args := m.Called(ctx, keyHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+// ListAPIKeys gets the API keys of the user
+func (m *MockRepository) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.APIKey), args.Error(1)
+}
+
+// DeleteAPIKey deletes the API key of the user
+func (m *MockRepository) DeleteAPIKey(ctx context.Context, userID, id uuid.UUID) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+// TouchAPIKey sets the time the API key was last used
+func (m *MockRepository) TouchAPIKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	args := m.Called(ctx, id, usedAt)
+	return args.Error(0)
+}
+
+// CreateRegistrationInvite saves a new registration invite
+func (m *MockRepository) CreateRegistrationInvite(ctx context.Context, invite models.RegistrationInvite) error {
+	args := m.Called(ctx, invite)
+	return args.Error(0)
+}
+
+// GetRegistrationInviteByHash gets the registration invite by the hash of its token
+func (m *MockRepository) GetRegistrationInviteByHash(ctx context.Context, tokenHash string) (*models.RegistrationInvite, error) {
+	args := m.Called(ctx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RegistrationInvite), args.Error(1)
+}
+
+// ListRegistrationInvites gets the registration invites of the tenant
+func (m *MockRepository) ListRegistrationInvites(ctx context.Context, tenantID string) ([]models.RegistrationInvite, error) {
+	args := m.Called(ctx, tenantID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.RegistrationInvite), args.Error(1)
+}
+
+// DeleteRegistrationInvite deletes the registration invite of the tenant
+func (m *MockRepository) DeleteRegistrationInvite(ctx context.Context, tenantID string, id uuid.UUID) error {
+	args := m.Called(ctx, tenantID, id)
+	return args.Error(0)
+}
+
+// CreateInvitedUser creates the user with the roles and organization of the invite
+func (m *MockRepository) CreateInvitedUser(ctx context.Context, user models.User, invite models.RegistrationInvite) (models.User, error) {
+	args := m.Called(ctx, user, invite)
+	return args.Get(0).(models.User), args.Error(1)
+}
+
+// CreateUserIdentity links the external identity to the user
+func (m *MockRepository) CreateUserIdentity(ctx context.Context, identity models.UserIdentity) error {
+	args := m.Called(ctx, identity)
+	return args.Error(0)
+}
+
+// GetUserIdentity gets the identity by the provider and the subject at the provider
+func (m *MockRepository) GetUserIdentity(ctx context.Context, tenantID, provider, subject string) (*models.UserIdentity, error) {
+	args := m.Called(ctx, tenantID, provider, subject)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserIdentity), args.Error(1)
+}
+
+// ListUserIdentities gets the external identities of the user
+func (m *MockRepository) ListUserIdentities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.UserIdentity), args.Error(1)
+}
+
+// DeleteUserIdentity unlinks the external identity from the user
+func (m *MockRepository) DeleteUserIdentity(ctx context.Context, userID, id uuid.UUID) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+// UseSAMLAssertion records the assertion as used until it expires
+func (m *MockRepository) UseSAMLAssertion(ctx context.Context, tenantID, id string, expiresAt time.Time) error {
+	args := m.Called(ctx, tenantID, id, expiresAt)
+	return args.Error(0)
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var errAPIKeyNotFound = fmt.Errorf("api key %w", models.ErrNotFound)
+
+// apiKeyRow the API key with the scopes as an array
+type apiKeyRow struct {
+	models.APIKey
+	Scopes pq.StringArray `db:"scopes"`
+}
+
+func (row apiKeyRow) toModel() models.APIKey {
+	key := row.APIKey
+	key.Scopes = []string(row.Scopes)
+	if key.Scopes == nil {
+		key.Scopes = []string{}
+	}
+	return key
+}
+
+// CreateAPIKey saves a new API key
+func (r *PgRepository) CreateAPIKey(ctx context.Context, key models.APIKey) error {
+	query := `
+		INSERT INTO api_keys (id, user_id, tenant_id, name, prefix, key_hash, scopes, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := r.db.ExecContext(ctx, query, key.ID, key.UserID, key.TenantID, key.Name, key.Prefix,
+		key.KeyHash, pq.StringArray(key.Scopes), key.ExpiresAt, key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+	return nil
+}
+
+// GetAPIKeyByHash gets the API key by the hash of the key
+func (r *PgRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	var row apiKeyRow
+	query := `SELECT * FROM api_keys WHERE key_hash = $1`
+
+	err := r.db.GetContext(ctx, &row, query, keyHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	key := row.toModel()
+	return &key, nil
+}
+
+// ListAPIKeys gets the API keys of the user
+func (r *PgRepository) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	var rows []apiKeyRow
+	query := `SELECT * FROM api_keys WHERE user_id = $1 ORDER BY created_at`
+
+	if err := r.db.SelectContext(ctx, &rows, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+
+	keys := make([]models.APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, row.toModel())
+	}
+	return keys, nil
+}
+
+// DeleteAPIKey deletes the API key of the user
+func (r *PgRepository) DeleteAPIKey(ctx context.Context, userID, id uuid.UUID) error {
+	query := `DELETE FROM api_keys WHERE user_id = $1 AND id = $2`
+
+	res, err := r.db.ExecContext(ctx, query, userID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete api key: %w", err)
+	}
+	return checkAffected(res, errAPIKeyNotFound)
+}
+
+// TouchAPIKey sets the time the API key was last used
+func (r *PgRepository) TouchAPIKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, id, usedAt); err != nil {
+		return fmt.Errorf("failed to update api key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+)
+
+// CreateAuditEvent saves the audit event
+func (r *PgRepository) CreateAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (id, tenant_id, type, user_id, actor_id, identifier, method, reason, ip, user_agent, created_at)
+		VALUES (:id, :tenant_id, :type, :user_id, :actor_id, :identifier, :method, :reason, :ip, :user_agent, :created_at)`
+
+	if _, err := r.db.NamedExecContext(ctx, query, event); err != nil {
+		return fmt.Errorf("failed to create audit event: %w", err)
+	}
+	return nil
+}
+
+// ListAuditEvents gets the audit events matching the filter, newest first
+func (r *PgRepository) ListAuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	where("tenant_id = $%d", filter.TenantID)
+	if filter.UserID != nil {
+		where("user_id = $%d", *filter.UserID)
+	}
+	if filter.Type != "" {
+		where("type = $%d", filter.Type)
+	}
+	if !filter.From.IsZero() {
+		where("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("created_at < $%d", filter.To)
+	}
+
+	query := `SELECT * FROM audit_events WHERE ` + strings.Join(conditions, " AND ")
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(` ORDER BY created_at DESC LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	events := []models.AuditEvent{}
+	if err := r.db.SelectContext(ctx, &events, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	return events, nil
+}
+
+// AuditSink writes audit events to the audit_events table
+type AuditSink struct {
+	repo Repository
+}
+
+// NewAuditSink creates a new object of 'AuditSink' type
+// and returns a pointer to it.
+func NewAuditSink(repo Repository) *AuditSink {
+	return &AuditSink{repo: repo}
+}
+
+// Write saves the event
+func (s *AuditSink) Write(ctx context.Context, event models.AuditEvent) error {
+	return s.repo.CreateAuditEvent(ctx, event)
+}
@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/google/uuid"
+)
+
+var errEmailChangeNotFound = fmt.Errorf("email change %w", models.ErrNotFound)
+
+// CreateEmailChange saves a new email change request
+func (r *PgRepository) CreateEmailChange(ctx context.Context, change models.EmailChange) error {
+	change.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO email_changes (id, user_id, old_email, new_email, status, expires_at, created_at)
+		VALUES (:id, :user_id, :old_email, :new_email, :status, :expires_at, :created_at)`
+
+	if _, err := r.db.NamedExecContext(ctx, query, change); err != nil {
+		return fmt.Errorf("failed to create email change: %w", err)
+	}
+	return nil
+}
+
+// GetEmailChange gets the email change request by ID
+func (r *PgRepository) GetEmailChange(ctx context.Context, id uuid.UUID) (*models.EmailChange, error) {
+	var change models.EmailChange
+	query := `SELECT * FROM email_changes WHERE id = $1`
+
+	err := r.db.GetContext(ctx, &change, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errEmailChangeNotFound
+		}
+		return nil, fmt.Errorf("failed to get email change: %w", err)
+	}
+	return &change, nil
+}
+
+// ConfirmEmailChange marks the pending request as confirmed
+// and swaps the user's email to the new one in a transaction
+func (r *PgRepository) ConfirmEmailChange(ctx context.Context, change models.EmailChange) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE email_changes SET status = $2, confirmed_at = now()
+		WHERE id = $1 AND status = $3 AND expires_at > now()`,
+		change.ID, models.EmailChangeConfirmed, models.EmailChangePending)
+	if err != nil {
+		return fmt.Errorf("failed to confirm email change: %w", err)
+	}
+	if err := checkAffected(res, errEmailChangeNotFound); err != nil {
+		return err
+	}
+
+	res, err = tx.ExecContext(ctx, `
+		UPDATE users SET email = $2, email_verified = TRUE, updated_at = now()
+		WHERE id = $1 AND email = $3`,
+		change.UserID, change.NewEmail, change.OldEmail)
+	if err != nil {
+		return fmt.Errorf("failed to update email: %w", mapUniqueViolation(err))
+	}
+	if err := checkAffected(res, errUserNotFound); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RevertEmailChange marks the request as reverted and, if it has been confirmed,
+// restores the old email of the user in a transaction
+func (r *PgRepository) RevertEmailChange(ctx context.Context, change models.EmailChange) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE email_changes SET status = $2, reverted_at = now()
+		WHERE id = $1 AND status = $3`,
+		change.ID, models.EmailChangeReverted, change.Status)
+	if err != nil {
+		return fmt.Errorf("failed to revert email change: %w", err)
+	}
+	if err := checkAffected(res, errEmailChangeNotFound); err != nil {
+		return err
+	}
+
+	if change.Status == models.EmailChangeConfirmed {
+		res, err = tx.ExecContext(ctx, `
+			UPDATE users SET email = $2, email_verified = TRUE, updated_at = now()
+			WHERE id = $1 AND email = $3`,
+			change.UserID, change.OldEmail, change.NewEmail)
+		if err != nil {
+			return fmt.Errorf("failed to update email: %w", mapUniqueViolation(err))
+		}
+		if err := checkAffected(res, errUserNotFound); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+)
+
+var errLoginFailureNotFound = fmt.Errorf("login failure %w", models.ErrNotFound)
+
+// GetLoginFailure gets the failed login attempts for the key
+func (r *PgRepository) GetLoginFailure(ctx context.Context, key string) (*models.LoginFailure, error) {
+	var failure models.LoginFailure
+	query := `SELECT * FROM login_failures WHERE key = $1`
+
+	err := r.db.GetContext(ctx, &failure, query, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errLoginFailureNotFound
+		}
+		return nil, fmt.Errorf("failed to get login failure: %w", err)
+	}
+	return &failure, nil
+}
+
+// IncrementLoginFailures atomically increments the failed attempts for the key
+// and returns the new count. Attempts last updated before resetBefore start over.
+func (r *PgRepository) IncrementLoginFailures(ctx context.Context, key string, resetBefore time.Time) (int, error) {
+	var attempts int
+	query := `
+		INSERT INTO login_failures (key, attempts, updated_at)
+		VALUES ($1, 1, now())
+		ON CONFLICT (key) DO UPDATE SET
+			attempts = CASE WHEN login_failures.updated_at < $2 THEN 1 ELSE login_failures.attempts + 1 END,
+			updated_at = now()
+		RETURNING attempts`
+
+	if err := r.db.GetContext(ctx, &attempts, query, key, resetBefore); err != nil {
+		return 0, fmt.Errorf("failed to increment login failures: %w", err)
+	}
+	return attempts, nil
+}
+
+// LockLogin blocks logins for the key until the given time
+func (r *PgRepository) LockLogin(ctx context.Context, key string, until time.Time) error {
+	query := `UPDATE login_failures SET locked_until = $2 WHERE key = $1`
+
+	res, err := r.db.ExecContext(ctx, query, key, until)
+	if err != nil {
+		return fmt.Errorf("failed to lock login: %w", err)
+	}
+	return checkAffected(res, errLoginFailureNotFound)
+}
+
+// ResetLoginFailures removes the failed attempts and the lock for the key
+func (r *PgRepository) ResetLoginFailures(ctx context.Context, key string) error {
+	query := `DELETE FROM login_failures WHERE key = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, key); err != nil {
+		return fmt.Errorf("failed to reset login failures: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/google/uuid"
+)
+
+var errMagicLinkNotFound = fmt.Errorf("magic link %w", models.ErrNotFound)
+
+// CreateMagicLink saves a new magic link
+func (r *PgRepository) CreateMagicLink(ctx context.Context, link models.MagicLink) error {
+	link.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO magic_links (id, user_id, expires_at, created_at)
+		VALUES (:id, :user_id, :expires_at, :created_at)`
+
+	if _, err := r.db.NamedExecContext(ctx, query, link); err != nil {
+		return fmt.Errorf("failed to create magic link: %w", err)
+	}
+	return nil
+}
+
+// UseMagicLink marks the magic link as used.
+// Returns an error if the link does not exist, has expired or has already been used.
+func (r *PgRepository) UseMagicLink(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE magic_links SET used_at = now()
+		WHERE id = $1 AND used_at IS NULL AND expires_at > now()`
+
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to use magic link: %w", err)
+	}
+	return checkAffected(res, errMagicLinkNotFound)
+}
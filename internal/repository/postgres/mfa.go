@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	errMFAMethodNotFound = fmt.Errorf("mfa method %w", models.ErrNotFound)
+	errOTPCodeNotFound   = fmt.Errorf("otp code %w", models.ErrNotFound)
+)
+
+// CreateMFAMethod creates a new MFA method
+func (r *PgRepository) CreateMFAMethod(ctx context.Context, method models.MFAMethod) (models.MFAMethod, error) {
+	method.ID = uuid.New()
+	method.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO mfa_methods (id, user_id, type, destination, confirmed, created_at)
+		VALUES (:id, :user_id, :type, :destination, :confirmed, :created_at)`
+
+	if _, err := r.db.NamedExecContext(ctx, query, method); err != nil {
+		return models.MFAMethod{}, fmt.Errorf("failed to create mfa method: %w", err)
+	}
+	return method, nil
+}
+
+// GetMFAMethods gets all MFA methods of the user
+func (r *PgRepository) GetMFAMethods(ctx context.Context, userID uuid.UUID) ([]models.MFAMethod, error) {
+	var methods []models.MFAMethod
+	query := `SELECT * FROM mfa_methods WHERE user_id = $1 ORDER BY created_at`
+
+	if err := r.db.SelectContext(ctx, &methods, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to get mfa methods: %w", err)
+	}
+	return methods, nil
+}
+
+// ConfirmMFAMethod marks the MFA method as confirmed
+func (r *PgRepository) ConfirmMFAMethod(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE mfa_methods SET confirmed = TRUE WHERE id = $1`
+
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to confirm mfa method: %w", err)
+	}
+	return checkAffected(res, errMFAMethodNotFound)
+}
+
+// DeleteMFAMethod deletes the MFA method of the user
+func (r *PgRepository) DeleteMFAMethod(ctx context.Context, userID, id uuid.UUID) error {
+	query := `DELETE FROM mfa_methods WHERE id = $1 AND user_id = $2`
+
+	res, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete mfa method: %w", err)
+	}
+	return checkAffected(res, errMFAMethodNotFound)
+}
+
+// CreateOTPCode saves a new one-time code
+func (r *PgRepository) CreateOTPCode(ctx context.Context, code models.OTPCode) error {
+	code.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO otp_codes (id, user_id, method_id, code_hash, attempts, expires_at, created_at)
+		VALUES (:id, :user_id, :method_id, :code_hash, :attempts, :expires_at, :created_at)`
+
+	if _, err := r.db.NamedExecContext(ctx, query, code); err != nil {
+		return fmt.Errorf("failed to create otp code: %w", err)
+	}
+	return nil
+}
+
+// GetActiveOTPCode gets the latest unused and unexpired one-time code of the MFA method
+func (r *PgRepository) GetActiveOTPCode(ctx context.Context, methodID uuid.UUID) (*models.OTPCode, error) {
+	var code models.OTPCode
+	query := `
+		SELECT * FROM otp_codes
+		WHERE method_id = $1 AND used_at IS NULL AND expires_at > now()
+		ORDER BY created_at DESC
+		LIMIT 1`
+
+	err := r.db.GetContext(ctx, &code, query, methodID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errOTPCodeNotFound
+		}
+		return nil, fmt.Errorf("failed to get otp code: %w", err)
+	}
+	return &code, nil
+}
+
+// CountOTPCodesSince counts one-time codes sent to the user since the given time
+func (r *PgRepository) CountOTPCodesSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	var count int
+	query := `SELECT count(*) FROM otp_codes WHERE user_id = $1 AND created_at >= $2`
+
+	if err := r.db.GetContext(ctx, &count, query, userID, since); err != nil {
+		return 0, fmt.Errorf("failed to count otp codes: %w", err)
+	}
+	return count, nil
+}
+
+// IncrementOTPAttempts atomically increments the number of verification attempts
+// of the code if it has fewer than maxAttempts, so concurrent guesses can't exceed them
+func (r *PgRepository) IncrementOTPAttempts(ctx context.Context, id uuid.UUID, maxAttempts int) error {
+	var attempts int
+	query := `
+		UPDATE otp_codes SET attempts = attempts + 1
+		WHERE id = $1 AND attempts < $2
+		RETURNING attempts`
+
+	err := r.db.GetContext(ctx, &attempts, query, id, maxAttempts)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errOTPCodeNotFound
+		}
+		return fmt.Errorf("failed to increment otp attempts: %w", err)
+	}
+	return nil
+}
+
+// UseOTPCode marks the one-time code as used
+func (r *PgRepository) UseOTPCode(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE otp_codes SET used_at = now() WHERE id = $1 AND used_at IS NULL`
+
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to use otp code: %w", err)
+	}
+	return checkAffected(res, errOTPCodeNotFound)
+}
@@ -0,0 +1,230 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// All queries of members and invitations are scoped to the organization,
+// so a mistake in the caller can't leak the data of another tenant.
+
+var (
+	errOrgNotFound        = fmt.Errorf("organization %w", models.ErrNotFound)
+	errMembershipNotFound = fmt.Errorf("membership %w", models.ErrNotFound)
+	errInvitationNotFound = fmt.Errorf("invitation %w", models.ErrNotFound)
+)
+
+// selectMembers selects memberships with the username and email of the members
+const selectMembers = `
+	SELECT m.org_id, m.user_id, u.username, u.email, m.role, m.created_at
+	FROM memberships m
+	JOIN users u ON u.id = m.user_id`
+
+// CreateOrganization creates the organization with the owner as its first member
+func (r *PgRepository) CreateOrganization(ctx context.Context, org models.Organization, ownerID uuid.UUID) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.NamedExecContext(ctx, `
+		INSERT INTO organizations (id, tenant_id, name, slug, created_at)
+		VALUES (:id, :tenant_id, :name, :slug, :created_at)`, org)
+	if err != nil {
+		return fmt.Errorf("failed to create organization: %w", mapUniqueViolation(err))
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO memberships (org_id, user_id, role, created_at) VALUES ($1, $2, $3, $4)`,
+		org.ID, ownerID, models.OrgRoleOwner, org.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create membership: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetOrganization gets the organization of the tenant by ID
+func (r *PgRepository) GetOrganization(ctx context.Context, tenantID string, id uuid.UUID) (*models.Organization, error) {
+	var org models.Organization
+	err := r.db.GetContext(ctx, &org, `SELECT * FROM organizations WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errOrgNotFound
+		}
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	return &org, nil
+}
+
+// GetUserOrganizations gets the organizations the user is a member of
+func (r *PgRepository) GetUserOrganizations(ctx context.Context, userID uuid.UUID) ([]models.UserOrganization, error) {
+	orgs := []models.UserOrganization{}
+	query := `
+		SELECT o.*, m.role FROM organizations o
+		JOIN memberships m ON m.org_id = o.id
+		WHERE m.user_id = $1
+		ORDER BY o.name`
+
+	if err := r.db.SelectContext(ctx, &orgs, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to get user organizations: %w", err)
+	}
+	return orgs, nil
+}
+
+// GetMembership gets the membership of the user in the organization
+func (r *PgRepository) GetMembership(ctx context.Context, orgID, userID uuid.UUID) (*models.Membership, error) {
+	var membership models.Membership
+	query := selectMembers + ` WHERE m.org_id = $1 AND m.user_id = $2`
+
+	err := r.db.GetContext(ctx, &membership, query, orgID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errMembershipNotFound
+		}
+		return nil, fmt.Errorf("failed to get membership: %w", err)
+	}
+	return &membership, nil
+}
+
+// ListMembers gets the members of the organization
+func (r *PgRepository) ListMembers(ctx context.Context, orgID uuid.UUID) ([]models.Membership, error) {
+	members := []models.Membership{}
+	query := selectMembers + ` WHERE m.org_id = $1 ORDER BY m.created_at`
+
+	if err := r.db.SelectContext(ctx, &members, query, orgID); err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+	return members, nil
+}
+
+// CountMembersWithRole counts the members of the organization with the role
+func (r *PgRepository) CountMembersWithRole(ctx context.Context, orgID uuid.UUID, role string) (int, error) {
+	var count int
+	query := `SELECT count(*) FROM memberships WHERE org_id = $1 AND role = $2`
+
+	if err := r.db.GetContext(ctx, &count, query, orgID, role); err != nil {
+		return 0, fmt.Errorf("failed to count members: %w", err)
+	}
+	return count, nil
+}
+
+// UpdateMemberRole changes the role of the member
+func (r *PgRepository) UpdateMemberRole(ctx context.Context, orgID, userID uuid.UUID, role string) error {
+	query := `UPDATE memberships SET role = $3 WHERE org_id = $1 AND user_id = $2`
+
+	res, err := r.db.ExecContext(ctx, query, orgID, userID, role)
+	if err != nil {
+		return fmt.Errorf("failed to update member role: %w", err)
+	}
+	return checkAffected(res, errMembershipNotFound)
+}
+
+// RemoveMember removes the user from the organization
+func (r *PgRepository) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	query := `DELETE FROM memberships WHERE org_id = $1 AND user_id = $2`
+
+	res, err := r.db.ExecContext(ctx, query, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+	return checkAffected(res, errMembershipNotFound)
+}
+
+// CreateInvitation saves a new invitation
+func (r *PgRepository) CreateInvitation(ctx context.Context, invitation models.Invitation) error {
+	invitation.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO org_invitations (id, org_id, email, role, invited_by, status, expires_at, created_at)
+		VALUES (:id, :org_id, :email, :role, :invited_by, :status, :expires_at, :created_at)`
+
+	if _, err := r.db.NamedExecContext(ctx, query, invitation); err != nil {
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+	return nil
+}
+
+// GetInvitation gets the invitation to the organization by ID
+func (r *PgRepository) GetInvitation(ctx context.Context, orgID, id uuid.UUID) (*models.Invitation, error) {
+	var invitation models.Invitation
+	query := `SELECT * FROM org_invitations WHERE org_id = $1 AND id = $2`
+
+	err := r.db.GetContext(ctx, &invitation, query, orgID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errInvitationNotFound
+		}
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+	return &invitation, nil
+}
+
+// ListInvitations gets the pending invitations to the organization
+func (r *PgRepository) ListInvitations(ctx context.Context, orgID uuid.UUID) ([]models.Invitation, error) {
+	invitations := []models.Invitation{}
+	query := `
+		SELECT * FROM org_invitations
+		WHERE org_id = $1 AND status = $2 AND expires_at > now()
+		ORDER BY created_at`
+
+	if err := r.db.SelectContext(ctx, &invitations, query, orgID, models.InvitationPending); err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+	return invitations, nil
+}
+
+// RevokeInvitation revokes the pending invitation
+func (r *PgRepository) RevokeInvitation(ctx context.Context, orgID, id uuid.UUID) error {
+	query := `UPDATE org_invitations SET status = $3 WHERE org_id = $1 AND id = $2 AND status = $4`
+
+	res, err := r.db.ExecContext(ctx, query, orgID, id, models.InvitationRevoked, models.InvitationPending)
+	if err != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+	return checkAffected(res, errInvitationNotFound)
+}
+
+// AcceptInvitation marks the pending invitation as accepted
+// and adds the user to the organization in a transaction
+func (r *PgRepository) AcceptInvitation(ctx context.Context, invitation models.Invitation, userID uuid.UUID) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE org_invitations SET status = $3, accepted_at = now()
+		WHERE org_id = $1 AND id = $2 AND status = $4 AND expires_at > now()`,
+		invitation.OrgID, invitation.ID, models.InvitationAccepted, models.InvitationPending)
+	if err != nil {
+		return fmt.Errorf("failed to accept invitation: %w", err)
+	}
+	if err := checkAffected(res, errInvitationNotFound); err != nil {
+		return err
+	}
+
+	// an existing member keeps the current role
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO memberships (org_id, user_id, role, created_at) VALUES ($1, $2, $3, now())
+		ON CONFLICT DO NOTHING`, invitation.OrgID, userID, invitation.Role)
+	if err != nil {
+		return fmt.Errorf("failed to create membership: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
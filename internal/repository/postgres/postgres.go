@@ -10,14 +10,96 @@ import (
 	"github.com/AlexFox86/auth-service/internal/models"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
-var errUserNotFound = errors.New("user not found")
+// PostgreSQL error codes and constraint names of unique violations
+const (
+	uniqueViolation             = "23505"
+	foreignKeyViolation         = "23503"
+	usersEmailConstraint        = "users_email_key"
+	usersUsernameConstraint     = "users_username_key"
+	rolesNameConstraint         = "roles_pkey"
+	orgsSlugConstraint          = "organizations_slug_key"
+	identitiesSubjectConstraint = "user_identities_provider_subject_key"
+)
+
+var errUserNotFound = fmt.Errorf("user %w", models.ErrNotFound)
 
 // Repository interface for working with storage
 type Repository interface {
 	CreateUser(ctx context.Context, user models.User) (models.User, error)
-	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByEmail(ctx context.Context, tenantID, email string) (*models.User, error)
+	GetUserByUsername(ctx context.Context, tenantID, username string) (*models.User, error)
+	GetUserByID(ctx context.Context, tenantID string, id uuid.UUID) (*models.User, error)
+	SetEmailVerified(ctx context.Context, id uuid.UUID) error
+	ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error)
+	CountUsers(ctx context.Context, filter models.UserFilter) (int, error)
+	UpdateUser(ctx context.Context, user *models.User) error
+	UpdateProfile(ctx context.Context, user *models.User, version time.Time) error
+	SetUserStatus(ctx context.Context, id uuid.UUID, status, reason string, until *time.Time) error
+	SetPasswordResetRequired(ctx context.Context, id uuid.UUID) error
+	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	SetUserDeleted(ctx context.Context, id uuid.UUID, deletedAt *time.Time) error
+	PurgeDeletedUsers(ctx context.Context, before time.Time) ([]models.User, error)
+	CreateMagicLink(ctx context.Context, link models.MagicLink) error
+	UseMagicLink(ctx context.Context, id uuid.UUID) error
+	CreateMFAMethod(ctx context.Context, method models.MFAMethod) (models.MFAMethod, error)
+	GetMFAMethods(ctx context.Context, userID uuid.UUID) ([]models.MFAMethod, error)
+	ConfirmMFAMethod(ctx context.Context, id uuid.UUID) error
+	DeleteMFAMethod(ctx context.Context, userID, id uuid.UUID) error
+	CreateOTPCode(ctx context.Context, code models.OTPCode) error
+	GetActiveOTPCode(ctx context.Context, methodID uuid.UUID) (*models.OTPCode, error)
+	CountOTPCodesSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error)
+	IncrementOTPAttempts(ctx context.Context, id uuid.UUID, maxAttempts int) error
+	UseOTPCode(ctx context.Context, id uuid.UUID) error
+	CreateEmailChange(ctx context.Context, change models.EmailChange) error
+	GetEmailChange(ctx context.Context, id uuid.UUID) (*models.EmailChange, error)
+	ConfirmEmailChange(ctx context.Context, change models.EmailChange) error
+	RevertEmailChange(ctx context.Context, change models.EmailChange) error
+	GetLoginFailure(ctx context.Context, key string) (*models.LoginFailure, error)
+	IncrementLoginFailures(ctx context.Context, key string, resetBefore time.Time) (int, error)
+	LockLogin(ctx context.Context, key string, until time.Time) error
+	ResetLoginFailures(ctx context.Context, key string) error
+	CreateAuditEvent(ctx context.Context, event models.AuditEvent) error
+	ListAuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error)
+	CreateRole(ctx context.Context, role models.Role) error
+	UpdateRole(ctx context.Context, role models.Role) error
+	DeleteRole(ctx context.Context, tenantID, name string) error
+	ListRoles(ctx context.Context, tenantID string) ([]models.Role, error)
+	ListPermissions(ctx context.Context) ([]models.Permission, error)
+	GetUserRoles(ctx context.Context, userID uuid.UUID) ([]models.Role, error)
+	AssignRole(ctx context.Context, userID uuid.UUID, role string) error
+	RevokeRole(ctx context.Context, tenantID string, userID uuid.UUID, role string) error
+	CreateOrganization(ctx context.Context, org models.Organization, ownerID uuid.UUID) error
+	GetOrganization(ctx context.Context, tenantID string, id uuid.UUID) (*models.Organization, error)
+	GetUserOrganizations(ctx context.Context, userID uuid.UUID) ([]models.UserOrganization, error)
+	GetMembership(ctx context.Context, orgID, userID uuid.UUID) (*models.Membership, error)
+	ListMembers(ctx context.Context, orgID uuid.UUID) ([]models.Membership, error)
+	CountMembersWithRole(ctx context.Context, orgID uuid.UUID, role string) (int, error)
+	UpdateMemberRole(ctx context.Context, orgID, userID uuid.UUID, role string) error
+	RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error
+	CreateInvitation(ctx context.Context, invitation models.Invitation) error
+	GetInvitation(ctx context.Context, orgID, id uuid.UUID) (*models.Invitation, error)
+	ListInvitations(ctx context.Context, orgID uuid.UUID) ([]models.Invitation, error)
+	RevokeInvitation(ctx context.Context, orgID, id uuid.UUID) error
+	AcceptInvitation(ctx context.Context, invitation models.Invitation, userID uuid.UUID) error
+	CreateAPIKey(ctx context.Context, key models.APIKey) error
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error)
+	DeleteAPIKey(ctx context.Context, userID, id uuid.UUID) error
+	TouchAPIKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+	CreateRegistrationInvite(ctx context.Context, invite models.RegistrationInvite) error
+	GetRegistrationInviteByHash(ctx context.Context, tokenHash string) (*models.RegistrationInvite, error)
+	ListRegistrationInvites(ctx context.Context, tenantID string) ([]models.RegistrationInvite, error)
+	DeleteRegistrationInvite(ctx context.Context, tenantID string, id uuid.UUID) error
+	CreateInvitedUser(ctx context.Context, user models.User, invite models.RegistrationInvite) (models.User, error)
+	CreateUserIdentity(ctx context.Context, identity models.UserIdentity) error
+	GetUserIdentity(ctx context.Context, tenantID, provider, subject string) (*models.UserIdentity, error)
+	ListUserIdentities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error)
+	DeleteUserIdentity(ctx context.Context, userID, id uuid.UUID) error
+	UseSAMLAssertion(ctx context.Context, tenantID, id string, expiresAt time.Time) error
 }
 
 // PgRepository the structure for working with PostgreSQL database
@@ -31,29 +113,67 @@ func NewPgRepository(db *sqlx.DB) *PgRepository {
 	return &PgRepository{db: db}
 }
 
-// CreateUser creates a new user
+// CreateUser creates a new user. The creation and update times are set by the database.
 func (r *PgRepository) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	return insertUser(ctx, r.db, user)
+}
+
+// insertUser inserts the new user with a generated ID and returns it
+func insertUser(ctx context.Context, db sqlx.QueryerContext, user models.User) (models.User, error) {
 	user.ID = uuid.New()
-	user.CreatedAt = time.Now()
-	user.UpdatedAt = user.CreatedAt
+	if user.Status == "" {
+		user.Status = models.UserStatusActive
+	}
 
 	query := `
-		INSERT INTO users (id, username, email, password, created_at, updated_at)
-		VALUES (:id, :username, :email, :password, :created_at, :updated_at)`
+		INSERT INTO users (id, tenant_id, username, email, email_verified, password, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at, updated_at`
 
-	_, err := r.db.NamedExecContext(ctx, query, user)
+	err := db.QueryRowxContext(ctx, query, user.ID, user.TenantID, user.Username, user.Email,
+		user.EmailVerified, user.Password, user.Status).Scan(&user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
-		return models.User{}, fmt.Errorf("failed to create user: %w", err)
+		return models.User{}, fmt.Errorf("failed to create user: %w", mapUniqueViolation(err))
 	}
 	return user, nil
 }
 
-// GetUserByEmail gets the user by email
-func (r *PgRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+// GetUserByEmail gets the user of the tenant by email
+func (r *PgRepository) GetUserByEmail(ctx context.Context, tenantID, email string) (*models.User, error) {
+	var user models.User
+	query := `SELECT * FROM users WHERE tenant_id = $1 AND lower(email) = lower($2)`
+
+	err := r.db.GetContext(ctx, &user, query, tenantID, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+// GetUserByUsername gets the user of the tenant by username, case-insensitive
+func (r *PgRepository) GetUserByUsername(ctx context.Context, tenantID, username string) (*models.User, error) {
+	var user models.User
+	query := `SELECT * FROM users WHERE tenant_id = $1 AND lower(username) = lower($2)`
+
+	err := r.db.GetContext(ctx, &user, query, tenantID, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+// GetUserByID gets the user of the tenant by ID
+func (r *PgRepository) GetUserByID(ctx context.Context, tenantID string, id uuid.UUID) (*models.User, error) {
 	var user models.User
-	query := `SELECT * FROM users WHERE email = $1`
+	query := `SELECT * FROM users WHERE tenant_id = $1 AND id = $2`
 
-	err := r.db.GetContext(ctx, &user, query, email)
+	err := r.db.GetContext(ctx, &user, query, tenantID, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errUserNotFound
@@ -62,3 +182,55 @@ func (r *PgRepository) GetUserByEmail(ctx context.Context, email string) (*model
 	}
 	return &user, nil
 }
+
+// SetEmailVerified marks the user's email as verified
+func (r *PgRepository) SetEmailVerified(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE users SET email_verified = TRUE, updated_at = now() WHERE id = $1`
+
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to set email verified: %w", err)
+	}
+	return checkAffected(res, errUserNotFound)
+}
+
+// mapUniqueViolation converts unique violations to domain errors
+func mapUniqueViolation(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != uniqueViolation {
+		return err
+	}
+
+	switch pqErr.Constraint {
+	case usersEmailConstraint:
+		return models.ErrEmailExists
+	case usersUsernameConstraint:
+		return models.ErrUsernameExists
+	case rolesNameConstraint:
+		return models.ErrRoleExists
+	case orgsSlugConstraint:
+		return models.ErrSlugExists
+	case identitiesSubjectConstraint:
+		return models.ErrIdentityExists
+	default:
+		return err
+	}
+}
+
+// isForeignKeyViolation reports whether the statement referenced a missing row
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation
+}
+
+// checkAffected returns notFound if the statement did not affect any rows
+func checkAffected(res sql.Result, notFound error) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapUniqueViolation(t *testing.T) {
+	other := errors.New("other")
+
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{
+			name:     "duplicate email",
+			err:      &pq.Error{Code: uniqueViolation, Constraint: usersEmailConstraint},
+			expected: models.ErrEmailExists,
+		},
+		{
+			name:     "duplicate username",
+			err:      &pq.Error{Code: uniqueViolation, Constraint: usersUsernameConstraint},
+			expected: models.ErrUsernameExists,
+		},
+		{
+			name:     "duplicate role",
+			err:      &pq.Error{Code: uniqueViolation, Constraint: rolesNameConstraint},
+			expected: models.ErrRoleExists,
+		},
+		{
+			name:     "duplicate organization slug",
+			err:      &pq.Error{Code: uniqueViolation, Constraint: orgsSlugConstraint},
+			expected: models.ErrSlugExists,
+		},
+		{
+			name:     "identity linked to another user",
+			err:      &pq.Error{Code: uniqueViolation, Constraint: identitiesSubjectConstraint},
+			expected: models.ErrIdentityExists,
+		},
+		{
+			name:     "other constraint",
+			err:      &pq.Error{Code: uniqueViolation, Constraint: "other_key"},
+			expected: &pq.Error{Code: uniqueViolation, Constraint: "other_key"},
+		},
+		{
+			name:     "other error",
+			err:      other,
+			expected: other,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, mapUniqueViolation(tt.err))
+		})
+	}
+}
+
+func TestUserOrder(t *testing.T) {
+	order, err := userOrder(models.UserFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, "created_at ASC, id ASC", order)
+
+	order, err = userOrder(models.UserFilter{Sort: models.UserSortEmail, Desc: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "lower(email) DESC, id DESC", order)
+
+	_, err = userOrder(models.UserFilter{Sort: "password; DROP TABLE users"})
+	assert.Error(t, err)
+}
+
+func TestUserConditions(t *testing.T) {
+	conditions, args := userConditions(models.UserFilter{TenantID: "acme"})
+	assert.Equal(t, "tenant_id = $1", conditions)
+	assert.Equal(t, []any{"acme"}, args)
+
+	conditions, args = userConditions(models.UserFilter{Search: "Al_ex%"})
+	assert.Equal(t, "tenant_id = $1 AND (lower(username) LIKE $2 OR lower(email) LIKE $2)", conditions)
+	assert.Equal(t, []any{"", `%al\_ex\%%`}, args)
+}
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var errRegistrationInviteNotFound = fmt.Errorf("registration invite %w", models.ErrNotFound)
+
+// registrationInviteRow the registration invite with the roles as an array
+type registrationInviteRow struct {
+	models.RegistrationInvite
+	Roles pq.StringArray `db:"roles"`
+}
+
+func (row registrationInviteRow) toModel() models.RegistrationInvite {
+	invite := row.RegistrationInvite
+	invite.Roles = []string(row.Roles)
+	if invite.Roles == nil {
+		invite.Roles = []string{}
+	}
+	return invite
+}
+
+// CreateRegistrationInvite saves a new registration invite
+func (r *PgRepository) CreateRegistrationInvite(ctx context.Context, invite models.RegistrationInvite) error {
+	query := `
+		INSERT INTO registration_invites
+			(id, tenant_id, token_hash, email, roles, org_id, org_role, created_by, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err := r.db.ExecContext(ctx, query, invite.ID, invite.TenantID, invite.TokenHash, invite.Email,
+		pq.StringArray(invite.Roles), invite.OrgID, invite.OrgRole, invite.CreatedBy, invite.ExpiresAt, invite.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create registration invite: %w", err)
+	}
+	return nil
+}
+
+// GetRegistrationInviteByHash gets the registration invite by the hash of its token
+func (r *PgRepository) GetRegistrationInviteByHash(ctx context.Context, tokenHash string) (*models.RegistrationInvite, error) {
+	var row registrationInviteRow
+	query := `SELECT * FROM registration_invites WHERE token_hash = $1`
+
+	err := r.db.GetContext(ctx, &row, query, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errRegistrationInviteNotFound
+		}
+		return nil, fmt.Errorf("failed to get registration invite: %w", err)
+	}
+
+	invite := row.toModel()
+	return &invite, nil
+}
+
+// ListRegistrationInvites gets the registration invites of the tenant, the newest first
+func (r *PgRepository) ListRegistrationInvites(ctx context.Context, tenantID string) ([]models.RegistrationInvite, error) {
+	var rows []registrationInviteRow
+	query := `SELECT * FROM registration_invites WHERE tenant_id = $1 ORDER BY created_at DESC`
+
+	if err := r.db.SelectContext(ctx, &rows, query, tenantID); err != nil {
+		return nil, fmt.Errorf("failed to list registration invites: %w", err)
+	}
+
+	invites := make([]models.RegistrationInvite, 0, len(rows))
+	for _, row := range rows {
+		invites = append(invites, row.toModel())
+	}
+	return invites, nil
+}
+
+// DeleteRegistrationInvite deletes the registration invite of the tenant
+func (r *PgRepository) DeleteRegistrationInvite(ctx context.Context, tenantID string, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM registration_invites WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete registration invite: %w", err)
+	}
+	return checkAffected(res, errRegistrationInviteNotFound)
+}
+
+// CreateInvitedUser creates the user, uses up the invite, assigns the roles of the invite
+// and adds the user to its organization. Nothing is saved if the invite is used or expired.
+func (r *PgRepository) CreateInvitedUser(ctx context.Context, user models.User, invite models.RegistrationInvite) (models.User, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.User{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	user, err = insertUser(ctx, tx, user)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE registration_invites SET used_by = $2, used_at = now()
+		WHERE id = $1 AND used_at IS NULL AND expires_at > now()`, invite.ID, user.ID)
+	if err != nil {
+		return models.User{}, fmt.Errorf("failed to use registration invite: %w", err)
+	}
+	if err := checkAffected(res, models.ErrInvalidInvite); err != nil {
+		return models.User{}, err
+	}
+
+	// roles deleted since the invite was created are skipped
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO user_roles (user_id, role_name)
+		SELECT $1, name FROM roles WHERE name = ANY($2)`, user.ID, pq.StringArray(invite.Roles))
+	if err != nil {
+		return models.User{}, fmt.Errorf("failed to assign roles: %w", err)
+	}
+
+	if invite.OrgID != nil {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO memberships (org_id, user_id, role, created_at) VALUES ($1, $2, $3, now())`,
+			*invite.OrgID, user.ID, invite.OrgRole)
+		if err != nil {
+			return models.User{}, fmt.Errorf("failed to create membership: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.User{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return user, nil
+}
@@ -0,0 +1,191 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	errRoleNotFound           = fmt.Errorf("role %w", models.ErrNotFound)
+	errRoleAssignmentNotFound = fmt.Errorf("role assignment %w", models.ErrNotFound)
+)
+
+// roleRow the role with the permissions aggregated into an array
+type roleRow struct {
+	TenantID    string         `db:"tenant_id"`
+	Name        string         `db:"name"`
+	Description string         `db:"description"`
+	Permissions pq.StringArray `db:"permissions"`
+	CreatedAt   time.Time      `db:"created_at"`
+}
+
+// selectRoles selects roles with their permissions, must be followed by GROUP BY r.tenant_id, r.name
+const selectRoles = `
+	SELECT r.tenant_id, r.name, r.description, r.created_at,
+		COALESCE(array_agg(rp.permission_name ORDER BY rp.permission_name)
+			FILTER (WHERE rp.permission_name IS NOT NULL), '{}') AS permissions
+	FROM roles r
+	LEFT JOIN role_permissions rp ON rp.tenant_id = r.tenant_id AND rp.role_name = r.name`
+
+// CreateRole creates the role with its permissions, which must be in the catalogue
+func (r *PgRepository) CreateRole(ctx context.Context, role models.Role) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO roles (tenant_id, name, description, created_at) VALUES ($1, $2, $3, now())`,
+		role.TenantID, role.Name, role.Description)
+	if err != nil {
+		return fmt.Errorf("failed to create role: %w", mapUniqueViolation(err))
+	}
+
+	if err := setRolePermissions(ctx, tx, role); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// UpdateRole replaces the description and the permissions of the role
+func (r *PgRepository) UpdateRole(ctx context.Context, role models.Role) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE roles SET description = $3 WHERE tenant_id = $1 AND name = $2`,
+		role.TenantID, role.Name, role.Description)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+	if err := checkAffected(res, errRoleNotFound); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM role_permissions WHERE tenant_id = $1 AND role_name = $2`, role.TenantID, role.Name)
+	if err != nil {
+		return fmt.Errorf("failed to delete role permissions: %w", err)
+	}
+
+	if err := setRolePermissions(ctx, tx, role); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// setRolePermissions grants the permissions of the role
+func setRolePermissions(ctx context.Context, tx *sqlx.Tx, role models.Role) error {
+	if len(role.Permissions) == 0 {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO role_permissions (tenant_id, role_name, permission_name) SELECT $1, $2, unnest($3::text[])
+		ON CONFLICT DO NOTHING`, role.TenantID, role.Name, pq.Array(role.Permissions))
+	if err != nil {
+		return fmt.Errorf("failed to grant permissions: %w", err)
+	}
+	return nil
+}
+
+// DeleteRole deletes the role of the tenant and its assignments
+func (r *PgRepository) DeleteRole(ctx context.Context, tenantID, name string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM roles WHERE tenant_id = $1 AND name = $2`, tenantID, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	return checkAffected(res, errRoleNotFound)
+}
+
+// ListRoles gets the roles of the tenant with their permissions
+func (r *PgRepository) ListRoles(ctx context.Context, tenantID string) ([]models.Role, error) {
+	var rows []roleRow
+	query := selectRoles + ` WHERE r.tenant_id = $1 GROUP BY r.tenant_id, r.name ORDER BY r.name`
+
+	if err := r.db.SelectContext(ctx, &rows, query, tenantID); err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	return toRoles(rows), nil
+}
+
+// ListPermissions gets the catalogue of permissions
+func (r *PgRepository) ListPermissions(ctx context.Context) ([]models.Permission, error) {
+	permissions := []models.Permission{}
+	query := `SELECT * FROM permissions ORDER BY name`
+
+	if err := r.db.SelectContext(ctx, &permissions, query); err != nil {
+		return nil, fmt.Errorf("failed to list permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+// GetUserRoles gets the roles assigned to the user with their permissions
+func (r *PgRepository) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]models.Role, error) {
+	var rows []roleRow
+	query := selectRoles + `
+		JOIN user_roles ur ON ur.tenant_id = r.tenant_id AND ur.role_name = r.name
+		WHERE ur.user_id = $1
+		GROUP BY r.tenant_id, r.name ORDER BY r.name`
+
+	if err := r.db.SelectContext(ctx, &rows, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+	return toRoles(rows), nil
+}
+
+// AssignRole assigns the role of the user's tenant to the user, assigning it again does nothing
+func (r *PgRepository) AssignRole(ctx context.Context, userID uuid.UUID, role string) error {
+	query := `
+		INSERT INTO user_roles (tenant_id, user_id, role_name)
+		SELECT tenant_id, id, $2 FROM users WHERE id = $1
+		ON CONFLICT DO NOTHING`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, role); err != nil {
+		if isForeignKeyViolation(err) {
+			return errRoleNotFound
+		}
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+	return nil
+}
+
+// RevokeRole removes the role from the user of the tenant
+func (r *PgRepository) RevokeRole(ctx context.Context, tenantID string, userID uuid.UUID, role string) error {
+	query := `DELETE FROM user_roles WHERE tenant_id = $1 AND user_id = $2 AND role_name = $3`
+
+	res, err := r.db.ExecContext(ctx, query, tenantID, userID, role)
+	if err != nil {
+		return fmt.Errorf("failed to revoke role: %w", err)
+	}
+	return checkAffected(res, errRoleAssignmentNotFound)
+}
+
+func toRoles(rows []roleRow) []models.Role {
+	roles := make([]models.Role, 0, len(rows))
+	for _, row := range rows {
+		roles = append(roles, models.Role{
+			TenantID:    row.TenantID,
+			Name:        row.Name,
+			Description: row.Description,
+			Permissions: row.Permissions,
+			CreatedAt:   row.CreatedAt,
+		})
+	}
+	return roles
+}
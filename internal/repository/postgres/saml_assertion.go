@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var errSAMLAssertionUsed = errors.New("saml assertion already used")
+
+// UseSAMLAssertion records the assertion of the tenant as used until it expires.
+// Returns an error if the assertion has already been used.
+func (r *PgRepository) UseSAMLAssertion(ctx context.Context, tenantID, id string, expiresAt time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM saml_assertions WHERE expires_at < now()`); err != nil {
+		return fmt.Errorf("failed to delete expired saml assertions: %w", err)
+	}
+
+	query := `
+		INSERT INTO saml_assertions (tenant_id, id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`
+
+	res, err := r.db.ExecContext(ctx, query, tenantID, id, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to use saml assertion: %w", err)
+	}
+	return checkAffected(res, errSAMLAssertionUsed)
+}
@@ -0,0 +1,206 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// userSortColumns the columns users can be sorted by
+var userSortColumns = map[string]string{
+	"":                       "created_at",
+	models.UserSortCreatedAt: "created_at",
+	models.UserSortUsername:  "lower(username)",
+	models.UserSortEmail:     "lower(email)",
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// userConditions returns the WHERE clause of the filter and its arguments
+func userConditions(filter models.UserFilter) (string, []any) {
+	args := []any{filter.TenantID}
+	conditions := "tenant_id = $1"
+	if filter.Search != "" {
+		args = append(args, "%"+likeEscaper.Replace(strings.ToLower(filter.Search))+"%")
+		conditions += " AND (lower(username) LIKE $2 OR lower(email) LIKE $2)"
+	}
+	return conditions, args
+}
+
+// userOrder returns the ORDER BY clause of the filter.
+// The ID makes the order stable for pagination.
+func userOrder(filter models.UserFilter) (string, error) {
+	column, ok := userSortColumns[filter.Sort]
+	if !ok {
+		return "", fmt.Errorf("unknown sort field %q", filter.Sort)
+	}
+	direction := "ASC"
+	if filter.Desc {
+		direction = "DESC"
+	}
+	return fmt.Sprintf("%s %s, id %s", column, direction, direction), nil
+}
+
+// ListUsers gets a page of the users matching the filter
+func (r *PgRepository) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error) {
+	conditions, args := userConditions(filter)
+	order, err := userOrder(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`SELECT * FROM users WHERE %s ORDER BY %s LIMIT $%d OFFSET $%d`,
+		conditions, order, len(args)-1, len(args))
+
+	users := []models.User{}
+	if err := r.db.SelectContext(ctx, &users, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, nil
+}
+
+// CountUsers counts the users matching the filter, ignoring the pagination
+func (r *PgRepository) CountUsers(ctx context.Context, filter models.UserFilter) (int, error) {
+	conditions, args := userConditions(filter)
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, `SELECT count(*) FROM users WHERE `+conditions, args...); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+// UpdateUser saves the username, email and email verification of the user
+// and sets its update time
+func (r *PgRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	query := `
+		UPDATE users SET username = $2, email = $3, email_verified = $4, updated_at = now()
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err := r.db.GetContext(ctx, &user.UpdatedAt, query, user.ID, user.Username, user.Email, user.EmailVerified)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errUserNotFound
+		}
+		return fmt.Errorf("failed to update user: %w", mapUniqueViolation(err))
+	}
+	return nil
+}
+
+// UpdateProfile saves the profile of the user and sets its update time if the user
+// wasn't updated after the version, otherwise returns models.ErrUserModified
+func (r *PgRepository) UpdateProfile(ctx context.Context, user *models.User, version time.Time) error {
+	metadata := string(user.Metadata)
+	if metadata == "" {
+		metadata = "{}"
+	}
+
+	query := `
+		UPDATE users SET username = $3, display_name = $4, locale = $5, timezone = $6, metadata = $7,
+			updated_at = now()
+		WHERE id = $1 AND updated_at = $2
+		RETURNING updated_at`
+
+	err := r.db.GetContext(ctx, &user.UpdatedAt, query, user.ID, version, user.Username,
+		user.DisplayName, user.Locale, user.Timezone, metadata)
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		if err := r.db.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, user.ID); err != nil {
+			return fmt.Errorf("failed to check user: %w", err)
+		}
+		if !exists {
+			return errUserNotFound
+		}
+		return models.ErrUserModified
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update profile: %w", mapUniqueViolation(err))
+	}
+	return nil
+}
+
+// SetUserStatus sets the status of the user with its reason and the time it ends
+func (r *PgRepository) SetUserStatus(ctx context.Context, id uuid.UUID, status, reason string, until *time.Time) error {
+	query := `UPDATE users SET status = $2, status_reason = $3, status_until = $4, updated_at = now() WHERE id = $1`
+
+	res, err := r.db.ExecContext(ctx, query, id, status, reason, until)
+	if err != nil {
+		return fmt.Errorf("failed to set user status: %w", err)
+	}
+	return checkAffected(res, errUserNotFound)
+}
+
+// SetPasswordResetRequired requires the user to reset the password before logging in with it
+func (r *PgRepository) SetPasswordResetRequired(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE users SET password_reset_required = TRUE, updated_at = now() WHERE id = $1`
+
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to require password reset: %w", err)
+	}
+	return checkAffected(res, errUserNotFound)
+}
+
+// UpdatePassword sets the new password hash and clears the required password reset
+func (r *PgRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	query := `
+		UPDATE users SET password = $2, password_reset_required = FALSE, updated_at = now()
+		WHERE id = $1`
+
+	res, err := r.db.ExecContext(ctx, query, id, passwordHash)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	return checkAffected(res, errUserNotFound)
+}
+
+// DeleteUser deletes the user with all the user's data
+func (r *PgRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	return checkAffected(res, errUserNotFound)
+}
+
+// SetUserDeleted marks the user deleted since deletedAt, or restores it if deletedAt is nil
+func (r *PgRepository) SetUserDeleted(ctx context.Context, id uuid.UUID, deletedAt *time.Time) error {
+	query := `UPDATE users SET deleted_at = $2, updated_at = now() WHERE id = $1`
+
+	res, err := r.db.ExecContext(ctx, query, id, deletedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set user deleted: %w", err)
+	}
+	return checkAffected(res, errUserNotFound)
+}
+
+// PurgeDeletedUsers deletes the users of all tenants marked deleted before the time
+// and returns them. Their audit events, including the failed logins with their email
+// or username, lose the identifier, IP and user agent.
+func (r *PgRepository) PurgeDeletedUsers(ctx context.Context, before time.Time) ([]models.User, error) {
+	users := []models.User{}
+	query := `
+		WITH purged AS (
+			DELETE FROM users WHERE deleted_at < $1 RETURNING *
+		), anonymized AS (
+			UPDATE audit_events a SET identifier = '', ip = '', user_agent = ''
+			FROM purged p
+			WHERE a.user_id = p.id
+				OR (a.tenant_id = p.tenant_id AND lower(a.identifier) IN (lower(p.email), lower(p.username)))
+		)
+		SELECT * FROM purged`
+
+	if err := r.db.SelectContext(ctx, &users, query, before); err != nil {
+		return nil, fmt.Errorf("failed to purge deleted users: %w", err)
+	}
+	return users, nil
+}
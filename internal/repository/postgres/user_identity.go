@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/google/uuid"
+)
+
+var errUserIdentityNotFound = fmt.Errorf("user identity %w", models.ErrNotFound)
+
+// CreateUserIdentity links the external identity to the user
+func (r *PgRepository) CreateUserIdentity(ctx context.Context, identity models.UserIdentity) error {
+	query := `
+		INSERT INTO user_identities (id, tenant_id, user_id, provider, subject, email, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.db.ExecContext(ctx, query, identity.ID, identity.TenantID, identity.UserID,
+		identity.Provider, identity.Subject, identity.Email, identity.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create user identity: %w", mapUniqueViolation(err))
+	}
+	return nil
+}
+
+// GetUserIdentity gets the identity of the tenant by the provider and the subject at the provider
+func (r *PgRepository) GetUserIdentity(ctx context.Context, tenantID, provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	query := `SELECT * FROM user_identities WHERE tenant_id = $1 AND provider = $2 AND subject = $3`
+
+	err := r.db.GetContext(ctx, &identity, query, tenantID, provider, subject)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errUserIdentityNotFound
+		}
+		return nil, fmt.Errorf("failed to get user identity: %w", err)
+	}
+	return &identity, nil
+}
+
+// ListUserIdentities gets the external identities of the user, the oldest first
+func (r *PgRepository) ListUserIdentities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	identities := []models.UserIdentity{}
+	query := `SELECT * FROM user_identities WHERE user_id = $1 ORDER BY created_at`
+
+	if err := r.db.SelectContext(ctx, &identities, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list user identities: %w", err)
+	}
+	return identities, nil
+}
+
+// DeleteUserIdentity unlinks the external identity from the user
+func (r *PgRepository) DeleteUserIdentity(ctx context.Context, userID, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM user_identities WHERE user_id = $1 AND id = $2`, userID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user identity: %w", err)
+	}
+	return checkAffected(res, errUserIdentityNotFound)
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultDeletionGracePeriod = 30 * 24 * time.Hour
+
+	// exportAuditPageSize the number of audit events read at once for the export
+	exportAuditPageSize = 1000
+)
+
+// ErrAccountDeleted returned when a user whose account is deleted authenticates
+var ErrAccountDeleted = errors.New("account deleted")
+
+// deletionGracePeriod returns the time after which deleted accounts are purged
+func (s *Service) deletionGracePeriod() time.Duration {
+	if s.deletionGrace == 0 {
+		return defaultDeletionGracePeriod
+	}
+	return s.deletionGrace
+}
+
+// DeleteAccount deletes the account of the user confirmed with the password.
+// The account can't be used at once and is purged after the grace period,
+// until then an admin can restore it.
+func (s *Service) DeleteAccount(ctx context.Context, userID uuid.UUID, req *dto.DeleteAccountRequest) (*dto.AccountDeletion, error) {
+	if err := forbidImpersonation(ctx); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, s.tenantID(ctx), userID)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := crypto.CheckPassword(req.Password, user.Password); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.DeletedAt == nil {
+		now := time.Now()
+		if err := s.repo.SetUserDeleted(ctx, user.ID, &now); err != nil {
+			return nil, fmt.Errorf("delete account: %w", err)
+		}
+		user.DeletedAt = &now
+		s.audit(ctx, models.AuditEvent{Type: models.AuditDeletionRequested, UserID: &user.ID})
+
+		if s.mailer != nil {
+			// the deletion is done anyway, the email is only a confirmation
+			if err := s.sendDeletionNotice(ctx, user); err != nil {
+				log.Printf("send deletion notice to user %s: %v", user.ID, err)
+			}
+		}
+	}
+
+	return &dto.AccountDeletion{PurgeAt: user.DeletedAt.Add(s.deletionGracePeriod())}, nil
+}
+
+// sendDeletionNotice tells the user when the deleted account is purged
+func (s *Service) sendDeletionNotice(ctx context.Context, user *models.User) error {
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Your account has been deleted",
+		Body: fmt.Sprintf("Your account %s has been deleted and all its data will be erased on %s.\n\n"+
+			"If you didn't delete it, contact support before that date to restore it.",
+			user.Username, user.DeletedAt.Add(s.deletionGracePeriod()).Format(time.DateOnly)),
+	}
+	return s.mailer.Send(ctx, msg)
+}
+
+// RestoreUser cancels the deletion of the user's account within the grace period
+func (s *Service) RestoreUser(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.User(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.DeletedAt == nil {
+		return nil
+	}
+
+	if err := s.repo.SetUserDeleted(ctx, user.ID, nil); err != nil {
+		return fmt.Errorf("restore user: %w", err)
+	}
+	s.audit(ctx, models.AuditEvent{Type: models.AuditUserRestored, UserID: &user.ID})
+	return nil
+}
+
+// PurgeDeletedUsers erases the accounts of all tenants deleted longer than
+// the grace period ago and returns their number. The audit events of the users
+// are kept without the identifiers, addresses and user agents.
+func (s *Service) PurgeDeletedUsers(ctx context.Context) (int, error) {
+	users, err := s.repo.PurgeDeletedUsers(ctx, time.Now().Add(-s.deletionGracePeriod()))
+	if err != nil {
+		return 0, fmt.Errorf("purge deleted users: %w", err)
+	}
+
+	for _, user := range users {
+		tenantCtx := ContextWithTenant(ctx, &models.Tenant{ID: user.TenantID})
+		s.audit(tenantCtx, models.AuditEvent{Type: models.AuditUserDeleted, UserID: &user.ID})
+	}
+	return len(users), nil
+}
+
+// ExportData returns all data stored about the user
+func (s *Service) ExportData(ctx context.Context, userID uuid.UUID) (*dto.DataExport, error) {
+	user, err := s.User(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, _, err := s.userAccess(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	orgs, err := s.repo.GetUserOrganizations(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get user organizations: %w", err)
+	}
+	methods, err := s.repo.GetMFAMethods(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get mfa methods: %w", err)
+	}
+	keys, err := s.repo.ListAPIKeys(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	identities, err := s.repo.ListUserIdentities(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list identities: %w", err)
+	}
+
+	events := []models.AuditEvent{}
+	filter := models.AuditFilter{TenantID: s.tenantID(ctx), UserID: &user.ID, Limit: exportAuditPageSize}
+	for {
+		page, err := s.repo.ListAuditEvents(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("list audit events: %w", err)
+		}
+		events = append(events, page...)
+		if len(page) < filter.Limit {
+			break
+		}
+		filter.Offset += filter.Limit
+	}
+
+	s.audit(ctx, models.AuditEvent{Type: models.AuditDataExported, UserID: &user.ID})
+	return &dto.DataExport{
+		ExportedAt:    time.Now(),
+		User:          *user,
+		Roles:         roles,
+		Organizations: orgs,
+		MFAMethods:    methods,
+		APIKeys:       keys,
+		Identities:    identities,
+		AuditEvents:   events,
+	}, nil
+}
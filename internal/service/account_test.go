@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+func TestServiceDeleteAccount(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("password123")
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	newUser := func() *models.User {
+		return &models.User{ID: userID, Username: "alex", Email: "alex@example.com", Password: hashedPassword}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		m := mailer.NewMemoryMailer()
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(newUser(), nil)
+		mockRepo.On("SetUserDeleted", mock.Anything, userID, mock.AnythingOfType("*time.Time")).Return(nil)
+		service := New(mockRepo, "secret", time.Hour, WithMailer(m), WithDeletionGracePeriod(7*24*time.Hour))
+
+		deletion, err := service.DeleteAccount(context.Background(), userID, &dto.DeleteAccountRequest{Password: "password123"})
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), deletion.PurgeAt, time.Minute)
+		mockRepo.AssertExpectations(t)
+
+		msg, ok := m.Last()
+		if assert.True(t, ok) {
+			assert.Equal(t, "alex@example.com", msg.To)
+		}
+	})
+
+	t.Run("already deleted", func(t *testing.T) {
+		deletedAt := time.Now().Add(-24 * time.Hour)
+		user := newUser()
+		user.DeletedAt = &deletedAt
+
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(user, nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		deletion, err := service.DeleteAccount(context.Background(), userID, &dto.DeleteAccountRequest{Password: "password123"})
+		assert.NoError(t, err)
+		assert.Equal(t, deletedAt.Add(defaultDeletionGracePeriod), deletion.PurgeAt)
+		mockRepo.AssertNotCalled(t, "SetUserDeleted", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(newUser(), nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		_, err := service.DeleteAccount(context.Background(), userID, &dto.DeleteAccountRequest{Password: "wrong"})
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		mockRepo.AssertNotCalled(t, "SetUserDeleted", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("user without password", func(t *testing.T) {
+		user := newUser()
+		user.Password = ""
+
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(user, nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		_, err := service.DeleteAccount(context.Background(), userID, &dto.DeleteAccountRequest{Password: ""})
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		mockRepo.AssertNotCalled(t, "SetUserDeleted", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestServiceLoginDeleted(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("password123")
+	deletedAt := time.Now()
+	user := &models.User{
+		ID:        uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		Email:     "test@example.com",
+		Password:  hashedPassword,
+		DeletedAt: &deletedAt,
+	}
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+	mockRepo.On("GetLoginFailure", mock.Anything, mock.Anything).Return(nil, models.ErrNotFound)
+	service := New(mockRepo, "secret", time.Hour)
+
+	_, err := service.Login(context.Background(), &dto.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.ErrorIs(t, err, ErrAccountDeleted)
+}
+
+func TestServicePurgeDeletedUsers(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	sink := &memorySink{}
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("PurgeDeletedUsers", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+		return before.Before(time.Now().Add(-time.Hour + time.Minute))
+	})).Return([]models.User{{ID: userID, TenantID: "acme", Username: "alex"}}, nil)
+	service := New(mockRepo, "secret", time.Hour, WithDeletionGracePeriod(time.Hour), WithAuditSink(sink))
+
+	n, err := service.PurgeDeletedUsers(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	if assert.Len(t, sink.events, 1) {
+		assert.Equal(t, models.AuditUserDeleted, sink.events[0].Type)
+		assert.Equal(t, "acme", sink.events[0].TenantID)
+		assert.Empty(t, sink.events[0].Identifier)
+	}
+}
+
+func TestServiceExportData(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	events := make([]models.AuditEvent, exportAuditPageSize)
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID, Username: "alex"}, nil)
+	mockRepo.On("GetUserRoles", mock.Anything, userID).Return([]models.Role{{Name: "user"}}, nil)
+	mockRepo.On("GetUserOrganizations", mock.Anything, userID).Return([]models.UserOrganization{}, nil)
+	mockRepo.On("GetMFAMethods", mock.Anything, userID).Return([]models.MFAMethod{{Type: models.MFAMethodEmail}}, nil)
+	mockRepo.On("ListAPIKeys", mock.Anything, userID).Return([]models.APIKey{}, nil)
+	mockRepo.On("ListUserIdentities", mock.Anything, userID).Return([]models.UserIdentity{}, nil)
+	mockRepo.On("ListAuditEvents", mock.Anything, mock.MatchedBy(func(filter models.AuditFilter) bool {
+		return filter.Offset == 0
+	})).Return(events, nil)
+	mockRepo.On("ListAuditEvents", mock.Anything, mock.MatchedBy(func(filter models.AuditFilter) bool {
+		return filter.Offset == exportAuditPageSize
+	})).Return(events[:10], nil)
+	service := New(mockRepo, "secret", time.Hour)
+
+	export, err := service.ExportData(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.Equal(t, "alex", export.User.Username)
+	assert.Equal(t, []string{"user"}, export.Roles)
+	assert.Len(t, export.MFAMethods, 1)
+	assert.Len(t, export.AuditEvents, exportAuditPageSize+10)
+	mockRepo.AssertExpectations(t)
+}
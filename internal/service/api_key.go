@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+)
+
+const (
+	// APIKeyPrefix the prefix of API keys which tells them apart from JWT tokens
+	APIKeyPrefix = "ak_"
+
+	// apiKeyDisplayLength the length of the key's beginning shown in the key list
+	apiKeyDisplayLength = 11
+
+	// apiKeyTouchInterval how often the last used time of a key is updated
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	// ErrAPIKeyNotFound returned when the user has no API key with the ID
+	ErrAPIKeyNotFound = errors.New("api key not found")
+
+	// ErrInvalidAPIKey returned when the API key is unknown, expired, issued in another tenant
+	// or its user is disabled
+	ErrInvalidAPIKey = errors.New("invalid api key")
+
+	// ErrInvalidScope returned when a requested scope isn't a permission of the user
+	ErrInvalidScope = errors.New("invalid scope")
+
+	// ErrInvalidExpiry returned when the expiration time of the key is in the past
+	ErrInvalidExpiry = errors.New("expiration time is in the past")
+)
+
+// IsAPIKey reports whether the credential, with or without the Bearer scheme, is an API key
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(strings.TrimPrefix(credential, "Bearer "), APIKeyPrefix)
+}
+
+// CreateAPIKey creates a personal API key of the user. The scopes must be
+// permissions of the user. The key itself is returned only here, only its hash is stored.
+func (s *Service) CreateAPIKey(ctx context.Context, userID uuid.UUID, req *dto.CreateAPIKeyRequest) (*dto.APIKeyCreated, error) {
+	if err := forbidImpersonation(ctx); err != nil {
+		return nil, err
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidExpiry
+	}
+
+	_, permissions, err := s.userAccess(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(permissions, scope) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+
+	secret, err := crypto.GenerateRandomString(32)
+	if err != nil {
+		return nil, fmt.Errorf("generate api key: %w", err)
+	}
+	key := APIKeyPrefix + secret
+
+	apiKey := models.APIKey{
+		ID:        uuid.New(),
+		UserID:    userID,
+		TenantID:  s.tenantID(ctx),
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    key[:apiKeyDisplayLength],
+		KeyHash:   crypto.HashToken(key),
+		Scopes:    uniqueSorted(req.Scopes),
+		ExpiresAt: req.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.CreateAPIKey(ctx, apiKey); err != nil {
+		return nil, fmt.Errorf("create api key: %w", err)
+	}
+
+	s.audit(ctx, models.AuditEvent{Type: models.AuditAPIKeyCreated, UserID: &userID})
+	return &dto.APIKeyCreated{APIKey: apiKey, Key: key}, nil
+}
+
+// APIKeys returns the API keys of the user
+func (s *Service) APIKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	keys, err := s.repo.ListAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey deletes the API key of the user, so it is no longer accepted
+func (s *Service) RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error {
+	if err := forbidImpersonation(ctx); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteAPIKey(ctx, userID, keyID); err != nil {
+		return ErrAPIKeyNotFound
+	}
+	s.audit(ctx, models.AuditEvent{Type: models.AuditAPIKeyRevoked, UserID: &userID})
+	return nil
+}
+
+// AuthenticateAPIKey checks the API key and returns the claims of its user
+// like those of an access token. The permissions are the key's scopes the user
+// still has, and there is no authentication context, so API keys never satisfy
+// the required authentication level.
+func (s *Service) AuthenticateAPIKey(ctx context.Context, key string) (jwt.MapClaims, error) {
+	key = strings.TrimPrefix(key, "Bearer ")
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := s.repo.GetAPIKeyByHash(ctx, crypto.HashToken(key))
+	if err != nil || apiKey.TenantID != s.tenantID(ctx) {
+		return nil, ErrInvalidAPIKey
+	}
+	now := time.Now()
+	if apiKey.ExpiresAt != nil && !apiKey.ExpiresAt.After(now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.repo.GetUserByID(ctx, s.tenantID(ctx), apiKey.UserID)
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	if _, err := accountBlocked(user); err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	roles, permissions, err := s.userAccess(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	granted := []string{}
+	for _, scope := range apiKey.Scopes {
+		if slices.Contains(permissions, scope) {
+			granted = append(granted, scope)
+		}
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyTouchInterval {
+		if err := s.repo.TouchAPIKey(ctx, apiKey.ID, now); err != nil {
+			log.Printf("update api key %s last used time: %v", apiKey.ID, err)
+		}
+	}
+
+	claims := jwt.MapClaims{
+		"sub":            user.ID.String(),
+		"username":       user.Username,
+		"email_verified": user.EmailVerified,
+		"roles":          roles,
+		"permissions":    granted,
+		"scopes":         nonNilStrings(apiKey.Scopes),
+		"api_key_id":     apiKey.ID.String(),
+	}
+	if tenantID := s.tenantID(ctx); tenantID != models.DefaultTenantID {
+		claims["tid"] = tenantID
+	}
+	return claims, nil
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
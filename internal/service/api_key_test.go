@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+func TestServiceCreateAPIKey(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	roles := []models.Role{{Name: "auditor", Permissions: []string{models.PermissionAuditRead, models.PermissionUsersRead}}}
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name        string
+		req         dto.CreateAPIKeyRequest
+		mockSetup   func(*mockrepo.MockRepository)
+		expectedErr error
+	}{
+		{
+			name: "success",
+			req:  dto.CreateAPIKeyRequest{Name: "ci", Scopes: []string{models.PermissionAuditRead}},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserRoles", mock.Anything, userID).Return(roles, nil)
+				m.On("CreateAPIKey", mock.Anything, mock.MatchedBy(func(key models.APIKey) bool {
+					return key.UserID == userID && key.Name == "ci" &&
+						strings.HasPrefix(key.Prefix, APIKeyPrefix) && len(key.KeyHash) == 64
+				})).Return(nil)
+			},
+		},
+		{
+			name: "scope the user doesn't have",
+			req:  dto.CreateAPIKeyRequest{Name: "ci", Scopes: []string{models.PermissionRolesManage}},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserRoles", mock.Anything, userID).Return(roles, nil)
+			},
+			expectedErr: ErrInvalidScope,
+		},
+		{
+			name:        "expired",
+			req:         dto.CreateAPIKeyRequest{Name: "ci", ExpiresAt: &past},
+			mockSetup:   func(m *mockrepo.MockRepository) {},
+			expectedErr: ErrInvalidExpiry,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			service := New(mockRepo, "secret", time.Hour)
+
+			created, err := service.CreateAPIKey(context.Background(), userID, &tt.req)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else if assert.NoError(t, err) {
+				assert.True(t, IsAPIKey(created.Key))
+				assert.True(t, strings.HasPrefix(created.Key, created.Prefix))
+				assert.Equal(t, crypto.HashToken(created.Key), created.KeyHash)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceAuthenticateAPIKey(t *testing.T) {
+	user := &models.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), Username: "testuser"}
+	key := APIKeyPrefix + "secret"
+	expired := time.Now().Add(-time.Minute)
+	recentlyUsed := time.Now().Add(-time.Second)
+	roles := []models.Role{{Name: "auditor", Permissions: []string{models.PermissionAuditRead}}}
+
+	tests := []struct {
+		name        string
+		key         string
+		apiKey      *models.APIKey
+		mockSetup   func(*mockrepo.MockRepository, *models.APIKey)
+		permissions []string
+		expectedErr error
+	}{
+		{
+			name: "success",
+			key:  "Bearer " + key,
+			apiKey: &models.APIKey{
+				UserID: user.ID,
+				Scopes: []string{models.PermissionAuditRead, models.PermissionUsersWrite},
+			},
+			mockSetup: func(m *mockrepo.MockRepository, apiKey *models.APIKey) {
+				m.On("GetAPIKeyByHash", mock.Anything, crypto.HashToken(key)).Return(apiKey, nil)
+				m.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+				m.On("GetUserRoles", mock.Anything, user.ID).Return(roles, nil)
+				m.On("TouchAPIKey", mock.Anything, apiKey.ID, mock.AnythingOfType("time.Time")).Return(nil)
+			},
+			// the revoked users.write permission is no longer granted
+			permissions: []string{models.PermissionAuditRead},
+		},
+		{
+			name:   "recently used",
+			key:    key,
+			apiKey: &models.APIKey{UserID: user.ID, LastUsedAt: &recentlyUsed},
+			mockSetup: func(m *mockrepo.MockRepository, apiKey *models.APIKey) {
+				m.On("GetAPIKeyByHash", mock.Anything, crypto.HashToken(key)).Return(apiKey, nil)
+				m.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+				m.On("GetUserRoles", mock.Anything, user.ID).Return(roles, nil)
+			},
+			permissions: []string{},
+		},
+		{
+			name: "unknown",
+			key:  key,
+			mockSetup: func(m *mockrepo.MockRepository, apiKey *models.APIKey) {
+				m.On("GetAPIKeyByHash", mock.Anything, crypto.HashToken(key)).Return(nil, models.ErrNotFound)
+			},
+			expectedErr: ErrInvalidAPIKey,
+		},
+		{
+			name:   "expired",
+			key:    key,
+			apiKey: &models.APIKey{UserID: user.ID, ExpiresAt: &expired},
+			mockSetup: func(m *mockrepo.MockRepository, apiKey *models.APIKey) {
+				m.On("GetAPIKeyByHash", mock.Anything, crypto.HashToken(key)).Return(apiKey, nil)
+			},
+			expectedErr: ErrInvalidAPIKey,
+		},
+		{
+			name:   "another tenant",
+			key:    key,
+			apiKey: &models.APIKey{UserID: user.ID, TenantID: "acme"},
+			mockSetup: func(m *mockrepo.MockRepository, apiKey *models.APIKey) {
+				m.On("GetAPIKeyByHash", mock.Anything, crypto.HashToken(key)).Return(apiKey, nil)
+			},
+			expectedErr: ErrInvalidAPIKey,
+		},
+		{
+			name:        "not an api key",
+			key:         "Bearer eyJhbGciOiJIUzI1NiJ9",
+			mockSetup:   func(m *mockrepo.MockRepository, apiKey *models.APIKey) {},
+			expectedErr: ErrInvalidAPIKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo, tt.apiKey)
+			service := New(mockRepo, "secret", time.Hour)
+
+			claims, err := service.AuthenticateAPIKey(context.Background(), tt.key)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, user.ID.String(), claims["sub"])
+				assert.Equal(t, tt.permissions, claims["permissions"])
+				assert.NotContains(t, claims, "acr")
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceRevokeAPIKey(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	keyID := uuid.MustParse("00000000-0000-0000-0000-0000000000b1")
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("DeleteAPIKey", mock.Anything, userID, keyID).Return(nil).Once()
+	mockRepo.On("DeleteAPIKey", mock.Anything, userID, keyID).Return(models.ErrNotFound).Once()
+	service := New(mockRepo, "secret", time.Hour)
+
+	assert.NoError(t, service.RevokeAPIKey(context.Background(), userID, keyID))
+	assert.ErrorIs(t, service.RevokeAPIKey(context.Background(), userID, keyID), ErrAPIKeyNotFound)
+	mockRepo.AssertExpectations(t)
+}
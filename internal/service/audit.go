@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/audit"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// AuditSink receives the audit events of the service
+type AuditSink interface {
+	Write(ctx context.Context, event models.AuditEvent) error
+}
+
+// AuditEvents returns the audit events matching the filter, newest first
+func (s *Service) AuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	filter.Limit = min(filter.Limit, maxAuditLimit)
+	filter.TenantID = s.tenantID(ctx)
+
+	events, err := s.repo.ListAuditEvents(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("list audit events: %w", err)
+	}
+	return events, nil
+}
+
+// audit writes the event to the sinks. A failed sink doesn't fail the operation.
+func (s *Service) audit(ctx context.Context, event models.AuditEvent) {
+	if len(s.auditSinks) == 0 {
+		return
+	}
+
+	event.ID = uuid.New()
+	event.TenantID = s.tenantID(ctx)
+	event.CreatedAt = time.Now()
+	client := audit.ClientFromContext(ctx)
+	event.IP = client.IP
+	event.UserAgent = client.UserAgent
+	if actorID, ok := ActorFromContext(ctx); ok && event.ActorID == nil {
+		event.ActorID = &actorID
+	}
+
+	for _, sink := range s.auditSinks {
+		if err := sink.Write(ctx, event); err != nil {
+			log.Printf("write audit event %s: %v", event.Type, err)
+		}
+	}
+}
+
+// auditLoginFailed records the failed login. The user is nil if unknown.
+func (s *Service) auditLoginFailed(ctx context.Context, user *models.User, identifier, method, reason string) {
+	event := models.AuditEvent{
+		Type:       models.AuditLoginFailed,
+		Identifier: identifier,
+		Method:     method,
+		Reason:     reason,
+	}
+	if user != nil {
+		event.UserID = &user.ID
+	}
+	s.audit(ctx, event)
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/audit"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+type memorySink struct {
+	mu     sync.Mutex
+	events []models.AuditEvent
+}
+
+func (s *memorySink) Write(_ context.Context, event models.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func TestServiceLoginAudit(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("password123")
+	user := &models.User{
+		ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: hashedPassword,
+	}
+	ctx := audit.ContextWithClient(context.Background(), audit.Client{IP: "192.0.2.1", UserAgent: "curl/8.0"})
+
+	tests := []struct {
+		name      string
+		req       *dto.LoginRequest
+		mockSetup func(*mockrepo.MockRepository)
+		expected  models.AuditEvent
+	}{
+		{
+			name: "success",
+			req:  &dto.LoginRequest{Email: user.Email, Password: "password123"},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+				m.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{}, nil)
+				m.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{}, nil)
+			},
+			expected: models.AuditEvent{Type: models.AuditLoginSucceeded, UserID: &user.ID, Method: "pwd"},
+		},
+		{
+			name: "invalid password",
+			req:  &dto.LoginRequest{Email: user.Email, Password: "wrongpassword"},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+			},
+			expected: models.AuditEvent{
+				Type:       models.AuditLoginFailed,
+				UserID:     &user.ID,
+				Identifier: user.Email,
+				Method:     "pwd",
+				Reason:     models.AuditReasonInvalidPassword,
+			},
+		},
+		{
+			name: "unknown user",
+			req:  &dto.LoginRequest{Identifier: "nobody", Password: "password123"},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetUserByUsername", mock.Anything, "", "nobody").Return(nil, errUserNotFound)
+			},
+			expected: models.AuditEvent{
+				Type:       models.AuditLoginFailed,
+				Identifier: "nobody",
+				Method:     "pwd",
+				Reason:     models.AuditReasonUnknownUser,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			sink := &memorySink{}
+			service := New(mockRepo, "secret", time.Hour, WithAuditSink(sink))
+
+			service.Login(ctx, tt.req)
+
+			assert.Len(t, sink.events, 1)
+			event := sink.events[0]
+			assert.NotEqual(t, uuid.Nil, event.ID)
+			assert.False(t, event.CreatedAt.IsZero())
+			assert.Equal(t, "192.0.2.1", event.IP)
+			assert.Equal(t, "curl/8.0", event.UserAgent)
+
+			event.ID, event.CreatedAt, event.IP, event.UserAgent = tt.expected.ID, tt.expected.CreatedAt, "", ""
+			assert.Equal(t, tt.expected, event)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceAuditEvents(t *testing.T) {
+	tests := []struct {
+		name          string
+		limit         int
+		expectedLimit int
+	}{
+		{name: "default limit", limit: 0, expectedLimit: defaultAuditLimit},
+		{name: "limit", limit: 10, expectedLimit: 10},
+		{name: "max limit", limit: 5000, expectedLimit: maxAuditLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			events := []models.AuditEvent{{Type: models.AuditRegistered}}
+			mockRepo.On("ListAuditEvents", mock.Anything, models.AuditFilter{Type: models.AuditRegistered, Limit: tt.expectedLimit}).
+				Return(events, nil)
+			service := New(mockRepo, "secret", time.Hour)
+
+			result, err := service.AuditEvents(context.Background(), models.AuditFilter{Type: models.AuditRegistered, Limit: tt.limit})
+
+			assert.NoError(t, err)
+			assert.Equal(t, events, result)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
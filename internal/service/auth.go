@@ -4,76 +4,327 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/AlexFox86/auth-service/internal/delivery/dto"
 	"github.com/AlexFox86/auth-service/internal/models"
 	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	"github.com/AlexFox86/auth-service/internal/pkg/oidc"
+	"github.com/AlexFox86/auth-service/internal/pkg/saml"
+	"github.com/AlexFox86/auth-service/internal/pkg/sender"
 	"github.com/AlexFox86/auth-service/internal/pkg/token"
 	"github.com/AlexFox86/auth-service/internal/repository/postgres"
+	"github.com/google/uuid"
 )
 
-// ErrInvalidCredentials returned when authentication failed due to incorrect credentials
-var ErrInvalidCredentials = errors.New("invalid credentials")
+var (
+	// ErrInvalidCredentials returned when authentication failed due to incorrect credentials
+	ErrInvalidCredentials = errors.New("invalid credentials")
+
+	// ErrEmailExists returned when the email belongs to another user
+	ErrEmailExists = models.ErrEmailExists
+
+	// ErrUsernameExists returned when the username belongs to another user
+	ErrUsernameExists = models.ErrUsernameExists
+
+	// ErrUsernameReserved returned when the username is in the reserved list
+	ErrUsernameReserved = errors.New("username is reserved")
+
+	// ErrUserNotFound returned when the user doesn't exist
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// defaultReservedUsernames can't be registered by users
+var defaultReservedUsernames = []string{
+	"admin", "administrator", "root", "system", "support", "security",
+	"api", "auth", "login", "me", "null", "undefined",
+}
+
+// dummyPasswordHash is compared with the password of unknown users,
+// so they take as long to log in as existing ones
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := crypto.HashPassword("dummy password")
+	return hash
+})
 
 // Service provides methods for authentication and registration
 type Service struct {
 	repo        postgres.Repository
 	jwtSecret   []byte
 	tokenExpiry time.Duration
+
+	mailer          mailer.Mailer
+	baseURL         string
+	frontendURL     string
+	magicLinkExpiry time.Duration
+
+	otpSenders    map[string]sender.Sender
+	otpSendLimit  int
+	otpSendWindow time.Duration
+
+	stepUpExpiry time.Duration
+
+	reservedUsernames []string
+
+	emailVerificationPolicy EmailVerificationPolicy
+
+	lockoutPolicy   *LockoutPolicy
+	lockoutNotifier LockoutNotifier
+
+	enumerationProtection bool
+
+	auditSinks []AuditSink
+
+	passwordPolicy models.PasswordPolicy
+	tenants        map[string]*models.Tenant
+	tenantHosts    map[string]string
+
+	deletionGrace time.Duration
+
+	impersonationExpiry time.Duration
+
+	oidcProviders  map[string]*oidc.Provider
+	samlIdPs       map[string]map[string]*saml.IdentityProvider
+	authenticators map[string][]Authenticator
+
+	registrationMode  RegistrationMode
+	allowedDomains    []string
+	disposableDomains map[string]struct{}
 }
 
 // New creates a new authentication service
-func New(repo postgres.Repository, jwtSecret string, tokenExpiry time.Duration) *Service {
-	return &Service{
+func New(repo postgres.Repository, jwtSecret string, tokenExpiry time.Duration, opts ...Option) *Service {
+	s := &Service{
 		repo:        repo,
 		jwtSecret:   []byte(jwtSecret),
 		tokenExpiry: tokenExpiry,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-// JwtSecret returns the 'jwtSecret' field
+// JwtSecret returns the 'jwtSecret' field, the signing key of the default tenant
 func (s *Service) JwtSecret() []byte {
 	return s.jwtSecret
 }
 
-// TokenExpiry returns the 'tokenExpiry' field
+// TokenExpiry returns the 'tokenExpiry' field, the token expiry of the default tenant
 func (s *Service) TokenExpiry() time.Duration {
 	return s.tokenExpiry
 }
 
 // Register creates a new user
 func (s *Service) Register(ctx context.Context, req *dto.RegisterRequest) (models.User, error) {
+	if s.isReservedUsername(req.Username) {
+		return models.User{}, ErrUsernameReserved
+	}
+	invite, err := s.checkRegistration(ctx, req)
+	if err != nil {
+		return models.User{}, err
+	}
+	if err := s.checkPasswordPolicy(ctx, req.Password); err != nil {
+		return models.User{}, err
+	}
+
 	hashedPassword, err := crypto.HashPassword(req.Password)
 	if err != nil {
 		return models.User{}, fmt.Errorf("hash password: %w", err)
 	}
 
 	user := models.User{
+		TenantID: s.tenantID(ctx),
 		Username: req.Username,
-		Email:    req.Email,
+		Email:    normalizeEmail(req.Email),
 		Password: hashedPassword,
 	}
 
-	if user, err = s.repo.CreateUser(ctx, user); err != nil {
+	if invite != nil {
+		user, err = s.repo.CreateInvitedUser(ctx, user, *invite)
+	} else {
+		user, err = s.repo.CreateUser(ctx, user)
+	}
+	if err != nil {
+		if s.enumerationProtection && errors.Is(err, ErrEmailExists) && s.mailer != nil {
+			if err := s.sendRegistrationAttempt(ctx, normalizeEmail(req.Email)); err != nil {
+				log.Printf("send registration attempt notification: %v", err)
+			}
+		}
 		return models.User{}, fmt.Errorf("create user: %w", err)
 	}
+	event := models.AuditEvent{Type: models.AuditRegistered, UserID: &user.ID, Identifier: user.Username}
+	if invite != nil {
+		event.Method = "invite"
+	}
+	s.audit(ctx, event)
+
+	if s.mailer != nil {
+		// the user can request the link again, so a failure doesn't fail the registration
+		if err := s.sendEmailVerification(ctx, &user); err != nil {
+			log.Printf("send email verification to user %s: %v", user.ID, err)
+		}
+	}
 
 	return user, nil
 }
 
-// Login performs user authentication
+// EnumerationProtection reports whether registration responses must not reveal taken emails
+func (s *Service) EnumerationProtection() bool {
+	return s.enumerationProtection
+}
+
+// sendRegistrationAttempt tells the owner of the email that someone tried to register with it
+func (s *Service) sendRegistrationAttempt(ctx context.Context, email string) error {
+	msg := mailer.Message{
+		To:      email,
+		Subject: "Sign up attempt with your email",
+		Body: fmt.Sprintf("Someone tried to create an account with your email address, "+
+			"but you already have one. If it was you, sign in at %s/login.\n\n"+
+			"If it wasn't you, you can ignore this email.", s.publicURL(ctx)),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("send registration attempt email: %w", err)
+	}
+	return nil
+}
+
+// Login performs user authentication by username or email
 func (s *Service) Login(ctx context.Context, req *dto.LoginRequest) (*dto.Response, error) {
-	user, err := s.repo.GetUserByEmail(ctx, req.Email)
+	identifier := req.Identifier
+	if identifier == "" {
+		identifier = req.Email
+	}
+
+	user, err := s.getUserByIdentifier(ctx, identifier)
 	if err != nil {
+		// unknown identifiers go through the same lockout as existing accounts
+		user = nil
+	}
+
+	key := s.loginLockKey(ctx, user, identifier)
+	failure, err := s.checkLoginLock(ctx, key)
+	if err != nil {
+		s.auditLoginFailed(ctx, user, identifier, token.AMRPassword, models.AuditReasonLocked)
+		return nil, err
+	}
+
+	// users without a local password may be in an external store
+	if len(s.authenticators[s.tenantID(ctx)]) > 0 && (user == nil || !user.HasPassword()) {
+		account, err := s.authenticateExternal(ctx, identifier, req.Password)
+		if err != nil && !errors.Is(err, ErrInvalidCredentials) {
+			return nil, err
+		}
+		if account != nil {
+			s.resetLoginFailures(ctx, key, failure)
+			user, err := s.accountUser(ctx, account)
+			if err != nil {
+				return nil, err
+			}
+			return s.completeLogin(ctx, user, []string{token.AMRPassword}, req.OrgID)
+		}
+	}
+
+	if user == nil {
+		// the result doesn't matter, it only makes unknown users as slow as existing ones
+		_ = crypto.CheckPassword(req.Password, dummyPasswordHash())
+		s.recordLoginFailure(ctx, key, nil)
+		s.auditLoginFailed(ctx, nil, identifier, token.AMRPassword, models.AuditReasonUnknownUser)
 		return nil, ErrInvalidCredentials
 	}
 
-	if err := crypto.CheckPassword(req.Password, user.Password); err != nil {
+	// users without a password are checked against the dummy hash, so they
+	// can't be told apart from users with a wrong password by the response time
+	hash := user.Password
+	if !user.HasPassword() {
+		hash = dummyPasswordHash()
+	}
+	if crypto.CheckPassword(req.Password, hash) != nil || !user.HasPassword() {
+		s.recordLoginFailure(ctx, key, user)
+		s.auditLoginFailed(ctx, user, identifier, token.AMRPassword, models.AuditReasonInvalidPassword)
 		return nil, ErrInvalidCredentials
 	}
+	s.resetLoginFailures(ctx, key, failure)
+
+	if s.emailVerificationPolicy == EmailVerificationRequired && !user.EmailVerified {
+		s.auditLoginFailed(ctx, user, identifier, token.AMRPassword, models.AuditReasonEmailNotVerified)
+		return nil, ErrEmailNotVerified
+	}
+
+	return s.completeLogin(ctx, user, []string{token.AMRPassword}, req.OrgID)
+}
+
+// completeLogin requires a second factor if the user has one,
+// otherwise issues an access token. A non-nil orgID scopes the token
+// to the organization the user must be a member of.
+func (s *Service) completeLogin(ctx context.Context, user *models.User, amr []string, orgID uuid.UUID) (*dto.Response, error) {
+	if reason, err := accountBlocked(user); err != nil {
+		s.auditLoginFailed(ctx, user, "", strings.Join(amr, ","), reason)
+		return nil, err
+	}
+	// the reset is required by an admin whatever way the user logs in
+	if user.PasswordResetRequired {
+		s.auditLoginFailed(ctx, user, "", strings.Join(amr, ","), models.AuditReasonPasswordReset)
+		return nil, ErrPasswordResetRequired
+	}
 
-	token, err := token.GenerateToken(user, s.jwtSecret, s.tokenExpiry)
+	membership, err := s.orgMembership(ctx, orgID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.mfaChallenge(ctx, user, amr, false, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if challenge != nil {
+		return nil, &MFARequiredError{Challenge: *challenge}
+	}
+
+	resp, err := s.newResponse(ctx, user, amr, s.accessTokenExpiry(ctx), membership)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, models.AuditEvent{Type: models.AuditLoginSucceeded, UserID: &user.ID, Method: strings.Join(amr, ",")})
+	return resp, nil
+}
+
+// newResponse issues an access token for the user authenticated with the amr methods.
+// The token is scoped to the organization of the membership if it isn't nil.
+func (s *Service) newResponse(ctx context.Context, user *models.User, amr []string,
+	expiry time.Duration, membership *models.Membership) (*dto.Response, error) {
+	return s.issueToken(ctx, user, amr, time.Now(), expiry, membership)
+}
+
+// issueToken issues an access token with the given authentication context and extra claims
+func (s *Service) issueToken(ctx context.Context, user *models.User, amr []string, authTime time.Time,
+	expiry time.Duration, membership *models.Membership, extra ...token.Option) (*dto.Response, error) {
+	if _, err := accountBlocked(user); err != nil {
+		return nil, err
+	}
+
+	roles, permissions, err := s.userAccess(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []token.Option{
+		token.WithAuthContext(amr, authTime),
+		token.WithAccess(roles, permissions),
+	}
+	if membership != nil {
+		opts = append(opts, token.WithOrg(membership.OrgID.String(), membership.Role))
+	}
+	if tenantID := s.tenantID(ctx); tenantID != models.DefaultTenantID {
+		opts = append(opts, token.WithTenant(tenantID))
+	}
+	opts = append(opts, extra...)
+
+	token, err := token.GenerateToken(user, s.SigningKey(ctx), expiry, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("generate token: %w", err)
 	}
@@ -83,3 +334,31 @@ func (s *Service) Login(ctx context.Context, req *dto.LoginRequest) (*dto.Respon
 		User:  *user,
 	}, nil
 }
+
+// normalizeEmail makes emails which differ only in case or surrounding spaces equal
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// getUserByIdentifier gets the user by email if the identifier contains '@',
+// otherwise by username. Both paths make exactly one lookup.
+func (s *Service) getUserByIdentifier(ctx context.Context, identifier string) (*models.User, error) {
+	if strings.Contains(identifier, "@") {
+		return s.repo.GetUserByEmail(ctx, s.tenantID(ctx), normalizeEmail(identifier))
+	}
+	return s.repo.GetUserByUsername(ctx, s.tenantID(ctx), strings.TrimSpace(identifier))
+}
+
+func (s *Service) isReservedUsername(username string) bool {
+	reserved := s.reservedUsernames
+	if reserved == nil {
+		reserved = defaultReservedUsernames
+	}
+
+	for _, name := range reserved {
+		if strings.EqualFold(name, strings.TrimSpace(username)) {
+			return true
+		}
+	}
+	return false
+}
@@ -9,15 +9,17 @@ import (
 	"github.com/AlexFox86/auth-service/internal/delivery/dto"
 	"github.com/AlexFox86/auth-service/internal/models"
 	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
 
 	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
 )
 
 var (
-	errUserNotFound = errors.New("user not found")
+	errUserNotFound = models.ErrNotFound
 	errEmailExists  = errors.New("email already exists")
 )
 
@@ -80,6 +82,55 @@ func TestServiceRegister(t *testing.T) {
 			expected:    models.User{},
 			expectedErr: errEmailExists,
 		},
+		{
+			name: "username already exists",
+			req: &dto.RegisterRequest{
+				Username: "exists",
+				Email:    "test@example.com",
+				Password: "password123",
+			},
+			mockSetup: func(mr *mockrepo.MockRepository) {
+				mr.On("CreateUser", mock.Anything, mock.AnythingOfType("*models.User")).
+					Return(models.ErrUsernameExists)
+			},
+			expected:    models.User{},
+			expectedErr: ErrUsernameExists,
+		},
+		{
+			name: "reserved username",
+			req: &dto.RegisterRequest{
+				Username: "Admin",
+				Email:    "test@example.com",
+				Password: "password123",
+			},
+			mockSetup:   func(mr *mockrepo.MockRepository) {},
+			expected:    models.User{},
+			expectedErr: ErrUsernameReserved,
+		},
+		{
+			name: "email is normalized",
+			req: &dto.RegisterRequest{
+				Username: "testuser",
+				Email:    " Test@Example.com",
+				Password: "password123",
+			},
+			mockSetup: func(mr *mockrepo.MockRepository) {
+				mr.On("CreateUser", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+					return u.Email == "test@example.com"
+				})).
+					Return(nil).
+					Run(func(args mock.Arguments) {
+						user := args.Get(1).(*models.User)
+						user.ID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+					})
+			},
+			expected: models.User{
+				ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+				Username: "testuser",
+				Email:    "test@example.com",
+			},
+			expectedErr: nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -123,13 +174,17 @@ func TestServiceLogin(t *testing.T) {
 				Password: "password123",
 			},
 			mockSetup: func(mr *mockrepo.MockRepository) {
-				mr.On("GetUserByEmail", mock.Anything, "test@example.com").
+				mr.On("GetUserByEmail", mock.Anything, "", "test@example.com").
 					Return(&models.User{
 						ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
 						Username: "testuser",
 						Email:    "test@example.com",
 						Password: hashedPassword,
 					}, nil)
+				mr.On("GetMFAMethods", mock.Anything, mock.AnythingOfType("uuid.UUID")).
+					Return([]models.MFAMethod{}, nil)
+				mr.On("GetUserRoles", mock.Anything, mock.AnythingOfType("uuid.UUID")).
+					Return([]models.Role{}, nil)
 			},
 			expected: &dto.Response{
 				User: models.User{
@@ -141,6 +196,51 @@ func TestServiceLogin(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			name: "successful login by username",
+			req: &dto.LoginRequest{
+				Identifier: "testuser",
+				Password:   "password123",
+			},
+			mockSetup: func(mr *mockrepo.MockRepository) {
+				mr.On("GetUserByUsername", mock.Anything, "", "testuser").
+					Return(&models.User{
+						ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+						Username: "testuser",
+						Email:    "test@example.com",
+						Password: hashedPassword,
+					}, nil)
+				mr.On("GetMFAMethods", mock.Anything, mock.AnythingOfType("uuid.UUID")).
+					Return([]models.MFAMethod{}, nil)
+				mr.On("GetUserRoles", mock.Anything, mock.AnythingOfType("uuid.UUID")).
+					Return([]models.Role{}, nil)
+			},
+			expected: &dto.Response{
+				User: models.User{
+					ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+					Username: "testuser",
+					Email:    "test@example.com",
+				},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "identifier with email",
+			req: &dto.LoginRequest{
+				Identifier: "Test@Example.com",
+				Password:   "wrongpassword",
+			},
+			mockSetup: func(mr *mockrepo.MockRepository) {
+				mr.On("GetUserByEmail", mock.Anything, "", "test@example.com").
+					Return(&models.User{
+						ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+						Email:    "test@example.com",
+						Password: hashedPassword,
+					}, nil)
+			},
+			expected:    nil,
+			expectedErr: ErrInvalidCredentials,
+		},
 		{
 			name: "user not found",
 			req: &dto.LoginRequest{
@@ -148,7 +248,7 @@ func TestServiceLogin(t *testing.T) {
 				Password: "password123",
 			},
 			mockSetup: func(mr *mockrepo.MockRepository) {
-				mr.On("GetUserByEmail", mock.Anything, "notfound@example.com").
+				mr.On("GetUserByEmail", mock.Anything, "", "notfound@example.com").
 					Return(nil, errUserNotFound)
 			},
 			expected:    nil,
@@ -161,7 +261,7 @@ func TestServiceLogin(t *testing.T) {
 				Password: "wrongpassword",
 			},
 			mockSetup: func(mr *mockrepo.MockRepository) {
-				mr.On("GetUserByEmail", mock.Anything, "test@example.com").
+				mr.On("GetUserByEmail", mock.Anything, "", "test@example.com").
 					Return(&models.User{
 						ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
 						Username: "testuser",
@@ -197,3 +297,38 @@ func TestServiceLogin(t *testing.T) {
 		})
 	}
 }
+
+func TestDummyPasswordHash(t *testing.T) {
+	realHash, _ := crypto.HashPassword("password123")
+
+	realCost, err := bcrypt.Cost([]byte(realHash))
+	assert.NoError(t, err)
+	dummyCost, err := bcrypt.Cost([]byte(dummyPasswordHash()))
+	assert.NoError(t, err)
+
+	assert.Equal(t, realCost, dummyCost)
+}
+
+func TestServiceRegisterEnumerationProtection(t *testing.T) {
+	req := &dto.RegisterRequest{
+		Username: "testuser",
+		Email:    "Exists@example.com",
+		Password: "password123",
+	}
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("CreateUser", mock.Anything, mock.AnythingOfType("*models.User")).
+		Return(models.ErrEmailExists)
+	memoryMailer := mailer.NewMemoryMailer()
+	service := New(mockRepo, "secret", time.Hour, WithMailer(memoryMailer), WithEnumerationProtection())
+
+	_, err := service.Register(context.Background(), req)
+
+	assert.ErrorIs(t, err, ErrEmailExists)
+	assert.True(t, service.EnumerationProtection())
+	msg, ok := memoryMailer.Last()
+	assert.True(t, ok)
+	assert.Equal(t, "exists@example.com", msg.To)
+	assert.Contains(t, msg.Subject, "Sign up attempt")
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/ldap"
+)
+
+// ExternalAccount the user an authenticator verified the password of
+type ExternalAccount struct {
+	ExternalIdentity
+	// EmailVerified whether the external store vouches for the email
+	EmailVerified bool
+	// Roles the roles the user has in the external store
+	Roles []string
+	// ManagedRoles the roles the external store manages. The user's roles
+	// among them are replaced with Roles on every login.
+	ManagedRoles []string
+}
+
+// Authenticator verifies the passwords of users stored outside the service,
+// e.g. in a corporate directory. Login tries the authenticators in order
+// for users without a local password.
+type Authenticator interface {
+	// Authenticate returns the account with the identifier and the password,
+	// ErrInvalidCredentials if there is none
+	Authenticate(ctx context.Context, identifier, password string) (*ExternalAccount, error)
+
+	// Lookup returns the identity of the account with the identifier without
+	// checking a password, ErrInvalidCredentials if there is none
+	Lookup(ctx context.Context, identifier string) (*ExternalIdentity, error)
+}
+
+// ldapAuthenticator authenticates the users of an LDAP directory
+type ldapAuthenticator struct {
+	directory *ldap.Directory
+}
+
+// LDAPAuthenticator returns the authenticator of the users of the directory.
+// The identities are linked by the DN of the user's entry.
+func LDAPAuthenticator(directory *ldap.Directory) Authenticator {
+	return &ldapAuthenticator{directory: directory}
+}
+
+// Authenticate binds as the directory user with the username and the password
+func (a *ldapAuthenticator) Authenticate(ctx context.Context, identifier, password string) (*ExternalAccount, error) {
+	entry, err := a.directory.Authenticate(ctx, identifier, password)
+	if err != nil {
+		if errors.Is(err, ldap.ErrInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("directory %s: %w", a.directory.Name(), err)
+	}
+
+	return &ExternalAccount{
+		ExternalIdentity: a.identity(entry),
+		EmailVerified:    a.directory.TrustsEmail(),
+		Roles:            entry.Roles,
+		ManagedRoles:     a.directory.MappedRoles(),
+	}, nil
+}
+
+// Lookup searches the directory user with the username
+func (a *ldapAuthenticator) Lookup(ctx context.Context, identifier string) (*ExternalIdentity, error) {
+	entry, err := a.directory.Lookup(ctx, identifier)
+	if err != nil {
+		if errors.Is(err, ldap.ErrInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("directory %s: %w", a.directory.Name(), err)
+	}
+
+	identity := a.identity(entry)
+	return &identity, nil
+}
+
+// identity returns the identity of the directory user
+func (a *ldapAuthenticator) identity(entry *ldap.Entry) ExternalIdentity {
+	return ExternalIdentity{
+		Provider:    "ldap:" + a.directory.Name(),
+		Subject:     strings.ToLower(entry.DN),
+		Email:       entry.Email,
+		Username:    entry.Username,
+		DisplayName: entry.DisplayName,
+	}
+}
+
+// authenticateExternal tries the authenticators of the request's tenant in order. It returns ErrInvalidCredentials
+// if none of them knows the user with the password, or the last failure of an authenticator.
+func (s *Service) authenticateExternal(ctx context.Context, identifier, password string) (*ExternalAccount, error) {
+	err := ErrInvalidCredentials
+	for _, authenticator := range s.authenticators[s.tenantID(ctx)] {
+		account, authErr := authenticator.Authenticate(ctx, identifier, password)
+		if authErr == nil {
+			return account, nil
+		}
+		if !errors.Is(authErr, ErrInvalidCredentials) {
+			err = authErr
+		}
+	}
+	return nil, err
+}
+
+// loginLockKey returns the key the failed logins with the identifier are counted by.
+// Identifiers unknown locally are looked up in the external stores of the tenant,
+// so all login names of a directory account and the email of its local user
+// share one lockout.
+func (s *Service) loginLockKey(ctx context.Context, user *models.User, identifier string) string {
+	if user != nil || s.lockoutPolicy == nil {
+		return loginFailureKey(s.tenantID(ctx), user, identifier)
+	}
+
+	for _, authenticator := range s.authenticators[s.tenantID(ctx)] {
+		identity, err := authenticator.Lookup(ctx, identifier)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidCredentials) {
+				log.Printf("look up login identifier: %v", err)
+			}
+			continue
+		}
+
+		linked, err := s.repo.GetUserIdentity(ctx, s.tenantID(ctx), identity.Provider, identity.Subject)
+		if err == nil {
+			return loginFailureKey(s.tenantID(ctx), &models.User{ID: linked.UserID}, "")
+		}
+		// the account has no local user before its first login
+		return loginFailureKey(s.tenantID(ctx), nil, identity.Provider+" "+identity.Subject)
+	}
+	return loginFailureKey(s.tenantID(ctx), nil, identifier)
+}
+
+// accountUser returns the local user of the external account, provisioning it
+// on the first login, and syncs the roles the external store manages
+func (s *Service) accountUser(ctx context.Context, account *ExternalAccount) (*models.User, error) {
+	user, err := s.externalUser(ctx, account.ExternalIdentity, account.EmailVerified)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := s.repo.GetUserRoles(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get user roles: %w", err)
+	}
+	current := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		current[role.Name] = true
+	}
+
+	// a mapped role missing here doesn't fail the login
+	for _, role := range account.Roles {
+		if !current[role] {
+			if err := s.repo.AssignRole(ctx, user.ID, role); err != nil {
+				log.Printf("assign role %s to user %s: %v", role, user.ID, err)
+			}
+		}
+	}
+	for _, role := range account.ManagedRoles {
+		if current[role] && !slices.Contains(account.Roles, role) {
+			if err := s.repo.RevokeRole(ctx, user.TenantID, user.ID, role); err != nil {
+				log.Printf("revoke role %s from user %s: %v", role, user.ID, err)
+			}
+		}
+	}
+	return user, nil
+}
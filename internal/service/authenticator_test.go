@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/ldap"
+	"github.com/AlexFox86/auth-service/internal/pkg/ldap/ldaptest"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+func TestServiceLoginLDAP(t *testing.T) {
+	const (
+		baseDN = "dc=corp,dc=example,dc=com"
+		userDN = "cn=Alex Fox,ou=users," + baseDN
+		admins = "cn=Admins,ou=groups," + baseDN
+	)
+
+	stub, err := ldaptest.NewServer()
+	assert.NoError(t, err)
+	defer stub.Close()
+	stub.AddEntry("cn=svc-auth,"+baseDN, "svc-secret", nil)
+	stub.AddEntry(userDN, "password123", map[string][]string{
+		"sAMAccountName": {"afox"},
+		"mail":           {"alex@corp.example.com"},
+		"displayName":    {"Alex Fox"},
+		"memberOf":       {admins},
+	})
+	config := stub.Config("corp", "cn=svc-auth,"+baseDN, "svc-secret", baseDN, "(sAMAccountName={username})")
+	config.GroupRoles = map[string][]string{admins: {"admin"}, "cn=Auditors,ou=groups," + baseDN: {"auditor"}}
+	untrusted := LDAPAuthenticator(ldap.NewDirectory(config))
+	config.TrustEmail = true
+	authenticator := LDAPAuthenticator(ldap.NewDirectory(config))
+
+	user := &models.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), Username: "afox",
+		Email: "alex@corp.example.com", EmailVerified: true}
+
+	t.Run("first login", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByUsername", mock.Anything, "", "afox").Return(nil, models.ErrNotFound)
+		mockRepo.On("GetUserIdentity", mock.Anything, "", "ldap:corp", "cn=alex fox,ou=users,"+baseDN).
+			Return(nil, models.ErrNotFound)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(nil, models.ErrNotFound)
+		mockRepo.On("CreateUser", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+			return u.Username == "afox" && u.DisplayName == "Alex Fox" && !u.HasPassword()
+		})).Return(nil)
+		mockRepo.On("CreateUserIdentity", mock.Anything, mock.AnythingOfType("models.UserIdentity")).Return(nil)
+		mockRepo.On("GetUserRoles", mock.Anything, mock.Anything).Return([]models.Role{}, nil)
+		mockRepo.On("AssignRole", mock.Anything, mock.Anything, "admin").Return(nil)
+		mockRepo.On("GetMFAMethods", mock.Anything, mock.Anything).Return([]models.MFAMethod{}, nil)
+		service := New(mockRepo, "secret", time.Hour, WithAuthenticators("", authenticator))
+
+		resp, err := service.Login(context.Background(), &dto.LoginRequest{Identifier: "afox", Password: "password123"})
+		if assert.NoError(t, err) {
+			claims, err := token.ValidateToken(resp.Token, service.JwtSecret())
+			assert.NoError(t, err)
+			assert.Equal(t, []string{token.AMRPassword}, token.StringsClaim(claims, "amr"))
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("linked user left a group", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByUsername", mock.Anything, "", "afox").Return(user, nil)
+		mockRepo.On("GetUserIdentity", mock.Anything, "", "ldap:corp", "cn=alex fox,ou=users,"+baseDN).
+			Return(&models.UserIdentity{UserID: user.ID}, nil)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+		mockRepo.On("GetUserRoles", mock.Anything, user.ID).
+			Return([]models.Role{{Name: "admin"}, {Name: "auditor"}, {Name: "support"}}, nil)
+		mockRepo.On("RevokeRole", mock.Anything, "", user.ID, "auditor").Return(nil)
+		mockRepo.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{}, nil)
+		service := New(mockRepo, "secret", time.Hour, WithAuthenticators("", authenticator))
+
+		_, err := service.Login(context.Background(), &dto.LoginRequest{Identifier: "afox", Password: "password123"})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "RevokeRole", mock.Anything, "", user.ID, "support")
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByUsername", mock.Anything, "", "afox").Return(nil, models.ErrNotFound)
+		service := New(mockRepo, "secret", time.Hour, WithAuthenticators("", authenticator))
+
+		_, err := service.Login(context.Background(), &dto.LoginRequest{Identifier: "afox", Password: "wrong"})
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		mockRepo.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("directory of another tenant", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByUsername", mock.Anything, "", "afox").Return(nil, models.ErrNotFound)
+		service := New(mockRepo, "secret", time.Hour, WithAuthenticators("acme", authenticator))
+
+		_, err := service.Login(context.Background(), &dto.LoginRequest{Identifier: "afox", Password: "password123"})
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		mockRepo.AssertNotCalled(t, "GetUserIdentity", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("lockout of the linked user", func(t *testing.T) {
+		userKey := "user:" + user.ID.String()
+		until := time.Now().Add(time.Minute)
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByUsername", mock.Anything, "", "afox").Return(nil, models.ErrNotFound)
+		mockRepo.On("GetUserIdentity", mock.Anything, "", "ldap:corp", "cn=alex fox,ou=users,"+baseDN).
+			Return(&models.UserIdentity{UserID: user.ID}, nil)
+		mockRepo.On("GetLoginFailure", mock.Anything, userKey).
+			Return(&models.LoginFailure{Key: userKey, Attempts: 10, LockedUntil: &until}, nil)
+		service := New(mockRepo, "secret", time.Hour, WithAuthenticators("", authenticator),
+			WithLockoutPolicy(DefaultLockoutPolicy()))
+
+		// the directory login name shares the lockout with the email of the local user
+		_, err := service.Login(context.Background(), &dto.LoginRequest{Identifier: "afox", Password: "password123"})
+		assert.ErrorIs(t, err, ErrAccountLocked)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("lockout before the first login", func(t *testing.T) {
+		key := loginFailureKey("", nil, "ldap:corp cn=alex fox,ou=users,"+baseDN)
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByUsername", mock.Anything, "", "AFox").Return(nil, models.ErrNotFound)
+		mockRepo.On("GetUserIdentity", mock.Anything, "", "ldap:corp", "cn=alex fox,ou=users,"+baseDN).
+			Return(nil, models.ErrNotFound)
+		mockRepo.On("GetLoginFailure", mock.Anything, key).Return(nil, models.ErrNotFound)
+		mockRepo.On("IncrementLoginFailures", mock.Anything, key, mock.AnythingOfType("time.Time")).Return(1, nil)
+		service := New(mockRepo, "secret", time.Hour, WithAuthenticators("", authenticator),
+			WithLockoutPolicy(DefaultLockoutPolicy()))
+
+		_, err := service.Login(context.Background(), &dto.LoginRequest{Identifier: "AFox", Password: "wrong"})
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("local password", func(t *testing.T) {
+		hashedPassword, _ := crypto.HashPassword("local-password")
+		local := &models.User{ID: user.ID, Username: "afox", Password: hashedPassword}
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByUsername", mock.Anything, "", "afox").Return(local, nil)
+		service := New(mockRepo, "secret", time.Hour, WithAuthenticators("", authenticator))
+
+		// users with a local password never log in with the directory password
+		_, err := service.Login(context.Background(), &dto.LoginRequest{Identifier: "afox", Password: "password123"})
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		mockRepo.AssertNotCalled(t, "GetUserIdentity", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("invite-only registration", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByUsername", mock.Anything, "", "afox").Return(nil, models.ErrNotFound)
+		mockRepo.On("GetUserIdentity", mock.Anything, "", "ldap:corp", mock.Anything).Return(nil, models.ErrNotFound)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(nil, models.ErrNotFound)
+		service := New(mockRepo, "secret", time.Hour, WithAuthenticators("", authenticator),
+			WithRegistrationMode(RegistrationInviteOnly))
+
+		_, err := service.Login(context.Background(), &dto.LoginRequest{Identifier: "afox", Password: "password123"})
+		assert.ErrorIs(t, err, ErrInviteRequired)
+	})
+
+	t.Run("untrusted email", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByUsername", mock.Anything, "", "afox").Return(nil, models.ErrNotFound)
+		mockRepo.On("GetUserIdentity", mock.Anything, "", "ldap:corp", mock.Anything).Return(nil, models.ErrNotFound)
+		service := New(mockRepo, "secret", time.Hour, WithAuthenticators("", untrusted))
+
+		_, err := service.Login(context.Background(), &dto.LoginRequest{Identifier: "afox", Password: "password123"})
+		assert.ErrorIs(t, err, ErrExternalEmailNotVerified)
+		mockRepo.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("disabled user", func(t *testing.T) {
+		disabled := *user
+		disabled.Status = models.UserStatusDisabled
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByUsername", mock.Anything, "", "afox").Return(&disabled, nil)
+		mockRepo.On("GetUserIdentity", mock.Anything, "", "ldap:corp", mock.Anything).
+			Return(&models.UserIdentity{UserID: user.ID}, nil)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(&disabled, nil)
+		mockRepo.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{{Name: "admin"}}, nil)
+		service := New(mockRepo, "secret", time.Hour, WithAuthenticators("", authenticator))
+
+		_, err := service.Login(context.Background(), &dto.LoginRequest{Identifier: "afox", Password: "password123"})
+		assert.ErrorIs(t, err, ErrAccountDisabled)
+	})
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/google/uuid"
+)
+
+const (
+	emailChangeConfirmPurpose = "email_change_confirm"
+	emailChangeRevertPurpose  = "email_change_revert"
+	emailChangeConfirmExpiry  = 24 * time.Hour
+	emailChangeRevertExpiry   = 7 * 24 * time.Hour
+)
+
+// ErrInvalidEmailChange returned when the email change link is invalid,
+// expired or has already been used
+var ErrInvalidEmailChange = errors.New("invalid email change")
+
+// RequestEmailChange starts the email change. The new email is set only
+// after the confirmation sent to it, and the old email gets a link to revert the change.
+func (s *Service) RequestEmailChange(ctx context.Context, userID uuid.UUID, req *dto.ChangeEmailRequest) error {
+	if err := forbidImpersonation(ctx); err != nil {
+		return err
+	}
+
+	if s.mailer == nil {
+		return ErrMailerNotConfigured
+	}
+
+	user, err := s.repo.GetUserByID(ctx, s.tenantID(ctx), userID)
+	if err != nil {
+		return ErrInvalidCredentials
+	}
+
+	if err := crypto.CheckPassword(req.Password, user.Password); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	newEmail := normalizeEmail(req.NewEmail)
+	if newEmail == normalizeEmail(user.Email) {
+		return ErrEmailExists
+	}
+	if err := s.checkEmailDomain(newEmail); err != nil {
+		return err
+	}
+	if _, err := s.repo.GetUserByEmail(ctx, s.tenantID(ctx), newEmail); err == nil {
+		return ErrEmailExists
+	}
+
+	change := models.EmailChange{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		OldEmail:  user.Email,
+		NewEmail:  newEmail,
+		Status:    models.EmailChangePending,
+		ExpiresAt: time.Now().Add(emailChangeConfirmExpiry),
+	}
+	if err := s.repo.CreateEmailChange(ctx, change); err != nil {
+		return fmt.Errorf("create email change: %w", err)
+	}
+
+	confirmToken, err := token.GenerateLinkToken(emailChangeConfirmPurpose, map[string]any{
+		"jti": change.ID.String(),
+	}, s.SigningKey(ctx), emailChangeConfirmExpiry)
+	if err != nil {
+		return fmt.Errorf("generate confirmation token: %w", err)
+	}
+
+	revertToken, err := token.GenerateLinkToken(emailChangeRevertPurpose, map[string]any{
+		"jti": change.ID.String(),
+	}, s.SigningKey(ctx), emailChangeRevertExpiry)
+	if err != nil {
+		return fmt.Errorf("generate revert token: %w", err)
+	}
+
+	confirm := mailer.Message{
+		To:      change.NewEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Confirm your new email address by following the link:\n\n%s/me/email/confirm?token=%s\n\n"+
+			"The link expires in %s.", s.appURL(ctx), url.QueryEscape(confirmToken), emailChangeConfirmExpiry),
+	}
+	if err := s.mailer.Send(ctx, confirm); err != nil {
+		return fmt.Errorf("send confirmation email: %w", err)
+	}
+
+	notify := mailer.Message{
+		To:      change.OldEmail,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("A change of your account email to %s has been requested.\n\n"+
+			"If it wasn't you, revert the change by following the link:\n\n%s/me/email/revert?token=%s\n\n"+
+			"The link expires in %s.", change.NewEmail, s.appURL(ctx), url.QueryEscape(revertToken), emailChangeRevertExpiry),
+	}
+	if err := s.mailer.Send(ctx, notify); err != nil {
+		return fmt.Errorf("send notification email: %w", err)
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange swaps the user's email to the new one
+func (s *Service) ConfirmEmailChange(ctx context.Context, req *dto.EmailChangeTokenRequest) error {
+	change, err := s.emailChangeFromToken(ctx, req.Token, emailChangeConfirmPurpose)
+	if err != nil {
+		return err
+	}
+
+	if change.Status != models.EmailChangePending || time.Now().After(change.ExpiresAt) {
+		return ErrInvalidEmailChange
+	}
+
+	// the email could have been taken since the request
+	if _, err := s.repo.GetUserByEmail(ctx, s.tenantID(ctx), change.NewEmail); err == nil {
+		return ErrEmailExists
+	}
+
+	if err := s.repo.ConfirmEmailChange(ctx, *change); err != nil {
+		return fmt.Errorf("confirm email change: %w", err)
+	}
+	s.audit(ctx, models.AuditEvent{Type: models.AuditEmailChanged, UserID: &change.UserID})
+	return nil
+}
+
+// RevertEmailChange cancels the pending change or restores the old email
+func (s *Service) RevertEmailChange(ctx context.Context, req *dto.EmailChangeTokenRequest) error {
+	change, err := s.emailChangeFromToken(ctx, req.Token, emailChangeRevertPurpose)
+	if err != nil {
+		return err
+	}
+
+	if change.Status == models.EmailChangeReverted {
+		return ErrInvalidEmailChange
+	}
+
+	if err := s.repo.RevertEmailChange(ctx, *change); err != nil {
+		return fmt.Errorf("revert email change: %w", err)
+	}
+	s.audit(ctx, models.AuditEvent{Type: models.AuditEmailChangeReverted, UserID: &change.UserID})
+	return nil
+}
+
+func (s *Service) emailChangeFromToken(ctx context.Context, changeToken, purpose string) (*models.EmailChange, error) {
+	claims, err := token.ValidateLinkToken(changeToken, purpose, s.SigningKey(ctx))
+	if err != nil {
+		return nil, ErrInvalidEmailChange
+	}
+
+	jti, _ := claims["jti"].(string)
+	id, err := uuid.Parse(jti)
+	if err != nil {
+		return nil, ErrInvalidEmailChange
+	}
+
+	change, err := s.repo.GetEmailChange(ctx, id)
+	if err != nil {
+		return nil, ErrInvalidEmailChange
+	}
+	return change, nil
+}
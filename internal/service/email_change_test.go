@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+func TestServiceEmailChange(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("password123")
+	user := &models.User{
+		ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		Email:    "old@example.com",
+		Password: hashedPassword,
+	}
+
+	t.Run("wrong password", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+		service := New(mockRepo, "secret", time.Hour, WithMailer(mailer.NewMemoryMailer()))
+
+		err := service.RequestEmailChange(context.Background(), user.ID, &dto.ChangeEmailRequest{
+			NewEmail: "new@example.com",
+			Password: "wrongpassword",
+		})
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("email exists", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", "taken@example.com").Return(&models.User{ID: uuid.New()}, nil)
+		service := New(mockRepo, "secret", time.Hour, WithMailer(mailer.NewMemoryMailer()))
+
+		err := service.RequestEmailChange(context.Background(), user.ID, &dto.ChangeEmailRequest{
+			NewEmail: "taken@example.com",
+			Password: "password123",
+		})
+		assert.ErrorIs(t, err, ErrEmailExists)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("confirm and revert", func(t *testing.T) {
+		var change models.EmailChange
+
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", "new@example.com").Return(nil, errUserNotFound)
+		mockRepo.On("CreateEmailChange", mock.Anything, mock.AnythingOfType("models.EmailChange")).
+			Return(nil).
+			Run(func(args mock.Arguments) {
+				change = args.Get(1).(models.EmailChange)
+			})
+		m := mailer.NewMemoryMailer()
+		service := New(mockRepo, "secret", time.Hour, WithMailer(m), WithFrontendURL("https://app.example.com"))
+
+		err := service.RequestEmailChange(context.Background(), user.ID, &dto.ChangeEmailRequest{
+			NewEmail: "new@example.com",
+			Password: "password123",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, models.EmailChangePending, change.Status)
+		assert.Equal(t, "old@example.com", change.OldEmail)
+
+		messages := m.Messages()
+		if !assert.Len(t, messages, 2) {
+			return
+		}
+		assert.Equal(t, "new@example.com", messages[0].To)
+		assert.Equal(t, "old@example.com", messages[1].To)
+		assert.Contains(t, messages[0].Body, "https://app.example.com/me/email/confirm?token=")
+		assert.Contains(t, messages[1].Body, "https://app.example.com/me/email/revert?token=")
+		confirmToken := linkToken(t, messages[0].Body)
+		revertToken := linkToken(t, messages[1].Body)
+
+		// tokens can't be used for each other's purpose
+		mockRepo.On("GetEmailChange", mock.Anything, change.ID).Return(&change, nil).Once()
+		err = service.RevertEmailChange(context.Background(), &dto.EmailChangeTokenRequest{Token: confirmToken})
+		assert.ErrorIs(t, err, ErrInvalidEmailChange)
+
+		mockRepo.On("ConfirmEmailChange", mock.Anything, change).Return(nil).Once()
+		err = service.ConfirmEmailChange(context.Background(), &dto.EmailChangeTokenRequest{Token: confirmToken})
+		assert.NoError(t, err)
+
+		confirmed := change
+		confirmed.Status = models.EmailChangeConfirmed
+		mockRepo.On("GetEmailChange", mock.Anything, change.ID).Return(&confirmed, nil)
+		err = service.ConfirmEmailChange(context.Background(), &dto.EmailChangeTokenRequest{Token: confirmToken})
+		assert.ErrorIs(t, err, ErrInvalidEmailChange)
+
+		mockRepo.On("RevertEmailChange", mock.Anything, confirmed).Return(nil).Once()
+		err = service.RevertEmailChange(context.Background(), &dto.EmailChangeTokenRequest{Token: revertToken})
+		assert.NoError(t, err)
+
+		mockRepo.AssertExpectations(t)
+	})
+}
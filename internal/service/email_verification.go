@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/google/uuid"
+)
+
+const (
+	emailVerifyPurpose = "email_verify"
+	emailVerifyExpiry  = 24 * time.Hour
+)
+
+// EmailVerificationPolicy defines what users with an unverified email can do
+type EmailVerificationPolicy int
+
+const (
+	// EmailVerificationOptional allows unverified users to log in.
+	// Their tokens have the "email_verified": false claim, so routes
+	// can be limited with Handler.RequireVerifiedEmail.
+	EmailVerificationOptional EmailVerificationPolicy = iota
+
+	// EmailVerificationRequired blocks login until the email is verified
+	EmailVerificationRequired
+)
+
+var (
+	// ErrEmailNotVerified returned by Login when the policy requires a verified email
+	ErrEmailNotVerified = errors.New("email not verified")
+
+	// ErrInvalidVerificationToken returned when the email verification token is invalid or expired
+	ErrInvalidVerificationToken = errors.New("invalid verification token")
+)
+
+// VerifyEmail marks the user's email as verified
+func (s *Service) VerifyEmail(ctx context.Context, req *dto.VerifyEmailRequest) error {
+	claims, err := token.ValidateLinkToken(req.Token, emailVerifyPurpose, s.SigningKey(ctx))
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+
+	sub, _ := claims["sub"].(string)
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+
+	user, err := s.repo.GetUserByID(ctx, s.tenantID(ctx), userID)
+	if err != nil || user.Email != claims["email"] {
+		return ErrInvalidVerificationToken
+	}
+
+	if user.EmailVerified {
+		return nil
+	}
+
+	if err := s.repo.SetEmailVerified(ctx, user.ID); err != nil {
+		return fmt.Errorf("set email verified: %w", err)
+	}
+	return nil
+}
+
+// ResendEmailVerification sends the verification link again.
+// The result does not depend on whether the user exists.
+func (s *Service) ResendEmailVerification(ctx context.Context, req *dto.ResendVerificationRequest) error {
+	if s.mailer == nil {
+		return ErrMailerNotConfigured
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, s.tenantID(ctx), normalizeEmail(req.Email))
+	if err != nil || user.EmailVerified {
+		return nil
+	}
+
+	return s.sendEmailVerification(ctx, user)
+}
+
+// sendEmailVerification sends the link to verify the user's current email
+func (s *Service) sendEmailVerification(ctx context.Context, user *models.User) error {
+	if s.mailer == nil {
+		return ErrMailerNotConfigured
+	}
+
+	verifyToken, err := token.GenerateLinkToken(emailVerifyPurpose, map[string]any{
+		"sub":   user.ID.String(),
+		"email": user.Email,
+	}, s.SigningKey(ctx), emailVerifyExpiry)
+	if err != nil {
+		return fmt.Errorf("generate verification token: %w", err)
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Confirm your email address by following the link:\n\n%s/email/verify?token=%s\n\n"+
+			"The link expires in %s.", s.appURL(ctx), url.QueryEscape(verifyToken), emailVerifyExpiry),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("send verification email: %w", err)
+	}
+	return nil
+}
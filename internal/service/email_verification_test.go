@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+func TestServiceEmailVerification(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("CreateUser", mock.Anything, mock.AnythingOfType("*models.User")).
+		Return(nil).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(*models.User).ID = userID
+		})
+	m := mailer.NewMemoryMailer()
+	service := New(mockRepo, "secret", time.Hour, WithMailer(m),
+		WithBaseURL("https://auth.example.com"), WithFrontendURL("https://app.example.com"))
+
+	user, err := service.Register(context.Background(), &dto.RegisterRequest{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "password123",
+	})
+	assert.NoError(t, err)
+	assert.False(t, user.EmailVerified)
+
+	msg, ok := m.Last()
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, "test@example.com", msg.To)
+	assert.Contains(t, msg.Body, "https://app.example.com/email/verify?token=")
+	verifyToken := linkToken(t, msg.Body)
+
+	t.Run("successful verification", func(t *testing.T) {
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).
+			Return(&models.User{ID: userID, Email: "test@example.com"}, nil).Once()
+		mockRepo.On("SetEmailVerified", mock.Anything, userID).Return(nil).Once()
+
+		err := service.VerifyEmail(context.Background(), &dto.VerifyEmailRequest{Token: verifyToken})
+		assert.NoError(t, err)
+	})
+
+	t.Run("email changed", func(t *testing.T) {
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).
+			Return(&models.User{ID: userID, Email: "new@example.com"}, nil).Once()
+
+		err := service.VerifyEmail(context.Background(), &dto.VerifyEmailRequest{Token: verifyToken})
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		err := service.VerifyEmail(context.Background(), &dto.VerifyEmailRequest{Token: "invalid"})
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+	})
+
+	t.Run("resend to unknown email", func(t *testing.T) {
+		mockRepo.On("GetUserByEmail", mock.Anything, "", "notfound@example.com").Return(nil, errUserNotFound).Once()
+
+		err := service.ResendEmailVerification(context.Background(), &dto.ResendVerificationRequest{Email: "notfound@example.com"})
+		assert.NoError(t, err)
+		assert.Len(t, m.Messages(), 1)
+	})
+
+	t.Run("resend", func(t *testing.T) {
+		mockRepo.On("GetUserByEmail", mock.Anything, "", "test@example.com").
+			Return(&models.User{ID: userID, Email: "test@example.com"}, nil).Once()
+
+		err := service.ResendEmailVerification(context.Background(), &dto.ResendVerificationRequest{Email: "test@example.com"})
+		assert.NoError(t, err)
+		assert.Len(t, m.Messages(), 2)
+	})
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestServiceLoginEmailNotVerified(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("password123")
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByEmail", mock.Anything, "", "test@example.com").
+		Return(&models.User{
+			ID:       uuid.New(),
+			Email:    "test@example.com",
+			Password: hashedPassword,
+		}, nil)
+
+	service := New(mockRepo, "secret", time.Hour, WithEmailVerificationPolicy(EmailVerificationRequired))
+	resp, err := service.Login(context.Background(), &dto.LoginRequest{Email: "test@example.com", Password: "password123"})
+	assert.ErrorIs(t, err, ErrEmailNotVerified)
+	assert.Nil(t, resp)
+	mockRepo.AssertExpectations(t)
+}
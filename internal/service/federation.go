@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxUsernameAttempts the number of usernames tried for a provisioned user
+	maxUsernameAttempts = 3
+
+	// maxUsernameBase the maximum length of the username derived from the external identity,
+	// leaving room for the suffix
+	maxUsernameBase = 24
+)
+
+// ExternalIdentity the user as an external identity provider describes it
+type ExternalIdentity struct {
+	// Provider the provider the identity is linked with, also the method
+	// of the audit events, e.g. oidc:google
+	Provider string
+	// Subject the identifier of the user at the provider
+	Subject     string
+	Email       string
+	Username    string
+	DisplayName string
+}
+
+// provisionUser creates the local user of the external identity on the first login
+// (just-in-time provisioning). The registration mode and email domains apply
+// as for self-registration. The user has no password until a password reset.
+func (s *Service) provisionUser(ctx context.Context, identity ExternalIdentity) (*models.User, error) {
+	email := normalizeEmail(identity.Email)
+	if _, err := s.checkRegistration(ctx, &dto.RegisterRequest{Email: email}); err != nil {
+		return nil, err
+	}
+
+	username := s.externalUsername(identity.Username, email)
+	for attempt := 1; ; attempt++ {
+		user, err := s.repo.CreateUser(ctx, models.User{
+			TenantID:      s.tenantID(ctx),
+			Username:      username,
+			Email:         email,
+			EmailVerified: true,
+			DisplayName:   strings.TrimSpace(identity.DisplayName),
+		})
+		if err == nil {
+			s.audit(ctx, models.AuditEvent{Type: models.AuditRegistered, UserID: &user.ID, Identifier: user.Username,
+				Method: identity.Provider})
+			return &user, nil
+		}
+		if !errors.Is(err, ErrUsernameExists) || attempt == maxUsernameAttempts {
+			return nil, fmt.Errorf("create user: %w", err)
+		}
+
+		suffix, err := crypto.GenerateRandomString(3)
+		if err != nil {
+			return nil, fmt.Errorf("generate username: %w", err)
+		}
+		username = s.externalUsername(identity.Username, email) + "-" + suffix
+	}
+}
+
+// externalUsername returns the username the provider suggests, or the local part
+// of the email if it can't be used
+func (s *Service) externalUsername(preferred, email string) string {
+	username := strings.TrimSpace(preferred)
+	if username == "" || strings.Contains(username, "@") || s.isReservedUsername(username) {
+		username, _, _ = strings.Cut(email, "@")
+	}
+	if runes := []rune(username); len(runes) > maxUsernameBase {
+		username = string(runes[:maxUsernameBase])
+	}
+	if len([]rune(username)) < 3 || s.isReservedUsername(username) {
+		username += "-user"
+	}
+	return username
+}
+
+// linkIdentity links the external identity to the user
+func (s *Service) linkIdentity(ctx context.Context, user *models.User, identity ExternalIdentity) error {
+	err := s.repo.CreateUserIdentity(ctx, models.UserIdentity{
+		ID:        uuid.New(),
+		TenantID:  s.tenantID(ctx),
+		UserID:    user.ID,
+		Provider:  identity.Provider,
+		Subject:   identity.Subject,
+		Email:     normalizeEmail(identity.Email),
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("link identity: %w", err)
+	}
+
+	s.audit(ctx, models.AuditEvent{Type: models.AuditIdentityLinked, UserID: &user.ID, Method: identity.Provider})
+	return nil
+}
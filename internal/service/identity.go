@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrIdentityExists returned when the external identity is linked to another user
+	ErrIdentityExists = models.ErrIdentityExists
+
+	// ErrIdentityNotFound returned when the user has no linked identity with the ID
+	ErrIdentityNotFound = errors.New("identity not found")
+
+	// ErrLastLoginMethod returned when unlinking the identity would leave the user
+	// without a way to log in
+	ErrLastLoginMethod = errors.New("identity is the last login method")
+)
+
+// Identities returns the login methods of the user: whether the user has a password
+// and the linked external identities
+func (s *Service) Identities(ctx context.Context, userID uuid.UUID) (*dto.UserIdentities, error) {
+	user, err := s.User(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	identities, err := s.repo.ListUserIdentities(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list identities: %w", err)
+	}
+	return &dto.UserIdentities{HasPassword: user.HasPassword(), Identities: identities}, nil
+}
+
+// StartIdentityLink returns the URL of the provider's login page and a nonce like StartOIDCLogin.
+// The identity the user logs in with at the provider is linked to the user.
+func (s *Service) StartIdentityLink(ctx context.Context, userID uuid.UUID, name string) (authURL, nonce string, err error) {
+	if err := forbidImpersonation(ctx); err != nil {
+		return "", "", err
+	}
+
+	user, err := s.User(ctx, userID)
+	if err != nil {
+		return "", "", err
+	}
+	return s.startOIDCLogin(ctx, name, map[string]any{"link": user.ID.String()})
+}
+
+// linkExternalUser links the external identity to the user who started linking it.
+// Linking an identity again is a no-op.
+func (s *Service) linkExternalUser(ctx context.Context, userID string, identity ExternalIdentity) (*models.User, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrInvalidOIDCLogin
+	}
+	user, err := s.User(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	linked, err := s.repo.GetUserIdentity(ctx, s.tenantID(ctx), identity.Provider, identity.Subject)
+	if err == nil {
+		if linked.UserID != user.ID {
+			return nil, ErrIdentityExists
+		}
+		return user, nil
+	}
+
+	if err := s.linkIdentity(ctx, user, identity); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// UnlinkIdentity unlinks the external identity from the user. The last identity
+// of a user without a password can't be unlinked.
+func (s *Service) UnlinkIdentity(ctx context.Context, userID, identityID uuid.UUID) error {
+	if err := forbidImpersonation(ctx); err != nil {
+		return err
+	}
+
+	user, err := s.User(ctx, userID)
+	if err != nil {
+		return err
+	}
+	identities, err := s.repo.ListUserIdentities(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("list identities: %w", err)
+	}
+
+	var identity *models.UserIdentity
+	for i := range identities {
+		if identities[i].ID == identityID {
+			identity = &identities[i]
+		}
+	}
+	if identity == nil {
+		return ErrIdentityNotFound
+	}
+	if !user.HasPassword() && len(identities) == 1 {
+		return ErrLastLoginMethod
+	}
+
+	if err := s.repo.DeleteUserIdentity(ctx, user.ID, identity.ID); err != nil {
+		return ErrIdentityNotFound
+	}
+	s.audit(ctx, models.AuditEvent{Type: models.AuditIdentityUnlinked, UserID: &user.ID, Method: identity.Provider})
+	return nil
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/oidc"
+	"github.com/AlexFox86/auth-service/internal/pkg/oidc/oidctest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+func TestServiceLinkIdentity(t *testing.T) {
+	stub, err := oidctest.NewServer("client", "secret")
+	assert.NoError(t, err)
+	defer stub.Close()
+	provider := oidc.NewProvider(stub.Config("stub"), nil)
+
+	user := &models.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), Email: "alex@example.com", Password: "hash"}
+	// the identity's email differs from the user's and isn't verified, linking doesn't need it
+	claims := map[string]any{"sub": "12345", "email": "alex@work.example.com"}
+
+	link := func(t *testing.T, service *Service) (state, code, nonce string) {
+		authURL, nonce, err := service.StartIdentityLink(context.Background(), user.ID, "stub")
+		assert.NoError(t, err)
+		code, err = stub.Authorize(authURL, claims)
+		assert.NoError(t, err)
+		u, _ := url.Parse(authURL)
+		return u.Query().Get("state"), code, nonce
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+		mockRepo.On("GetUserIdentity", mock.Anything, "", "oidc:stub", "12345").Return(nil, models.ErrNotFound)
+		mockRepo.On("CreateUserIdentity", mock.Anything, mock.MatchedBy(func(i models.UserIdentity) bool {
+			return i.UserID == user.ID && i.Provider == "oidc:stub" && i.Subject == "12345"
+		})).Return(nil)
+		mockRepo.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{}, nil)
+		mockRepo.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{}, nil)
+		sink := &memorySink{}
+		service := New(mockRepo, "secret", time.Hour, WithBaseURL("https://auth.example.com"),
+			WithOIDCProviders(provider), WithAuditSink(sink))
+
+		state, code, nonce := link(t, service)
+		_, err := service.CompleteOIDCLogin(context.Background(), "stub", state, code, nonce)
+		assert.NoError(t, err)
+		if assert.NotEmpty(t, sink.events) {
+			assert.Equal(t, models.AuditIdentityLinked, sink.events[0].Type)
+			assert.Equal(t, "oidc:stub", sink.events[0].Method)
+		}
+		mockRepo.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("linked to another user", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+		mockRepo.On("GetUserIdentity", mock.Anything, "", "oidc:stub", "12345").Return(&models.UserIdentity{UserID: uuid.New()}, nil)
+		service := New(mockRepo, "secret", time.Hour, WithBaseURL("https://auth.example.com"), WithOIDCProviders(provider))
+
+		state, code, nonce := link(t, service)
+		_, err := service.CompleteOIDCLogin(context.Background(), "stub", state, code, nonce)
+		assert.ErrorIs(t, err, ErrIdentityExists)
+		mockRepo.AssertNotCalled(t, "CreateUserIdentity", mock.Anything, mock.Anything)
+	})
+
+	t.Run("impersonating", func(t *testing.T) {
+		service := New(new(mockrepo.MockRepository), "secret", time.Hour, WithOIDCProviders(provider))
+
+		ctx := ContextWithActor(context.Background(), uuid.New())
+		_, _, err := service.StartIdentityLink(ctx, user.ID, "stub")
+		assert.ErrorIs(t, err, ErrImpersonating)
+	})
+}
+
+func TestServiceUnlinkIdentity(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	first := models.UserIdentity{ID: uuid.MustParse("00000000-0000-0000-0000-000000000011"), UserID: userID, Provider: "oidc:google"}
+	second := models.UserIdentity{ID: uuid.MustParse("00000000-0000-0000-0000-000000000012"), UserID: userID, Provider: "oidc:github"}
+
+	tests := []struct {
+		name        string
+		password    string
+		identities  []models.UserIdentity
+		identityID  uuid.UUID
+		expectedErr error
+	}{
+		{
+			name:       "user with a password",
+			password:   "hash",
+			identities: []models.UserIdentity{first},
+			identityID: first.ID,
+		},
+		{
+			name:       "another identity left",
+			identities: []models.UserIdentity{first, second},
+			identityID: second.ID,
+		},
+		{
+			name:        "last login method",
+			identities:  []models.UserIdentity{first},
+			identityID:  first.ID,
+			expectedErr: ErrLastLoginMethod,
+		},
+		{
+			name:        "not found",
+			password:    "hash",
+			identities:  []models.UserIdentity{first},
+			identityID:  second.ID,
+			expectedErr: ErrIdentityNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID, Password: tt.password}, nil)
+			mockRepo.On("ListUserIdentities", mock.Anything, userID).Return(tt.identities, nil)
+			if tt.expectedErr == nil {
+				mockRepo.On("DeleteUserIdentity", mock.Anything, userID, tt.identityID).Return(nil)
+			}
+			service := New(mockRepo, "secret", time.Hour)
+
+			err := service.UnlinkIdentity(context.Background(), userID, tt.identityID)
+			assert.ErrorIs(t, err, tt.expectedErr)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceLoginWithoutPassword(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "alex@example.com", Status: models.UserStatusActive}
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+	mockRepo.On("GetLoginFailure", mock.Anything, mock.Anything).Return(nil, models.ErrNotFound)
+	mockRepo.On("IncrementLoginFailures", mock.Anything, mock.Anything, mock.Anything).Return(1, nil)
+	service := New(mockRepo, "secret", time.Hour)
+
+	for _, password := range []string{"", "password123"} {
+		_, err := service.Login(context.Background(), &dto.LoginRequest{Email: user.Email, Password: password})
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+func TestServiceImpersonate(t *testing.T) {
+	adminID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	user := &models.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000002"), Username: "alex"}
+	support := []models.Role{{Name: "support", Permissions: []string{models.PermissionImpersonate, models.PermissionUsersRead}}}
+	req := &dto.ImpersonateRequest{Reason: "ticket #42"}
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+		mockRepo.On("GetUserRoles", mock.Anything, adminID).Return(support, nil)
+		mockRepo.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{}, nil)
+		sink := &memorySink{}
+		service := New(mockRepo, "secret", time.Hour, WithAuditSink(sink))
+
+		resp, err := service.Impersonate(context.Background(), adminID, user.ID, req)
+		if assert.NoError(t, err) {
+			claims, err := token.ValidateToken(resp.Token, service.JwtSecret())
+			assert.NoError(t, err)
+			assert.Equal(t, user.ID.String(), claims["sub"])
+			actor, ok := token.Actor(claims)
+			assert.True(t, ok)
+			assert.Equal(t, adminID.String(), actor)
+			assert.InDelta(t, time.Now().Add(defaultImpersonationExpiry).Unix(), claims["exp"], 5)
+
+			// the admin doesn't pass as a freshly authenticated user
+			assert.Equal(t, token.ACRImpersonation, claims["acr"])
+			assert.False(t, token.ACRSatisfies(token.ACRImpersonation, token.ACRSingleFactor))
+			_, ok = token.AuthTime(claims)
+			assert.False(t, ok)
+		}
+		if assert.Len(t, sink.events, 1) {
+			assert.Equal(t, models.AuditImpersonationStart, sink.events[0].Type)
+			assert.Equal(t, &adminID, sink.events[0].ActorID)
+			assert.Equal(t, "ticket #42", sink.events[0].Reason)
+		}
+	})
+
+	t.Run("more privileged user", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+		mockRepo.On("GetUserRoles", mock.Anything, adminID).Return(support, nil)
+		mockRepo.On("GetUserRoles", mock.Anything, user.ID).
+			Return([]models.Role{{Name: "admin", Permissions: []string{models.PermissionRolesManage}}}, nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		_, err := service.Impersonate(context.Background(), adminID, user.ID, req)
+		assert.ErrorIs(t, err, ErrImpersonationNotAllowed)
+	})
+
+	t.Run("self", func(t *testing.T) {
+		service := New(new(mockrepo.MockRepository), "secret", time.Hour)
+
+		_, err := service.Impersonate(context.Background(), adminID, adminID, req)
+		assert.ErrorIs(t, err, ErrImpersonationNotAllowed)
+	})
+
+	t.Run("while impersonating", func(t *testing.T) {
+		service := New(new(mockrepo.MockRepository), "secret", time.Hour)
+		ctx := ContextWithActor(context.Background(), uuid.New())
+
+		_, err := service.Impersonate(ctx, adminID, user.ID, req)
+		assert.ErrorIs(t, err, ErrImpersonationNotAllowed)
+	})
+}
+
+func TestServiceImpersonationForbidsSensitiveActions(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	mockRepo := new(mockrepo.MockRepository)
+	sink := &memorySink{}
+	service := New(mockRepo, "secret", time.Hour, WithAuditSink(sink))
+	ctx := ContextWithActor(context.Background(), uuid.MustParse("00000000-0000-0000-0000-000000000001"))
+
+	_, err := service.StepUp(ctx, userID, uuid.Nil, &dto.StepUpRequest{Password: "password123"})
+	assert.ErrorIs(t, err, ErrImpersonating)
+	_, err = service.DeleteAccount(ctx, userID, &dto.DeleteAccountRequest{Password: "password123"})
+	assert.ErrorIs(t, err, ErrImpersonating)
+	_, err = service.CreateAPIKey(ctx, userID, &dto.CreateAPIKeyRequest{Name: "ci"})
+	assert.ErrorIs(t, err, ErrImpersonating)
+	assert.ErrorIs(t, service.DeleteMFAMethod(ctx, userID, uuid.New()), ErrImpersonating)
+	assert.ErrorIs(t, service.ConfirmMFAMethod(ctx, userID, uuid.New(), "123456"), ErrImpersonating)
+	assert.ErrorIs(t, service.RevokeAPIKey(ctx, userID, uuid.New()), ErrImpersonating)
+	_, err = service.UpdateProfile(ctx, userID, time.Now(), &dto.UpdateProfileRequest{})
+	assert.ErrorIs(t, err, ErrImpersonating)
+	mockRepo.AssertExpectations(t)
+
+	service.AuditImpersonatedRequest(ctx, userID, "GET /me")
+	if assert.Len(t, sink.events, 1) {
+		assert.Equal(t, models.AuditImpersonatedRequest, sink.events[0].Type)
+		assert.Equal(t, "GET /me", sink.events[0].Identifier)
+		assert.NotNil(t, sink.events[0].ActorID)
+	}
+}
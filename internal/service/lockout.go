@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	"github.com/google/uuid"
+)
+
+// ErrAccountLocked returned when logins are blocked after failed attempts
+var ErrAccountLocked = errors.New("too many failed login attempts")
+
+// LockedError returned by Login while logins are blocked. It's returned
+// for unknown identifiers as well, so it doesn't reveal whether the account exists.
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return ErrAccountLocked.Error()
+}
+
+// Is makes errors.Is(err, ErrAccountLocked) work
+func (e *LockedError) Is(target error) bool {
+	return target == ErrAccountLocked
+}
+
+// LockoutPolicy configures the delays after failed logins. The first FreeAttempts
+// failures don't delay the next login, each next one blocks logins for BaseDelay
+// doubled per attempt, and MaxAttempts failures lock the account for LockoutDuration.
+type LockoutPolicy struct {
+	FreeAttempts    int
+	BaseDelay       time.Duration
+	MaxAttempts     int
+	LockoutDuration time.Duration
+
+	// ResetAfter is how long failed attempts are remembered after the last one
+	ResetAfter time.Duration
+}
+
+// DefaultLockoutPolicy returns the recommended lockout policy
+func DefaultLockoutPolicy() LockoutPolicy {
+	return LockoutPolicy{
+		FreeAttempts:    3,
+		BaseDelay:       time.Second,
+		MaxAttempts:     10,
+		LockoutDuration: 15 * time.Minute,
+		ResetAfter:      24 * time.Hour,
+	}
+}
+
+// delay returns how long logins are blocked after the number of failed attempts
+func (p LockoutPolicy) delay(attempts int) time.Duration {
+	if attempts >= p.MaxAttempts {
+		return p.LockoutDuration
+	}
+	if attempts <= p.FreeAttempts {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < attempts && delay < p.LockoutDuration; i++ {
+		delay *= 2
+	}
+	return min(delay, p.LockoutDuration)
+}
+
+// LockoutNotifier is called when the account is locked after MaxAttempts failures
+type LockoutNotifier func(ctx context.Context, user models.User, until time.Time)
+
+// EmailLockoutNotifier returns a LockoutNotifier which tells the user by email
+func EmailLockoutNotifier(m mailer.Mailer) LockoutNotifier {
+	return func(ctx context.Context, user models.User, until time.Time) {
+		msg := mailer.Message{
+			To:      user.Email,
+			Subject: "Your account has been locked",
+			Body: fmt.Sprintf("Your account has been locked after too many failed login attempts "+
+				"and will be unlocked at %s.\n\nIf it wasn't you, consider changing your password.",
+				until.UTC().Format(time.RFC1123)),
+		}
+		if err := m.Send(ctx, msg); err != nil {
+			log.Printf("send lockout notification to user %s: %v", user.ID, err)
+		}
+	}
+}
+
+// UnlockUser removes the lockout and the failed login attempts of the user
+func (s *Service) UnlockUser(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.repo.GetUserByID(ctx, s.tenantID(ctx), userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.repo.ResetLoginFailures(ctx, loginFailureKey(s.tenantID(ctx), user, "")); err != nil {
+		return fmt.Errorf("reset login failures: %w", err)
+	}
+	s.audit(ctx, models.AuditEvent{Type: models.AuditAccountUnlocked, UserID: &user.ID})
+	return nil
+}
+
+// loginFailureKey returns the key failed logins are counted by: the user
+// if it exists, otherwise the identifier in the tenant, so unknown accounts get locked too
+func loginFailureKey(tenantID string, user *models.User, identifier string) string {
+	if user != nil {
+		return "user:" + user.ID.String()
+	}
+
+	identifier = strings.ToLower(strings.TrimSpace(identifier))
+	if tenantID != models.DefaultTenantID {
+		identifier = tenantID + ":" + identifier
+	}
+	return "identifier:" + crypto.HashToken(identifier)
+}
+
+// checkLoginLock returns the failed attempts for the key
+// and LockedError if logins are blocked
+func (s *Service) checkLoginLock(ctx context.Context, key string) (*models.LoginFailure, error) {
+	if s.lockoutPolicy == nil {
+		return nil, nil
+	}
+
+	failure, err := s.repo.GetLoginFailure(ctx, key)
+	if err != nil {
+		return nil, nil
+	}
+	if failure.LockedUntil != nil && time.Now().Before(*failure.LockedUntil) {
+		return failure, &LockedError{Until: *failure.LockedUntil}
+	}
+	return failure, nil
+}
+
+// recordLoginFailure counts the failed attempt and blocks logins for the key
+// according to the policy. The user is nil for unknown identifiers.
+func (s *Service) recordLoginFailure(ctx context.Context, key string, user *models.User) {
+	if s.lockoutPolicy == nil {
+		return
+	}
+	policy := *s.lockoutPolicy
+
+	attempts, err := s.repo.IncrementLoginFailures(ctx, key, time.Now().Add(-policy.ResetAfter))
+	if err != nil {
+		log.Printf("record login failure: %v", err)
+		return
+	}
+
+	delay := policy.delay(attempts)
+	if delay == 0 {
+		return
+	}
+
+	until := time.Now().Add(delay)
+	if err := s.repo.LockLogin(ctx, key, until); err != nil {
+		log.Printf("lock login: %v", err)
+		return
+	}
+
+	if attempts != policy.MaxAttempts || user == nil {
+		return
+	}
+	s.audit(ctx, models.AuditEvent{Type: models.AuditAccountLocked, UserID: &user.ID})
+
+	// notify asynchronously, so the response time doesn't reveal the account exists
+	if s.lockoutNotifier != nil {
+		go s.lockoutNotifier(context.WithoutCancel(ctx), *user, until)
+	}
+}
+
+// resetLoginFailures forgets the failed attempts after a successful login
+func (s *Service) resetLoginFailures(ctx context.Context, key string, failure *models.LoginFailure) {
+	if failure == nil {
+		return
+	}
+	if err := s.repo.ResetLoginFailures(ctx, key); err != nil {
+		log.Printf("reset login failures: %v", err)
+	}
+}
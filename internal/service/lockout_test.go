@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+func TestLockoutPolicyDelay(t *testing.T) {
+	policy := DefaultLockoutPolicy()
+
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 1, expected: 0},
+		{attempts: 3, expected: 0},
+		{attempts: 4, expected: time.Second},
+		{attempts: 5, expected: 2 * time.Second},
+		{attempts: 9, expected: 32 * time.Second},
+		{attempts: 10, expected: 15 * time.Minute},
+		{attempts: 50, expected: 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, policy.delay(tt.attempts), "attempts: %d", tt.attempts)
+	}
+}
+
+func TestServiceLoginLockout(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("password123")
+	user := &models.User{
+		ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: hashedPassword,
+	}
+	userKey := "user:" + user.ID.String()
+
+	t.Run("locked account", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		until := time.Now().Add(time.Minute)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+		mockRepo.On("GetLoginFailure", mock.Anything, userKey).
+			Return(&models.LoginFailure{Key: userKey, Attempts: 10, LockedUntil: &until}, nil)
+		service := New(mockRepo, "secret", time.Hour, WithLockoutPolicy(DefaultLockoutPolicy()))
+
+		_, err := service.Login(context.Background(), &dto.LoginRequest{Email: user.Email, Password: "password123"})
+
+		assert.ErrorIs(t, err, ErrAccountLocked)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("username shares the lockout with the email", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		until := time.Now().Add(time.Minute)
+		mockRepo.On("GetUserByUsername", mock.Anything, "", user.Username).Return(user, nil)
+		mockRepo.On("GetLoginFailure", mock.Anything, userKey).
+			Return(&models.LoginFailure{Key: userKey, Attempts: 10, LockedUntil: &until}, nil)
+		service := New(mockRepo, "secret", time.Hour, WithLockoutPolicy(DefaultLockoutPolicy()))
+
+		_, err := service.Login(context.Background(), &dto.LoginRequest{Identifier: user.Username, Password: "password123"})
+
+		assert.ErrorIs(t, err, ErrAccountLocked)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("unknown identifier is locked the same way", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		key := loginFailureKey(models.DefaultTenantID, nil, "nobody@example.com")
+		until := time.Now().Add(time.Minute)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", "nobody@example.com").Return(nil, errUserNotFound)
+		mockRepo.On("GetLoginFailure", mock.Anything, key).
+			Return(&models.LoginFailure{Key: key, Attempts: 10, LockedUntil: &until}, nil)
+		service := New(mockRepo, "secret", time.Hour, WithLockoutPolicy(DefaultLockoutPolicy()))
+
+		_, err := service.Login(context.Background(), &dto.LoginRequest{Email: "nobody@example.com", Password: "password123"})
+
+		assert.ErrorIs(t, err, ErrAccountLocked)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("last failed attempt locks the account and notifies", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+		mockRepo.On("GetLoginFailure", mock.Anything, userKey).
+			Return(&models.LoginFailure{Key: userKey, Attempts: 9}, nil)
+		mockRepo.On("IncrementLoginFailures", mock.Anything, userKey, mock.AnythingOfType("time.Time")).Return(10, nil)
+		mockRepo.On("LockLogin", mock.Anything, userKey, mock.AnythingOfType("time.Time")).Return(nil)
+
+		notified := make(chan models.User, 1)
+		service := New(mockRepo, "secret", time.Hour,
+			WithLockoutPolicy(DefaultLockoutPolicy()),
+			WithLockoutNotifier(func(ctx context.Context, user models.User, until time.Time) {
+				notified <- user
+			}),
+		)
+
+		_, err := service.Login(context.Background(), &dto.LoginRequest{Email: user.Email, Password: "wrongpassword"})
+
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		select {
+		case u := <-notified:
+			assert.Equal(t, user.ID, u.ID)
+		case <-time.After(time.Second):
+			t.Fatal("lockout notifier wasn't called")
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("free attempt doesn't lock", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+		mockRepo.On("GetLoginFailure", mock.Anything, userKey).Return(nil, errUserNotFound)
+		mockRepo.On("IncrementLoginFailures", mock.Anything, userKey, mock.AnythingOfType("time.Time")).Return(1, nil)
+		service := New(mockRepo, "secret", time.Hour, WithLockoutPolicy(DefaultLockoutPolicy()))
+
+		_, err := service.Login(context.Background(), &dto.LoginRequest{Email: user.Email, Password: "wrongpassword"})
+
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		mockRepo.AssertNotCalled(t, "LockLogin", mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("successful login resets failures", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+		mockRepo.On("GetLoginFailure", mock.Anything, userKey).
+			Return(&models.LoginFailure{Key: userKey, Attempts: 2}, nil)
+		mockRepo.On("ResetLoginFailures", mock.Anything, userKey).Return(nil)
+		mockRepo.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{}, nil)
+		mockRepo.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{}, nil)
+		service := New(mockRepo, "secret", time.Hour, WithLockoutPolicy(DefaultLockoutPolicy()))
+
+		resp, err := service.Login(context.Background(), &dto.LoginRequest{Email: user.Email, Password: "password123"})
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, resp.Token)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestServiceUnlockUser(t *testing.T) {
+	user := &models.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001")}
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+		mockRepo.On("ResetLoginFailures", mock.Anything, "user:"+user.ID.String()).Return(nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		assert.NoError(t, service.UnlockUser(context.Background(), user.ID))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(nil, errUserNotFound)
+		service := New(mockRepo, "secret", time.Hour)
+
+		assert.ErrorIs(t, service.UnlockUser(context.Background(), user.ID), ErrUserNotFound)
+		mockRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/google/uuid"
+)
+
+const (
+	magicLinkPurpose       = "magic_link"
+	defaultMagicLinkExpiry = 15 * time.Minute
+)
+
+var (
+	// ErrInvalidMagicLink returned when the magic link is invalid, expired,
+	// already used or opened in another browser
+	ErrInvalidMagicLink = errors.New("invalid magic link")
+
+	// ErrMailerNotConfigured returned when an email must be sent but no mailer is set
+	ErrMailerNotConfigured = errors.New("mailer not configured")
+)
+
+// MagicLinkExpiry returns the lifetime of magic login links
+func (s *Service) MagicLinkExpiry() time.Duration {
+	if s.magicLinkExpiry == 0 {
+		return defaultMagicLinkExpiry
+	}
+	return s.magicLinkExpiry
+}
+
+// RequestMagicLink sends a single-use login link to the user's email.
+// It returns a nonce which must be stored in the requesting browser
+// and presented together with the link. The result does not depend
+// on whether the user exists: the link is sent in the background.
+func (s *Service) RequestMagicLink(ctx context.Context, req *dto.MagicLinkRequest) (string, error) {
+	if s.mailer == nil {
+		return "", ErrMailerNotConfigured
+	}
+
+	nonce, err := crypto.GenerateRandomString(32)
+	if err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, s.tenantID(ctx), normalizeEmail(req.Email))
+	if err != nil {
+		return nonce, nil
+	}
+
+	// the link is sent in the background, so the response time doesn't reveal
+	// whether the email is registered
+	go func() {
+		if err := s.sendMagicLink(context.WithoutCancel(ctx), user, nonce); err != nil {
+			log.Printf("send magic link to user %s: %v", user.ID, err)
+		}
+	}()
+	return nonce, nil
+}
+
+// sendMagicLink creates the magic link of the user bound to the nonce and sends it
+func (s *Service) sendMagicLink(ctx context.Context, user *models.User, nonce string) error {
+	link := models.MagicLink{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(s.MagicLinkExpiry()),
+	}
+	if err := s.repo.CreateMagicLink(ctx, link); err != nil {
+		return fmt.Errorf("create magic link: %w", err)
+	}
+
+	linkToken, err := token.GenerateLinkToken(magicLinkPurpose, map[string]any{
+		"sub":   user.ID.String(),
+		"jti":   link.ID.String(),
+		"email": user.Email,
+		"nonce": crypto.HashToken(nonce),
+	}, s.SigningKey(ctx), s.MagicLinkExpiry())
+	if err != nil {
+		return fmt.Errorf("generate link token: %w", err)
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Your login link",
+		Body: fmt.Sprintf("Follow the link to log in:\n\n%s/login/magic-link/verify?token=%s\n\n"+
+			"The link expires in %s and works only in the browser where it was requested.",
+			s.publicURL(ctx), url.QueryEscape(linkToken), s.MagicLinkExpiry()),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("send magic link: %w", err)
+	}
+	return nil
+}
+
+// VerifyMagicLink consumes the magic link and performs user authentication
+func (s *Service) VerifyMagicLink(ctx context.Context, linkToken, nonce string) (*dto.Response, error) {
+	user, err := s.magicLinkUser(ctx, linkToken, nonce)
+	if err != nil {
+		if errors.Is(err, ErrInvalidMagicLink) {
+			s.auditLoginFailed(ctx, nil, "", token.AMREmail, models.AuditReasonInvalidMagicLink)
+		}
+		return nil, err
+	}
+
+	return s.completeLogin(ctx, user, []string{token.AMREmail}, uuid.Nil)
+}
+
+// magicLinkUser consumes the link and returns the user it was sent to
+func (s *Service) magicLinkUser(ctx context.Context, linkToken, nonce string) (*models.User, error) {
+	claims, err := token.ValidateLinkToken(linkToken, magicLinkPurpose, s.SigningKey(ctx))
+	if err != nil {
+		return nil, ErrInvalidMagicLink
+	}
+
+	nonceHash, _ := claims["nonce"].(string)
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(crypto.HashToken(nonce)), []byte(nonceHash)) != 1 {
+		return nil, ErrInvalidMagicLink
+	}
+
+	jti, _ := claims["jti"].(string)
+	id, err := uuid.Parse(jti)
+	if err != nil {
+		return nil, ErrInvalidMagicLink
+	}
+
+	if err := s.repo.UseMagicLink(ctx, id); err != nil {
+		return nil, ErrInvalidMagicLink
+	}
+
+	email, _ := claims["email"].(string)
+	user, err := s.repo.GetUserByEmail(ctx, s.tenantID(ctx), email)
+	if err != nil || user.ID.String() != claims["sub"] {
+		return nil, ErrInvalidMagicLink
+	}
+
+	// following the link proves the ownership of the email
+	if !user.EmailVerified {
+		if err := s.repo.SetEmailVerified(ctx, user.ID); err != nil {
+			return nil, fmt.Errorf("set email verified: %w", err)
+		}
+		user.EmailVerified = true
+	}
+
+	return user, nil
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+// linkToken extracts the token from the link in the email body
+// sentMessage waits for the message sent in the background
+func sentMessage(t *testing.T, m *mailer.MemoryMailer) mailer.Message {
+	t.Helper()
+
+	assert.Eventually(t, func() bool {
+		_, ok := m.Last()
+		return ok
+	}, time.Second, 10*time.Millisecond)
+	msg, _ := m.Last()
+	return msg
+}
+
+func linkToken(t *testing.T, body string) string {
+	t.Helper()
+
+	i := strings.Index(body, "token=")
+	if !assert.NotEqual(t, -1, i) {
+		return ""
+	}
+	escaped := strings.Fields(body[i+len("token="):])[0]
+	token, err := url.QueryUnescape(escaped)
+	assert.NoError(t, err)
+	return token
+}
+
+func TestServiceMagicLink(t *testing.T) {
+	user := &models.User{
+		ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		Username: "testuser",
+		Email:    "test@example.com",
+	}
+
+	t.Run("mailer not configured", func(t *testing.T) {
+		service := New(new(mockrepo.MockRepository), "secret", time.Hour)
+
+		_, err := service.RequestMagicLink(context.Background(), &dto.MagicLinkRequest{Email: user.Email})
+		assert.ErrorIs(t, err, ErrMailerNotConfigured)
+	})
+
+	t.Run("unknown email", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", "notfound@example.com").
+			Return(nil, errUserNotFound)
+		m := mailer.NewMemoryMailer()
+		service := New(mockRepo, "secret", time.Hour, WithMailer(m))
+
+		nonce, err := service.RequestMagicLink(context.Background(), &dto.MagicLinkRequest{Email: "notfound@example.com"})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, nonce)
+		assert.Empty(t, m.Messages())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("successful login", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+		mockRepo.On("CreateMagicLink", mock.Anything, mock.AnythingOfType("models.MagicLink")).Return(nil).Once()
+		mockRepo.On("UseMagicLink", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(nil).Once()
+		mockRepo.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{}, nil).Once()
+		mockRepo.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{}, nil).Once()
+		mockRepo.On("SetEmailVerified", mock.Anything, user.ID).Return(nil).Once()
+		m := mailer.NewMemoryMailer()
+		service := New(mockRepo, "secret", time.Hour, WithMailer(m), WithBaseURL("https://auth.example.com"))
+
+		nonce, err := service.RequestMagicLink(context.Background(), &dto.MagicLinkRequest{Email: user.Email})
+		assert.NoError(t, err)
+
+		msg := sentMessage(t, m)
+		assert.Equal(t, user.Email, msg.To)
+		assert.Contains(t, msg.Body, "https://auth.example.com/login/magic-link/verify?token=")
+
+		resp, err := service.VerifyMagicLink(context.Background(), linkToken(t, msg.Body), nonce)
+		assert.NoError(t, err)
+		assert.Equal(t, user.ID, resp.User.ID)
+		assert.NotEmpty(t, resp.Token)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("another browser", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+		mockRepo.On("CreateMagicLink", mock.Anything, mock.AnythingOfType("models.MagicLink")).Return(nil).Once()
+		m := mailer.NewMemoryMailer()
+		service := New(mockRepo, "secret", time.Hour, WithMailer(m))
+
+		_, err := service.RequestMagicLink(context.Background(), &dto.MagicLinkRequest{Email: user.Email})
+		assert.NoError(t, err)
+		msg := sentMessage(t, m)
+
+		resp, err := service.VerifyMagicLink(context.Background(), linkToken(t, msg.Body), "other-nonce")
+		assert.ErrorIs(t, err, ErrInvalidMagicLink)
+		assert.Nil(t, resp)
+
+		resp, err = service.VerifyMagicLink(context.Background(), linkToken(t, msg.Body), "")
+		assert.ErrorIs(t, err, ErrInvalidMagicLink)
+		assert.Nil(t, resp)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("link already used", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+		mockRepo.On("CreateMagicLink", mock.Anything, mock.AnythingOfType("models.MagicLink")).Return(nil).Once()
+		mockRepo.On("UseMagicLink", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(errUserNotFound).Once()
+		m := mailer.NewMemoryMailer()
+		service := New(mockRepo, "secret", time.Hour, WithMailer(m))
+
+		nonce, err := service.RequestMagicLink(context.Background(), &dto.MagicLinkRequest{Email: user.Email})
+		assert.NoError(t, err)
+		msg := sentMessage(t, m)
+
+		resp, err := service.VerifyMagicLink(context.Background(), linkToken(t, msg.Body), nonce)
+		assert.ErrorIs(t, err, ErrInvalidMagicLink)
+		assert.Nil(t, resp)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		service := New(new(mockrepo.MockRepository), "secret", time.Hour)
+
+		resp, err := service.VerifyMagicLink(context.Background(), "invalid.token", "nonce")
+		assert.ErrorIs(t, err, ErrInvalidMagicLink)
+		assert.Nil(t, resp)
+	})
+}
@@ -0,0 +1,380 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/google/uuid"
+)
+
+const (
+	mfaChallengePurpose = "mfa_challenge"
+	mfaChallengeExpiry  = 5 * time.Minute
+
+	otpLength            = 6
+	otpExpiry            = 5 * time.Minute
+	maxOTPAttempts       = 5
+	defaultOTPSendLimit  = 5
+	defaultOTPSendWindow = 15 * time.Minute
+)
+
+var (
+	// ErrInvalidMFAChallenge returned when the login challenge is invalid or expired
+	ErrInvalidMFAChallenge = errors.New("invalid mfa challenge")
+
+	// ErrMFAMethodNotFound returned when the user has no such MFA method
+	ErrMFAMethodNotFound = errors.New("mfa method not found")
+
+	// ErrUnsupportedMFAMethod returned when no sender is configured for the MFA method type
+	ErrUnsupportedMFAMethod = errors.New("unsupported mfa method")
+
+	// ErrInvalidOTP returned when the one-time code is wrong, expired or already used
+	ErrInvalidOTP = errors.New("invalid one-time code")
+
+	// ErrTooManyOTPAttempts returned when the one-time code has been guessed too many times
+	ErrTooManyOTPAttempts = errors.New("too many one-time code attempts")
+
+	// ErrOTPRateLimited returned when too many one-time codes have been sent to the user
+	ErrOTPRateLimited = errors.New("too many one-time codes requested")
+)
+
+// challengeClaims the state of the login carried by the challenge token
+type challengeClaims struct {
+	userID uuid.UUID
+	amr    []string
+	stepUp bool
+	orgID  uuid.UUID
+}
+
+// MFARequiredError returned by Login when the user must pass a second factor
+type MFARequiredError struct {
+	Challenge dto.MFAChallenge
+}
+
+func (e *MFARequiredError) Error() string {
+	return "mfa required"
+}
+
+// MFAMethods returns the MFA methods of the user
+func (s *Service) MFAMethods(ctx context.Context, userID uuid.UUID) ([]models.MFAMethod, error) {
+	methods, err := s.repo.GetMFAMethods(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get mfa methods: %w", err)
+	}
+	return methods, nil
+}
+
+// AddMFAMethod enrolls a new MFA method and sends a code to confirm it
+func (s *Service) AddMFAMethod(ctx context.Context, userID uuid.UUID, req *dto.AddMFAMethodRequest) (models.MFAMethod, error) {
+	if err := forbidImpersonation(ctx); err != nil {
+		return models.MFAMethod{}, err
+	}
+
+	if _, ok := s.otpSenders[req.Type]; !ok {
+		return models.MFAMethod{}, ErrUnsupportedMFAMethod
+	}
+
+	destination := req.Destination
+	if destination == "" && req.Type == models.MFAMethodEmail {
+		user, err := s.repo.GetUserByID(ctx, s.tenantID(ctx), userID)
+		if err != nil {
+			return models.MFAMethod{}, fmt.Errorf("get user: %w", err)
+		}
+		destination = user.Email
+	}
+
+	method, err := s.repo.CreateMFAMethod(ctx, models.MFAMethod{
+		UserID:      userID,
+		Type:        req.Type,
+		Destination: destination,
+	})
+	if err != nil {
+		return models.MFAMethod{}, fmt.Errorf("create mfa method: %w", err)
+	}
+
+	if err := s.sendOTP(ctx, &method); err != nil {
+		return models.MFAMethod{}, err
+	}
+	return method, nil
+}
+
+// ConfirmMFAMethod confirms the MFA method with the code sent to it
+func (s *Service) ConfirmMFAMethod(ctx context.Context, userID, methodID uuid.UUID, code string) error {
+	if err := forbidImpersonation(ctx); err != nil {
+		return err
+	}
+
+	method, err := s.findMFAMethod(ctx, userID, methodID, false)
+	if err != nil {
+		return err
+	}
+
+	if err := s.verifyOTP(ctx, method, code); err != nil {
+		return err
+	}
+
+	if err := s.repo.ConfirmMFAMethod(ctx, method.ID); err != nil {
+		return fmt.Errorf("confirm mfa method: %w", err)
+	}
+	s.audit(ctx, models.AuditEvent{Type: models.AuditMFAMethodAdded, UserID: &userID, Method: method.Type})
+	return nil
+}
+
+// DeleteMFAMethod deletes the MFA method of the user
+func (s *Service) DeleteMFAMethod(ctx context.Context, userID, methodID uuid.UUID) error {
+	if err := forbidImpersonation(ctx); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteMFAMethod(ctx, userID, methodID); err != nil {
+		return ErrMFAMethodNotFound
+	}
+	s.audit(ctx, models.AuditEvent{Type: models.AuditMFAMethodRemoved, UserID: &userID})
+	return nil
+}
+
+// SendLoginOTP sends a one-time code to the MFA method to pass the login challenge
+func (s *Service) SendLoginOTP(ctx context.Context, req *dto.MFASendRequest) error {
+	challenge, err := s.parseMFAChallenge(ctx, req.ChallengeToken)
+	if err != nil {
+		return err
+	}
+
+	method, err := s.findMFAMethod(ctx, challenge.userID, req.MethodID, true)
+	if err != nil {
+		return err
+	}
+
+	return s.sendOTP(ctx, method)
+}
+
+// VerifyLoginOTP passes the login challenge with the one-time code.
+// For a step-up challenge the issued token is an elevated one, see StepUp.
+func (s *Service) VerifyLoginOTP(ctx context.Context, req *dto.MFAVerifyRequest) (*dto.Response, error) {
+	challenge, err := s.parseMFAChallenge(ctx, req.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	method, err := s.findMFAMethod(ctx, challenge.userID, req.MethodID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.verifyOTP(ctx, method, req.Code); err != nil {
+		s.audit(ctx, models.AuditEvent{
+			Type:   models.AuditLoginFailed,
+			UserID: &challenge.userID,
+			Method: method.Type,
+			Reason: models.AuditReasonInvalidOTP,
+		})
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, s.tenantID(ctx), challenge.userID)
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	// the membership may have been removed while the user was entering the code
+	membership, err := s.orgMembership(ctx, challenge.orgID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	amr := slices.Concat(challenge.amr, methodAMR(method.Type), []string{token.AMRMFA})
+	expiry, eventType := s.accessTokenExpiry(ctx), models.AuditLoginSucceeded
+	if challenge.stepUp {
+		expiry, eventType = s.StepUpExpiry(), models.AuditStepUp
+	}
+
+	resp, err := s.newResponse(ctx, user, amr, expiry, membership)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, models.AuditEvent{Type: eventType, UserID: &user.ID, Method: strings.Join(amr, ",")})
+	return resp, nil
+}
+
+// mfaChallenge returns the login challenge if the user has confirmed MFA methods.
+// The amr are the methods the user has already passed, the orgID is the organization
+// the token will be scoped to.
+func (s *Service) mfaChallenge(ctx context.Context, user *models.User, amr []string,
+	stepUp bool, orgID uuid.UUID) (*dto.MFAChallenge, error) {
+	methods, err := s.repo.GetMFAMethods(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get mfa methods: %w", err)
+	}
+
+	challenge := dto.MFAChallenge{MFARequired: true}
+	for _, m := range methods {
+		if m.Confirmed {
+			challenge.Methods = append(challenge.Methods, dto.MFAMethod{
+				ID:          m.ID,
+				Type:        m.Type,
+				Destination: maskDestination(m.Destination),
+			})
+		}
+	}
+	if len(challenge.Methods) == 0 {
+		return nil, nil
+	}
+
+	claims := map[string]any{
+		"sub":     user.ID.String(),
+		"amr":     amr,
+		"step_up": stepUp,
+	}
+	if orgID != uuid.Nil {
+		claims["org_id"] = orgID.String()
+	}
+
+	challenge.ChallengeToken, err = token.GenerateLinkToken(mfaChallengePurpose, claims, s.SigningKey(ctx), mfaChallengeExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("generate challenge token: %w", err)
+	}
+	return &challenge, nil
+}
+
+func (s *Service) parseMFAChallenge(ctx context.Context, challengeToken string) (*challengeClaims, error) {
+	claims, err := token.ValidateLinkToken(challengeToken, mfaChallengePurpose, s.SigningKey(ctx))
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	sub, _ := claims["sub"].(string)
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	var orgID uuid.UUID
+	if org, ok := claims["org_id"].(string); ok {
+		if orgID, err = uuid.Parse(org); err != nil {
+			return nil, ErrInvalidMFAChallenge
+		}
+	}
+
+	stepUp, _ := claims["step_up"].(bool)
+	return &challengeClaims{
+		userID: userID,
+		amr:    token.StringsClaim(claims, "amr"),
+		stepUp: stepUp,
+		orgID:  orgID,
+	}, nil
+}
+
+// methodAMR returns the amr values of the MFA method type
+func methodAMR(methodType string) []string {
+	if methodType == models.MFAMethodSMS {
+		return []string{token.AMROTP, token.AMRSMS}
+	}
+	return []string{token.AMROTP}
+}
+
+func (s *Service) findMFAMethod(ctx context.Context, userID, methodID uuid.UUID, confirmed bool) (*models.MFAMethod, error) {
+	methods, err := s.repo.GetMFAMethods(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get mfa methods: %w", err)
+	}
+
+	for _, m := range methods {
+		if m.ID == methodID && (m.Confirmed || !confirmed) {
+			return &m, nil
+		}
+	}
+	return nil, ErrMFAMethodNotFound
+}
+
+// sendOTP generates a one-time code, stores its hash and sends it to the MFA method
+func (s *Service) sendOTP(ctx context.Context, method *models.MFAMethod) error {
+	otpSender, ok := s.otpSenders[method.Type]
+	if !ok {
+		return ErrUnsupportedMFAMethod
+	}
+
+	limit, window := s.otpSendLimit, s.otpSendWindow
+	if limit == 0 {
+		limit, window = defaultOTPSendLimit, defaultOTPSendWindow
+	}
+	sent, err := s.repo.CountOTPCodesSince(ctx, method.UserID, time.Now().Add(-window))
+	if err != nil {
+		return fmt.Errorf("count otp codes: %w", err)
+	}
+	if sent >= limit {
+		return ErrOTPRateLimited
+	}
+
+	code, err := crypto.GenerateNumericCode(otpLength)
+	if err != nil {
+		return fmt.Errorf("generate code: %w", err)
+	}
+
+	codeHash, err := crypto.HashPassword(code)
+	if err != nil {
+		return fmt.Errorf("hash code: %w", err)
+	}
+
+	otp := models.OTPCode{
+		ID:        uuid.New(),
+		UserID:    method.UserID,
+		MethodID:  method.ID,
+		CodeHash:  codeHash,
+		ExpiresAt: time.Now().Add(otpExpiry),
+	}
+	if err := s.repo.CreateOTPCode(ctx, otp); err != nil {
+		return fmt.Errorf("create otp code: %w", err)
+	}
+
+	message := fmt.Sprintf("Your verification code is %s. It expires in %s.", code, otpExpiry)
+	if err := otpSender.Send(ctx, method.Destination, message); err != nil {
+		return fmt.Errorf("send code: %w", err)
+	}
+	return nil
+}
+
+// verifyOTP checks the code against the active one-time code of the MFA method
+func (s *Service) verifyOTP(ctx context.Context, method *models.MFAMethod, code string) error {
+	otp, err := s.repo.GetActiveOTPCode(ctx, method.ID)
+	if err != nil {
+		return ErrInvalidOTP
+	}
+
+	// the attempt is counted before the check, the code is
+	// exhausted when no attempts are left to count
+	err = s.repo.IncrementOTPAttempts(ctx, otp.ID, maxOTPAttempts)
+	if errors.Is(err, models.ErrNotFound) {
+		return ErrTooManyOTPAttempts
+	}
+	if err != nil {
+		return fmt.Errorf("increment otp attempts: %w", err)
+	}
+
+	if err := crypto.CheckPassword(code, otp.CodeHash); err != nil {
+		return ErrInvalidOTP
+	}
+
+	if err := s.repo.UseOTPCode(ctx, otp.ID); err != nil {
+		return ErrInvalidOTP
+	}
+	return nil
+}
+
+// maskDestination hides most of the email address or phone number
+func maskDestination(destination string) string {
+	if i := strings.Index(destination, "@"); i > 0 {
+		return destination[:1] + "***" + destination[i:]
+	}
+	if len(destination) > 4 {
+		return "***" + destination[len(destination)-4:]
+	}
+	return "***"
+}
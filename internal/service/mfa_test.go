@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/sender"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+func TestServiceLoginMFARequired(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("password123")
+	user := &models.User{
+		ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		Email:    "test@example.com",
+		Password: hashedPassword,
+	}
+	method := models.MFAMethod{
+		ID:          uuid.New(),
+		UserID:      user.ID,
+		Type:        models.MFAMethodSMS,
+		Destination: "+10000001234",
+		Confirmed:   true,
+	}
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+	mockRepo.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{
+		method,
+		{ID: uuid.New(), UserID: user.ID, Type: models.MFAMethodEmail, Destination: user.Email},
+	}, nil)
+
+	service := New(mockRepo, "secret", time.Hour)
+	resp, err := service.Login(context.Background(), &dto.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.Nil(t, resp)
+
+	var mfaErr *MFARequiredError
+	if assert.True(t, errors.As(err, &mfaErr)) {
+		assert.True(t, mfaErr.Challenge.MFARequired)
+		assert.NotEmpty(t, mfaErr.Challenge.ChallengeToken)
+		assert.Equal(t, []dto.MFAMethod{
+			{ID: method.ID, Type: models.MFAMethodSMS, Destination: "***1234"},
+		}, mfaErr.Challenge.Methods)
+
+		challenge, err := service.parseMFAChallenge(context.Background(), mfaErr.Challenge.ChallengeToken)
+		assert.NoError(t, err)
+		assert.Equal(t, user.ID, challenge.userID)
+		assert.Equal(t, []string{token.AMRPassword}, challenge.amr)
+		assert.False(t, challenge.stepUp)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestServiceAddMFAMethod(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+	t.Run("unsupported method", func(t *testing.T) {
+		service := New(new(mockrepo.MockRepository), "secret", time.Hour)
+
+		_, err := service.AddMFAMethod(context.Background(), userID, &dto.AddMFAMethodRequest{Type: models.MFAMethodSMS})
+		assert.ErrorIs(t, err, ErrUnsupportedMFAMethod)
+	})
+
+	t.Run("email method", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).
+			Return(&models.User{ID: userID, Email: "test@example.com"}, nil)
+		mockRepo.On("CreateMFAMethod", mock.Anything, mock.AnythingOfType("*models.MFAMethod")).
+			Return(nil).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(*models.MFAMethod).ID = uuid.New()
+			})
+		mockRepo.On("CountOTPCodesSince", mock.Anything, userID, mock.AnythingOfType("time.Time")).Return(0, nil)
+		mockRepo.On("CreateOTPCode", mock.Anything, mock.AnythingOfType("models.OTPCode")).Return(nil)
+
+		s := sender.NewMemorySender()
+		service := New(mockRepo, "secret", time.Hour, WithOTPSender(models.MFAMethodEmail, s))
+
+		method, err := service.AddMFAMethod(context.Background(), userID, &dto.AddMFAMethodRequest{Type: models.MFAMethodEmail})
+		assert.NoError(t, err)
+		assert.Equal(t, "test@example.com", method.Destination)
+		assert.False(t, method.Confirmed)
+
+		msg, ok := s.Last()
+		assert.True(t, ok)
+		assert.Equal(t, "test@example.com", msg.To)
+		assert.Regexp(t, `code is [0-9]{6}\.`, msg.Message)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("CreateMFAMethod", mock.Anything, mock.AnythingOfType("*models.MFAMethod")).Return(nil)
+		mockRepo.On("CountOTPCodesSince", mock.Anything, userID, mock.AnythingOfType("time.Time")).Return(3, nil)
+
+		s := sender.NewMemorySender()
+		service := New(mockRepo, "secret", time.Hour,
+			WithOTPSender(models.MFAMethodSMS, s),
+			WithOTPSendLimit(3, time.Hour),
+		)
+
+		_, err := service.AddMFAMethod(context.Background(), userID, &dto.AddMFAMethodRequest{
+			Type:        models.MFAMethodSMS,
+			Destination: "+10000000000",
+		})
+		assert.ErrorIs(t, err, ErrOTPRateLimited)
+		assert.Empty(t, s.Messages())
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestServiceVerifyLoginOTP(t *testing.T) {
+	user := &models.User{
+		ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		Username: "testuser",
+	}
+	method := models.MFAMethod{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Type:      models.MFAMethodSMS,
+		Confirmed: true,
+	}
+	codeHash, _ := crypto.HashPassword("123456")
+
+	challengeFor := func(service *Service) string {
+		challenge, _ := service.mfaChallenge(context.Background(), user, []string{token.AMRPassword}, false, uuid.Nil)
+		return challenge.ChallengeToken
+	}
+
+	tests := []struct {
+		name        string
+		code        string
+		otp         *models.OTPCode
+		mockSetup   func(*mockrepo.MockRepository, *models.OTPCode)
+		expectedErr error
+	}{
+		{
+			name: "successful verification",
+			code: "123456",
+			otp:  &models.OTPCode{ID: uuid.New(), CodeHash: codeHash},
+			mockSetup: func(mr *mockrepo.MockRepository, otp *models.OTPCode) {
+				mr.On("IncrementOTPAttempts", mock.Anything, otp.ID, maxOTPAttempts).Return(nil)
+				mr.On("UseOTPCode", mock.Anything, otp.ID).Return(nil)
+				mr.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+				mr.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{}, nil)
+			},
+		},
+		{
+			name: "wrong code",
+			code: "654321",
+			otp:  &models.OTPCode{ID: uuid.New(), CodeHash: codeHash},
+			mockSetup: func(mr *mockrepo.MockRepository, otp *models.OTPCode) {
+				mr.On("IncrementOTPAttempts", mock.Anything, otp.ID, maxOTPAttempts).Return(nil)
+			},
+			expectedErr: ErrInvalidOTP,
+		},
+		{
+			name: "too many attempts",
+			code: "123456",
+			otp:  &models.OTPCode{ID: uuid.New(), CodeHash: codeHash, Attempts: maxOTPAttempts},
+			mockSetup: func(mr *mockrepo.MockRepository, otp *models.OTPCode) {
+				mr.On("IncrementOTPAttempts", mock.Anything, otp.ID, maxOTPAttempts).Return(models.ErrNotFound)
+			},
+			expectedErr: ErrTooManyOTPAttempts,
+		},
+		{
+			name:        "no active code",
+			code:        "123456",
+			mockSetup:   func(mr *mockrepo.MockRepository, otp *models.OTPCode) {},
+			expectedErr: ErrInvalidOTP,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			mockRepo.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{method}, nil)
+			if tt.otp != nil {
+				mockRepo.On("GetActiveOTPCode", mock.Anything, method.ID).Return(tt.otp, nil)
+			} else {
+				mockRepo.On("GetActiveOTPCode", mock.Anything, method.ID).Return(nil, errUserNotFound)
+			}
+			tt.mockSetup(mockRepo, tt.otp)
+
+			service := New(mockRepo, "secret", time.Hour)
+			resp, err := service.VerifyLoginOTP(context.Background(), &dto.MFAVerifyRequest{
+				ChallengeToken: challengeFor(service),
+				MethodID:       method.ID,
+				Code:           tt.code,
+			})
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, user.ID, resp.User.ID)
+				assert.NotEmpty(t, resp.Token)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+
+	t.Run("invalid challenge", func(t *testing.T) {
+		service := New(new(mockrepo.MockRepository), "secret", time.Hour)
+
+		resp, err := service.VerifyLoginOTP(context.Background(), &dto.MFAVerifyRequest{
+			ChallengeToken: "invalid",
+			MethodID:       method.ID,
+			Code:           "123456",
+		})
+		assert.ErrorIs(t, err, ErrInvalidMFAChallenge)
+		assert.Nil(t, resp)
+	})
+}
+
+func TestMaskDestination(t *testing.T) {
+	assert.Equal(t, "a***@example.com", maskDestination("alex@example.com"))
+	assert.Equal(t, "***1234", maskDestination("+10000001234"))
+	assert.Equal(t, "***", maskDestination("123"))
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/google/uuid"
+)
+
+const (
+	oidcStatePurpose = "oidc_state"
+
+	// OIDCLoginExpiry the time the user has to sign in with the provider
+	OIDCLoginExpiry = 10 * time.Minute
+)
+
+var (
+	// ErrProviderNotFound returned when no identity provider with the name is configured
+	ErrProviderNotFound = errors.New("identity provider not found")
+
+	// ErrInvalidOIDCLogin returned when the callback doesn't belong to a login started
+	// in the browser, or the provider's code or ID token is invalid
+	ErrInvalidOIDCLogin = errors.New("invalid or expired login")
+
+	// ErrExternalEmailNotVerified returned when the provider doesn't vouch for the user's email,
+	// so it can't be linked to a local user
+	ErrExternalEmailNotVerified = errors.New("email not verified by the identity provider")
+)
+
+// OIDCProviders returns the names of the configured upstream providers
+func (s *Service) OIDCProviders() []string {
+	names := make([]string, 0, len(s.oidcProviders))
+	for name := range s.oidcProviders {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// oidcRedirectURL returns the callback URL of the provider
+func (s *Service) oidcRedirectURL(ctx context.Context, provider string) string {
+	return s.publicURL(ctx) + "/login/oidc/" + provider + "/callback"
+}
+
+// StartOIDCLogin returns the URL of the provider's login page and a nonce
+// which must be stored in the browser and presented with the callback
+func (s *Service) StartOIDCLogin(ctx context.Context, name string) (authURL, nonce string, err error) {
+	return s.startOIDCLogin(ctx, name, nil)
+}
+
+// startOIDCLogin starts the login with the provider, the state carries the claims
+func (s *Service) startOIDCLogin(ctx context.Context, name string, claims map[string]any) (authURL, nonce string, err error) {
+	provider, ok := s.oidcProviders[name]
+	if !ok {
+		return "", "", ErrProviderNotFound
+	}
+
+	nonce, err = crypto.GenerateRandomString(32)
+	if err != nil {
+		return "", "", fmt.Errorf("generate nonce: %w", err)
+	}
+
+	stateClaims := map[string]any{
+		"provider": name,
+		"nonce":    crypto.HashToken(nonce),
+	}
+	maps.Copy(stateClaims, claims)
+	state, err := token.GenerateLinkToken(oidcStatePurpose, stateClaims, s.SigningKey(ctx), OIDCLoginExpiry)
+	if err != nil {
+		return "", "", fmt.Errorf("generate state: %w", err)
+	}
+
+	authURL, err = provider.AuthCodeURL(ctx, s.oidcRedirectURL(ctx, name), state, crypto.HashToken(nonce))
+	if err != nil {
+		return "", "", fmt.Errorf("build authorization url: %w", err)
+	}
+	return authURL, nonce, nil
+}
+
+// CompleteOIDCLogin validates the provider's callback and performs user authentication.
+// The identity is linked to the user who started linking it, otherwise an identity seen
+// for the first time is linked to the local user with the same verified email or a new one.
+func (s *Service) CompleteOIDCLogin(ctx context.Context, name, state, code, nonce string) (*dto.Response, error) {
+	provider, ok := s.oidcProviders[name]
+	if !ok {
+		return nil, ErrProviderNotFound
+	}
+
+	claims, err := token.ValidateLinkToken(state, oidcStatePurpose, s.SigningKey(ctx))
+	if err != nil || claims["provider"] != name {
+		return nil, ErrInvalidOIDCLogin
+	}
+	nonceHash, _ := claims["nonce"].(string)
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(crypto.HashToken(nonce)), []byte(nonceHash)) != 1 {
+		return nil, ErrInvalidOIDCLogin
+	}
+
+	method := "oidc:" + name
+	idClaims, err := provider.Exchange(ctx, s.oidcRedirectURL(ctx, name), code, nonceHash)
+	if err != nil {
+		s.auditLoginFailed(ctx, nil, "", method, models.AuditReasonInvalidExternal)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOIDCLogin, err)
+	}
+
+	identity := ExternalIdentity{
+		Provider:    method,
+		Subject:     idClaims.Subject,
+		Email:       idClaims.Email,
+		Username:    idClaims.PreferredUsername,
+		DisplayName: idClaims.Name,
+	}
+
+	var user *models.User
+	if linkUserID, ok := claims["link"].(string); ok {
+		user, err = s.linkExternalUser(ctx, linkUserID, identity)
+	} else {
+		user, err = s.externalUser(ctx, identity, idClaims.EmailVerified)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(ctx, user, []string{token.AMRFederated}, uuid.Nil)
+}
+
+// externalUser returns the local user the external identity is linked to. An identity
+// seen for the first time is linked to the user with the same email, or to the user created
+// for it. The provider must vouch for the email: an email attribute any user of the provider
+// can set would claim the local account. The local user must have verified the email too,
+// or whoever registered it first would keep a password to the account the identity logs in to.
+func (s *Service) externalUser(ctx context.Context, identity ExternalIdentity, emailVerified bool) (*models.User, error) {
+	linked, err := s.repo.GetUserIdentity(ctx, s.tenantID(ctx), identity.Provider, identity.Subject)
+	if err == nil {
+		return s.User(ctx, linked.UserID)
+	}
+	if !errors.Is(err, models.ErrNotFound) {
+		return nil, fmt.Errorf("get user identity: %w", err)
+	}
+
+	if identity.Email == "" || !emailVerified {
+		return nil, ErrExternalEmailNotVerified
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, s.tenantID(ctx), normalizeEmail(identity.Email))
+	switch {
+	case err == nil:
+		if !user.EmailVerified {
+			return nil, ErrIdentityExists
+		}
+	case errors.Is(err, models.ErrNotFound):
+		user, err = s.provisionUser(ctx, identity)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("get user by email: %w", err)
+	}
+
+	if err := s.linkIdentity(ctx, user, identity); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/oidc"
+	"github.com/AlexFox86/auth-service/internal/pkg/oidc/oidctest"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+// oidcLogin starts the login with the stub provider, signs the user in there
+// and returns the state and code of the callback
+func oidcLogin(t *testing.T, service *Service, stub *oidctest.Server, claims map[string]any) (state, code, nonce string) {
+	t.Helper()
+
+	authURL, nonce, err := service.StartOIDCLogin(context.Background(), "stub")
+	assert.NoError(t, err)
+	code, err = stub.Authorize(authURL, claims)
+	assert.NoError(t, err)
+
+	u, _ := url.Parse(authURL)
+	assert.Equal(t, "https://auth.example.com/login/oidc/stub/callback", u.Query().Get("redirect_uri"))
+	return u.Query().Get("state"), code, nonce
+}
+
+func TestServiceOIDCLogin(t *testing.T) {
+	stub, err := oidctest.NewServer("client", "secret")
+	assert.NoError(t, err)
+	defer stub.Close()
+	provider := oidc.NewProvider(stub.Config("stub"), nil)
+
+	user := &models.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), Username: "alex", Email: "alex@example.com",
+		EmailVerified: true}
+	verified := map[string]any{"sub": "12345", "email": "Alex@example.com", "email_verified": true, "name": "Alex"}
+
+	t.Run("linked identity", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserIdentity", mock.Anything, "", "oidc:stub", "12345").Return(&models.UserIdentity{UserID: user.ID}, nil)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+		mockRepo.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{}, nil)
+		mockRepo.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{}, nil)
+		service := New(mockRepo, "secret", time.Hour, WithBaseURL("https://auth.example.com"), WithOIDCProviders(provider))
+
+		// the provider's email doesn't need to be verified or match once the identity is linked
+		state, code, nonce := oidcLogin(t, service, stub, map[string]any{"sub": "12345", "email": "other@example.com"})
+		resp, err := service.CompleteOIDCLogin(context.Background(), "stub", state, code, nonce)
+		if assert.NoError(t, err) {
+			claims, err := token.ValidateToken(resp.Token, service.JwtSecret())
+			assert.NoError(t, err)
+			assert.Equal(t, user.ID.String(), claims["sub"])
+		}
+		mockRepo.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertNotCalled(t, "CreateUserIdentity", mock.Anything, mock.Anything)
+	})
+
+	t.Run("existing user", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserIdentity", mock.Anything, "", "oidc:stub", mock.Anything).Return(nil, models.ErrNotFound)
+		mockRepo.On("CreateUserIdentity", mock.Anything, mock.MatchedBy(func(i models.UserIdentity) bool {
+			return i.UserID == user.ID && i.Provider == "oidc:stub" && i.Subject == "12345" && i.Email == user.Email
+		})).Return(nil)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+		mockRepo.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{}, nil)
+		mockRepo.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{}, nil)
+		service := New(mockRepo, "secret", time.Hour, WithBaseURL("https://auth.example.com"), WithOIDCProviders(provider))
+
+		state, code, nonce := oidcLogin(t, service, stub, verified)
+		resp, err := service.CompleteOIDCLogin(context.Background(), "stub", state, code, nonce)
+		if assert.NoError(t, err) {
+			claims, err := token.ValidateToken(resp.Token, service.JwtSecret())
+			assert.NoError(t, err)
+			assert.Equal(t, user.ID.String(), claims["sub"])
+			assert.Equal(t, []string{token.AMRFederated}, token.StringsClaim(claims, "amr"))
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("new user", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserIdentity", mock.Anything, "", "oidc:stub", mock.Anything).Return(nil, models.ErrNotFound)
+		mockRepo.On("CreateUserIdentity", mock.Anything, mock.MatchedBy(func(i models.UserIdentity) bool {
+			return i.Subject == "67890"
+		})).Return(nil)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", "new@example.com").Return(nil, models.ErrNotFound)
+		mockRepo.On("CreateUser", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+			return u.Username == "new" && u.Email == "new@example.com" && u.EmailVerified && !u.HasPassword()
+		})).Return(models.ErrUsernameExists).Once()
+		mockRepo.On("CreateUser", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+			return len(u.Username) == len("new-xxxx") && u.DisplayName == "New User"
+		})).Return(nil).Once()
+		mockRepo.On("GetMFAMethods", mock.Anything, mock.Anything).Return([]models.MFAMethod{}, nil)
+		mockRepo.On("GetUserRoles", mock.Anything, mock.Anything).Return([]models.Role{}, nil)
+		sink := &memorySink{}
+		service := New(mockRepo, "secret", time.Hour, WithBaseURL("https://auth.example.com"),
+			WithOIDCProviders(provider), WithAuditSink(sink))
+
+		state, code, nonce := oidcLogin(t, service, stub, map[string]any{
+			"sub": "67890", "email": "new@example.com", "email_verified": true, "name": "New User", "preferred_username": "new",
+		})
+		_, err := service.CompleteOIDCLogin(context.Background(), "stub", state, code, nonce)
+		assert.NoError(t, err)
+		if assert.Len(t, sink.events, 3) {
+			assert.Equal(t, models.AuditRegistered, sink.events[0].Type)
+			assert.Equal(t, "oidc:stub", sink.events[0].Method)
+			assert.Equal(t, models.AuditIdentityLinked, sink.events[1].Type)
+			assert.Equal(t, models.AuditLoginSucceeded, sink.events[2].Type)
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("invite-only registration", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserIdentity", mock.Anything, "", "oidc:stub", mock.Anything).Return(nil, models.ErrNotFound)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(nil, models.ErrNotFound)
+		service := New(mockRepo, "secret", time.Hour, WithBaseURL("https://auth.example.com"),
+			WithOIDCProviders(provider), WithRegistrationMode(RegistrationInviteOnly))
+
+		state, code, nonce := oidcLogin(t, service, stub, verified)
+		_, err := service.CompleteOIDCLogin(context.Background(), "stub", state, code, nonce)
+		assert.ErrorIs(t, err, ErrInviteRequired)
+		mockRepo.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unverified email", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserIdentity", mock.Anything, "", "oidc:stub", mock.Anything).Return(nil, models.ErrNotFound)
+		service := New(mockRepo, "secret", time.Hour, WithBaseURL("https://auth.example.com"),
+			WithOIDCProviders(provider))
+
+		state, code, nonce := oidcLogin(t, service, stub, map[string]any{"sub": "12345", "email": user.Email})
+		_, err := service.CompleteOIDCLogin(context.Background(), "stub", state, code, nonce)
+		assert.ErrorIs(t, err, ErrExternalEmailNotVerified)
+	})
+
+	t.Run("unverified local account", func(t *testing.T) {
+		unverified := *user
+		unverified.EmailVerified = false
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserIdentity", mock.Anything, "", "oidc:stub", mock.Anything).Return(nil, models.ErrNotFound)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(&unverified, nil)
+		service := New(mockRepo, "secret", time.Hour, WithBaseURL("https://auth.example.com"),
+			WithOIDCProviders(provider))
+
+		// whoever registered the email first must not keep a password to the account
+		state, code, nonce := oidcLogin(t, service, stub, verified)
+		_, err := service.CompleteOIDCLogin(context.Background(), "stub", state, code, nonce)
+		assert.ErrorIs(t, err, ErrIdentityExists)
+		mockRepo.AssertNotCalled(t, "CreateUserIdentity", mock.Anything, mock.Anything)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserIdentity", mock.Anything, "", "oidc:stub", mock.Anything).Return(nil, models.ErrNotFound)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(nil, errors.New("connection refused"))
+		service := New(mockRepo, "secret", time.Hour, WithBaseURL("https://auth.example.com"),
+			WithOIDCProviders(provider))
+
+		state, code, nonce := oidcLogin(t, service, stub, verified)
+		_, err := service.CompleteOIDCLogin(context.Background(), "stub", state, code, nonce)
+		assert.Error(t, err)
+		mockRepo.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("another browser", func(t *testing.T) {
+		service := New(new(mockrepo.MockRepository), "secret", time.Hour, WithBaseURL("https://auth.example.com"),
+			WithOIDCProviders(provider))
+
+		state, code, _ := oidcLogin(t, service, stub, verified)
+		_, err := service.CompleteOIDCLogin(context.Background(), "stub", state, code, "other-nonce")
+		assert.ErrorIs(t, err, ErrInvalidOIDCLogin)
+	})
+
+	t.Run("invalid code", func(t *testing.T) {
+		service := New(new(mockrepo.MockRepository), "secret", time.Hour, WithBaseURL("https://auth.example.com"),
+			WithOIDCProviders(provider))
+
+		state, _, nonce := oidcLogin(t, service, stub, verified)
+		_, err := service.CompleteOIDCLogin(context.Background(), "stub", state, "forged", nonce)
+		assert.ErrorIs(t, err, ErrInvalidOIDCLogin)
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		service := New(new(mockrepo.MockRepository), "secret", time.Hour)
+
+		_, _, err := service.StartOIDCLogin(context.Background(), "stub")
+		assert.ErrorIs(t, err, ErrProviderNotFound)
+	})
+}
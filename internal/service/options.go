@@ -0,0 +1,213 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	"github.com/AlexFox86/auth-service/internal/pkg/oidc"
+	"github.com/AlexFox86/auth-service/internal/pkg/saml"
+	"github.com/AlexFox86/auth-service/internal/pkg/sender"
+)
+
+// Option configures the optional features of the service
+type Option func(*Service)
+
+// WithMailer sets the mailer used to send emails to users
+func WithMailer(m mailer.Mailer) Option {
+	return func(s *Service) {
+		s.mailer = m
+	}
+}
+
+// WithBaseURL sets the public URL of the service used to build links in emails
+func WithBaseURL(baseURL string) Option {
+	return func(s *Service) {
+		s.baseURL = baseURL
+	}
+}
+
+// WithFrontendURL sets the URL of the web app which opens the links in emails that
+// need a form or a login, e.g. to reset the password, and posts their tokens to the API
+func WithFrontendURL(frontendURL string) Option {
+	return func(s *Service) {
+		s.frontendURL = frontendURL
+	}
+}
+
+// WithMagicLinkExpiry sets the lifetime of magic login links
+func WithMagicLinkExpiry(expiry time.Duration) Option {
+	return func(s *Service) {
+		s.magicLinkExpiry = expiry
+	}
+}
+
+// WithOTPSender sets the sender of one-time codes for the MFA method type
+func WithOTPSender(methodType string, otpSender sender.Sender) Option {
+	return func(s *Service) {
+		if s.otpSenders == nil {
+			s.otpSenders = make(map[string]sender.Sender)
+		}
+		s.otpSenders[methodType] = otpSender
+	}
+}
+
+// WithOTPSendLimit limits the number of one-time codes sent to a user within the window
+func WithOTPSendLimit(limit int, window time.Duration) Option {
+	return func(s *Service) {
+		s.otpSendLimit = limit
+		s.otpSendWindow = window
+	}
+}
+
+// WithStepUpExpiry sets the lifetime of elevated tokens issued by StepUp
+func WithStepUpExpiry(expiry time.Duration) Option {
+	return func(s *Service) {
+		s.stepUpExpiry = expiry
+	}
+}
+
+// WithEmailVerificationPolicy sets what users with an unverified email can do
+func WithEmailVerificationPolicy(policy EmailVerificationPolicy) Option {
+	return func(s *Service) {
+		s.emailVerificationPolicy = policy
+	}
+}
+
+// WithReservedUsernames replaces the default list of usernames users can't register
+func WithReservedUsernames(names ...string) Option {
+	return func(s *Service) {
+		s.reservedUsernames = names
+	}
+}
+
+// WithLockoutPolicy enables the delays and the lockout after failed logins
+func WithLockoutPolicy(policy LockoutPolicy) Option {
+	return func(s *Service) {
+		s.lockoutPolicy = &policy
+	}
+}
+
+// WithLockoutNotifier sets the function called when an account is locked
+func WithLockoutNotifier(notifier LockoutNotifier) Option {
+	return func(s *Service) {
+		s.lockoutNotifier = notifier
+	}
+}
+
+// WithAuditSink adds a sink of audit events
+func WithAuditSink(sink AuditSink) Option {
+	return func(s *Service) {
+		s.auditSinks = append(s.auditSinks, sink)
+	}
+}
+
+// WithEnumerationProtection makes registration respond the same whether the email
+// is taken or not. The owner of a taken email is told about the attempt by email.
+func WithEnumerationProtection() Option {
+	return func(s *Service) {
+		s.enumerationProtection = true
+	}
+}
+
+// WithPasswordPolicy sets the password policy of the service, the policies of tenants add to it
+func WithPasswordPolicy(policy models.PasswordPolicy) Option {
+	return func(s *Service) {
+		s.passwordPolicy = policy
+	}
+}
+
+// WithTenants enables the isolated tenants. Each tenant has its own users,
+// signing key, token expiry and password policy.
+func WithTenants(tenants ...models.Tenant) Option {
+	return func(s *Service) {
+		if s.tenants == nil {
+			s.tenants = make(map[string]*models.Tenant)
+			s.tenantHosts = make(map[string]string)
+		}
+		for _, tenant := range tenants {
+			s.tenants[tenant.ID] = &tenant
+			for _, host := range tenant.Hosts {
+				s.tenantHosts[strings.ToLower(host)] = tenant.ID
+			}
+		}
+	}
+}
+
+// WithDeletionGracePeriod sets the time deleted accounts can be restored
+// before they are purged, 30 days by default
+func WithDeletionGracePeriod(period time.Duration) Option {
+	return func(s *Service) {
+		s.deletionGrace = period
+	}
+}
+
+// WithRegistrationMode sets who can register. The domains are the email domains
+// allowed to register in the RegistrationDomainRestricted mode.
+func WithRegistrationMode(mode RegistrationMode, domains ...string) Option {
+	return func(s *Service) {
+		s.registrationMode = mode
+		for _, domain := range domains {
+			s.allowedDomains = append(s.allowedDomains, strings.ToLower(strings.TrimSpace(domain)))
+		}
+	}
+}
+
+// WithDisposableEmailDomains blocks registration with emails of the domains
+// and their subdomains, unless the user has an invite
+func WithDisposableEmailDomains(domains ...string) Option {
+	return func(s *Service) {
+		if s.disposableDomains == nil {
+			s.disposableDomains = make(map[string]struct{}, len(domains))
+		}
+		for _, domain := range domains {
+			s.disposableDomains[strings.ToLower(strings.TrimSpace(domain))] = struct{}{}
+		}
+	}
+}
+
+// WithImpersonationExpiry sets the lifetime of the tokens admins get to act as users
+func WithImpersonationExpiry(expiry time.Duration) Option {
+	return func(s *Service) {
+		s.impersonationExpiry = expiry
+	}
+}
+
+// WithAuthenticators adds the external password stores Login tries
+// for the tenant's users without a local password
+func WithAuthenticators(tenantID string, authenticators ...Authenticator) Option {
+	return func(s *Service) {
+		if s.authenticators == nil {
+			s.authenticators = make(map[string][]Authenticator)
+		}
+		s.authenticators[tenantID] = append(s.authenticators[tenantID], authenticators...)
+	}
+}
+
+// WithOIDCProviders enables login with the upstream OpenID providers
+func WithOIDCProviders(providers ...*oidc.Provider) Option {
+	return func(s *Service) {
+		if s.oidcProviders == nil {
+			s.oidcProviders = make(map[string]*oidc.Provider, len(providers))
+		}
+		for _, provider := range providers {
+			s.oidcProviders[provider.Name()] = provider
+		}
+	}
+}
+
+// WithSAMLIdPs enables login with the SAML identity providers of the tenants
+func WithSAMLIdPs(idps ...*saml.IdentityProvider) Option {
+	return func(s *Service) {
+		if s.samlIdPs == nil {
+			s.samlIdPs = make(map[string]map[string]*saml.IdentityProvider)
+		}
+		for _, idp := range idps {
+			if s.samlIdPs[idp.Tenant()] == nil {
+				s.samlIdPs[idp.Tenant()] = make(map[string]*saml.IdentityProvider)
+			}
+			s.samlIdPs[idp.Tenant()][idp.Name()] = idp
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/google/uuid"
+)
+
+const (
+	invitationPurpose = "org_invitation"
+	invitationExpiry  = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrOrgNotFound returned when the organization doesn't exist or the user isn't its member
+	ErrOrgNotFound = errors.New("organization not found")
+
+	// ErrNotOrgMember returned when a token is requested for an organization
+	// the user isn't a member of
+	ErrNotOrgMember = errors.New("not a member of the organization")
+
+	// ErrOrgAccessDenied returned when the member's role doesn't allow the operation
+	ErrOrgAccessDenied = errors.New("organization access denied")
+
+	// ErrMemberNotFound returned when the user isn't a member of the organization
+	ErrMemberNotFound = errors.New("member not found")
+
+	// ErrLastOwner returned when the operation would leave the organization without owners
+	ErrLastOwner = errors.New("organization must have at least one owner")
+
+	// ErrInvalidSlug returned when the slug isn't lowercase letters and digits separated by hyphens
+	ErrInvalidSlug = errors.New("invalid slug")
+
+	// ErrInvalidInvitation returned when the invitation is invalid, expired,
+	// revoked, already accepted or sent to another email
+	ErrInvalidInvitation = errors.New("invalid invitation")
+
+	// ErrSlugExists returned when an organization with the slug already exists
+	ErrSlugExists = models.ErrSlugExists
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// CreateOrganization creates the organization with the user as its owner
+func (s *Service) CreateOrganization(ctx context.Context, userID uuid.UUID, req *dto.CreateOrgRequest) (*models.Organization, error) {
+	slug := strings.ToLower(strings.TrimSpace(req.Slug))
+	if !slugPattern.MatchString(slug) {
+		return nil, ErrInvalidSlug
+	}
+
+	org := models.Organization{
+		ID:        uuid.New(),
+		TenantID:  s.tenantID(ctx),
+		Name:      strings.TrimSpace(req.Name),
+		Slug:      slug,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.CreateOrganization(ctx, org, userID); err != nil {
+		return nil, fmt.Errorf("create organization: %w", err)
+	}
+	return &org, nil
+}
+
+// UserOrganizations returns the organizations the user is a member of with the user's roles
+func (s *Service) UserOrganizations(ctx context.Context, userID uuid.UUID) ([]models.UserOrganization, error) {
+	orgs, err := s.repo.GetUserOrganizations(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user organizations: %w", err)
+	}
+	return orgs, nil
+}
+
+// SwitchOrg reissues the access token scoped to another organization, or unscoped
+// if the orgID is nil. The authentication context and the expiration of the token
+// are kept, so switching doesn't prolong the session.
+func (s *Service) SwitchOrg(ctx context.Context, accessToken string, orgID uuid.UUID) (*dto.Response, error) {
+	claims, err := token.ValidateToken(accessToken, s.SigningKey(ctx))
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	sub, _ := claims["sub"].(string)
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	authTime, ok := token.AuthTime(claims)
+	if !ok {
+		authTime = time.Now()
+	}
+
+	user, err := s.repo.GetUserByID(ctx, s.tenantID(ctx), userID)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	membership, err := s.orgMembership(ctx, orgID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the token of another scope keeps the expiration time and the impersonating user
+	var extra []token.Option
+	if actorID, ok := token.Actor(claims); ok {
+		extra = append(extra, token.WithActor(actorID))
+	}
+	expiry := time.Until(time.Unix(int64(exp), 0))
+	return s.issueToken(ctx, user, token.StringsClaim(claims, "amr"), authTime, expiry, membership, extra...)
+}
+
+// Members returns the members of the organization, visible to any member
+func (s *Service) Members(ctx context.Context, actorID, orgID uuid.UUID) ([]models.Membership, error) {
+	if _, err := s.requireOrgRole(ctx, orgID, actorID); err != nil {
+		return nil, err
+	}
+
+	members, err := s.repo.ListMembers(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("list members: %w", err)
+	}
+	return members, nil
+}
+
+// UpdateMemberRole changes the role of the member. Admins manage admins and members,
+// only owners can grant the owner role or change the role of another owner.
+func (s *Service) UpdateMemberRole(ctx context.Context, actorID, orgID, userID uuid.UUID, role string) error {
+	actor, err := s.requireOrgRole(ctx, orgID, actorID, models.OrgRoleOwner, models.OrgRoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	member, err := s.repo.GetMembership(ctx, orgID, userID)
+	if err != nil {
+		return memberError("get membership", err)
+	}
+
+	if (role == models.OrgRoleOwner || member.Role == models.OrgRoleOwner) && actor.Role != models.OrgRoleOwner {
+		return ErrOrgAccessDenied
+	}
+	if member.Role == models.OrgRoleOwner && role != models.OrgRoleOwner {
+		if err := s.checkNotLastOwner(ctx, orgID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.UpdateMemberRole(ctx, orgID, userID, role); err != nil {
+		return memberError("update member role", err)
+	}
+	return nil
+}
+
+// RemoveMember removes the user from the organization. Members can leave themselves,
+// admins remove admins and members, and only owners can remove other owners.
+// Tokens already scoped to the organization stay valid until they expire.
+func (s *Service) RemoveMember(ctx context.Context, actorID, orgID, userID uuid.UUID) error {
+	actor, err := s.requireOrgRole(ctx, orgID, actorID)
+	if err != nil {
+		return err
+	}
+
+	member, err := s.repo.GetMembership(ctx, orgID, userID)
+	if err != nil {
+		return memberError("get membership", err)
+	}
+
+	if actorID != userID {
+		if !isOrgManager(actor.Role) || (member.Role == models.OrgRoleOwner && actor.Role != models.OrgRoleOwner) {
+			return ErrOrgAccessDenied
+		}
+	}
+	if member.Role == models.OrgRoleOwner {
+		if err := s.checkNotLastOwner(ctx, orgID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.RemoveMember(ctx, orgID, userID); err != nil {
+		return memberError("remove member", err)
+	}
+	return nil
+}
+
+// InviteMember sends the invitation to join the organization to the email.
+// Only owners can invite new owners.
+func (s *Service) InviteMember(ctx context.Context, actorID, orgID uuid.UUID, req *dto.InviteRequest) (*models.Invitation, error) {
+	if s.mailer == nil {
+		return nil, ErrMailerNotConfigured
+	}
+
+	actor, err := s.requireOrgRole(ctx, orgID, actorID, models.OrgRoleOwner, models.OrgRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if req.Role == models.OrgRoleOwner && actor.Role != models.OrgRoleOwner {
+		return nil, ErrOrgAccessDenied
+	}
+
+	invitation := models.Invitation{
+		ID:        uuid.New(),
+		OrgID:     orgID,
+		Email:     normalizeEmail(req.Email),
+		Role:      req.Role,
+		InvitedBy: actorID,
+		Status:    models.InvitationPending,
+		ExpiresAt: time.Now().Add(invitationExpiry),
+	}
+	if err := s.repo.CreateInvitation(ctx, invitation); err != nil {
+		return nil, fmt.Errorf("create invitation: %w", err)
+	}
+
+	invitationToken, err := token.GenerateLinkToken(invitationPurpose, map[string]any{
+		"jti":    invitation.ID.String(),
+		"org_id": orgID.String(),
+	}, s.SigningKey(ctx), invitationExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("generate invitation token: %w", err)
+	}
+
+	msg := mailer.Message{
+		To:      invitation.Email,
+		Subject: "You have been invited to an organization",
+		Body: fmt.Sprintf("You have been invited to join an organization as %s.\n\n"+
+			"Sign in with this email and accept the invitation by following the link:\n\n"+
+			"%s/invitations/accept?token=%s\n\nThe link expires in %s.",
+			invitation.Role, s.appURL(ctx), url.QueryEscape(invitationToken), invitationExpiry),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return nil, fmt.Errorf("send invitation: %w", err)
+	}
+
+	return &invitation, nil
+}
+
+// Invitations returns the pending invitations to the organization
+func (s *Service) Invitations(ctx context.Context, actorID, orgID uuid.UUID) ([]models.Invitation, error) {
+	if _, err := s.requireOrgRole(ctx, orgID, actorID, models.OrgRoleOwner, models.OrgRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	invitations, err := s.repo.ListInvitations(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("list invitations: %w", err)
+	}
+	return invitations, nil
+}
+
+// RevokeInvitation revokes the pending invitation
+func (s *Service) RevokeInvitation(ctx context.Context, actorID, orgID, invitationID uuid.UUID) error {
+	if _, err := s.requireOrgRole(ctx, orgID, actorID, models.OrgRoleOwner, models.OrgRoleAdmin); err != nil {
+		return err
+	}
+
+	if err := s.repo.RevokeInvitation(ctx, orgID, invitationID); err != nil {
+		return ErrInvalidInvitation
+	}
+	return nil
+}
+
+// AcceptInvitation adds the user to the organization. The invitation can only
+// be accepted by the user with the email it was sent to.
+func (s *Service) AcceptInvitation(ctx context.Context, userID uuid.UUID, req *dto.AcceptInvitationRequest) (*models.Invitation, error) {
+	claims, err := token.ValidateLinkToken(req.Token, invitationPurpose, s.SigningKey(ctx))
+	if err != nil {
+		return nil, ErrInvalidInvitation
+	}
+
+	jti, _ := claims["jti"].(string)
+	invitationID, err := uuid.Parse(jti)
+	if err != nil {
+		return nil, ErrInvalidInvitation
+	}
+	org, _ := claims["org_id"].(string)
+	orgID, err := uuid.Parse(org)
+	if err != nil {
+		return nil, ErrInvalidInvitation
+	}
+
+	invitation, err := s.repo.GetInvitation(ctx, orgID, invitationID)
+	if err != nil {
+		return nil, ErrInvalidInvitation
+	}
+	if invitation.Status != models.InvitationPending || time.Now().After(invitation.ExpiresAt) {
+		return nil, ErrInvalidInvitation
+	}
+
+	user, err := s.repo.GetUserByID(ctx, s.tenantID(ctx), userID)
+	if err != nil {
+		return nil, ErrInvalidInvitation
+	}
+	if normalizeEmail(user.Email) != invitation.Email {
+		return nil, ErrInvalidInvitation
+	}
+
+	if err := s.repo.AcceptInvitation(ctx, *invitation, user.ID); err != nil {
+		return nil, ErrInvalidInvitation
+	}
+	return invitation, nil
+}
+
+// orgMembership returns the membership the token is scoped to,
+// or nil if the orgID is nil and the token is unscoped
+func (s *Service) orgMembership(ctx context.Context, orgID, userID uuid.UUID) (*models.Membership, error) {
+	if orgID == uuid.Nil {
+		return nil, nil
+	}
+
+	membership, err := s.repo.GetMembership(ctx, orgID, userID)
+	if err != nil {
+		return nil, ErrNotOrgMember
+	}
+	return membership, nil
+}
+
+// memberError returns ErrMemberNotFound if the membership doesn't exist,
+// otherwise the error of the action
+func memberError(action string, err error) error {
+	if errors.Is(err, models.ErrNotFound) {
+		return ErrMemberNotFound
+	}
+	return fmt.Errorf("%s: %w", action, err)
+}
+
+// requireOrgRole returns the membership of the actor if it has one of the roles,
+// any role if none are given. Non-members get ErrOrgNotFound, so they can't
+// tell whether the organization exists.
+func (s *Service) requireOrgRole(ctx context.Context, orgID, actorID uuid.UUID, roles ...string) (*models.Membership, error) {
+	membership, err := s.repo.GetMembership(ctx, orgID, actorID)
+	if err != nil {
+		return nil, ErrOrgNotFound
+	}
+
+	if len(roles) == 0 {
+		return membership, nil
+	}
+	for _, role := range roles {
+		if membership.Role == role {
+			return membership, nil
+		}
+	}
+	return nil, ErrOrgAccessDenied
+}
+
+func (s *Service) checkNotLastOwner(ctx context.Context, orgID uuid.UUID) error {
+	owners, err := s.repo.CountMembersWithRole(ctx, orgID, models.OrgRoleOwner)
+	if err != nil {
+		return fmt.Errorf("count owners: %w", err)
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+func isOrgManager(role string) bool {
+	return role == models.OrgRoleOwner || role == models.OrgRoleAdmin
+}
@@ -0,0 +1,422 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+var (
+	testOrgID   = uuid.MustParse("00000000-0000-0000-0000-0000000000a1")
+	testOwnerID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	testAdminID = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	testUserID  = uuid.MustParse("00000000-0000-0000-0000-000000000003")
+)
+
+func member(userID uuid.UUID, role string) *models.Membership {
+	return &models.Membership{OrgID: testOrgID, UserID: userID, Role: role}
+}
+
+func TestServiceLoginOrgScope(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("password123")
+	user := &models.User{ID: testUserID, Email: "test@example.com", Password: hashedPassword}
+	req := &dto.LoginRequest{Email: user.Email, Password: "password123", OrgID: testOrgID}
+
+	t.Run("member", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+		mockRepo.On("GetMembership", mock.Anything, testOrgID, user.ID).
+			Return(member(user.ID, models.OrgRoleAdmin), nil)
+		mockRepo.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{}, nil)
+		mockRepo.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{}, nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		resp, err := service.Login(context.Background(), req)
+		assert.NoError(t, err)
+
+		claims, err := token.ValidateToken(resp.Token, service.JwtSecret())
+		assert.NoError(t, err)
+		assert.Equal(t, testOrgID.String(), claims["org_id"])
+		assert.Equal(t, models.OrgRoleAdmin, claims["org_role"])
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("not a member", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+		mockRepo.On("GetMembership", mock.Anything, testOrgID, user.ID).
+			Return(nil, models.ErrNotFound)
+		service := New(mockRepo, "secret", time.Hour)
+
+		_, err := service.Login(context.Background(), req)
+		assert.ErrorIs(t, err, ErrNotOrgMember)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("mfa challenge keeps the organization", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+		mockRepo.On("GetMembership", mock.Anything, testOrgID, user.ID).
+			Return(member(user.ID, models.OrgRoleMember), nil)
+		mockRepo.On("GetMFAMethods", mock.Anything, user.ID).
+			Return([]models.MFAMethod{{ID: uuid.New(), Type: models.MFAMethodEmail, Confirmed: true}}, nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		_, err := service.Login(context.Background(), req)
+		var mfaErr *MFARequiredError
+		if !assert.ErrorAs(t, err, &mfaErr) {
+			return
+		}
+
+		challenge, err := service.parseMFAChallenge(context.Background(), mfaErr.Challenge.ChallengeToken)
+		assert.NoError(t, err)
+		assert.Equal(t, testOrgID, challenge.orgID)
+	})
+}
+
+func TestServiceSwitchOrg(t *testing.T) {
+	user := &models.User{ID: testUserID, Email: "test@example.com"}
+	authTime := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+	mockRepo.On("GetMembership", mock.Anything, testOrgID, user.ID).
+		Return(member(user.ID, models.OrgRoleMember), nil)
+	mockRepo.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{}, nil)
+	service := New(mockRepo, "secret", time.Hour)
+
+	accessToken, _ := token.GenerateToken(user, service.JwtSecret(), 30*time.Minute,
+		token.WithAuthContext([]string{token.AMRPassword}, authTime))
+	original, _ := token.ValidateToken(accessToken, service.JwtSecret())
+
+	resp, err := service.SwitchOrg(context.Background(), accessToken, testOrgID)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	claims, err := token.ValidateToken(resp.Token, service.JwtSecret())
+	assert.NoError(t, err)
+	assert.Equal(t, testOrgID.String(), claims["org_id"])
+	assert.Equal(t, []string{token.AMRPassword}, token.StringsClaim(claims, "amr"))
+	switchedAuthTime, _ := token.AuthTime(claims)
+	assert.Equal(t, authTime.Unix(), switchedAuthTime.Unix())
+	assert.LessOrEqual(t, claims["exp"], original["exp"])
+
+	t.Run("not a member", func(t *testing.T) {
+		otherOrg := uuid.New()
+		mockRepo.On("GetMembership", mock.Anything, otherOrg, user.ID).
+			Return(nil, models.ErrNotFound)
+
+		_, err := service.SwitchOrg(context.Background(), accessToken, otherOrg)
+		assert.ErrorIs(t, err, ErrNotOrgMember)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, err := service.SwitchOrg(context.Background(), "invalid", testOrgID)
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+}
+
+func TestServiceCreateOrganization(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("CreateOrganization", mock.Anything, mock.MatchedBy(func(org models.Organization) bool {
+			return org.Name == "Acme" && org.Slug == "acme-inc"
+		}), testOwnerID).Return(nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		org, err := service.CreateOrganization(context.Background(), testOwnerID,
+			&dto.CreateOrgRequest{Name: " Acme ", Slug: "Acme-Inc"})
+		assert.NoError(t, err)
+		assert.Equal(t, "acme-inc", org.Slug)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("invalid slug", func(t *testing.T) {
+		service := New(new(mockrepo.MockRepository), "secret", time.Hour)
+
+		_, err := service.CreateOrganization(context.Background(), testOwnerID,
+			&dto.CreateOrgRequest{Name: "Acme", Slug: "acme inc"})
+		assert.ErrorIs(t, err, ErrInvalidSlug)
+	})
+
+	t.Run("slug exists", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("CreateOrganization", mock.Anything, mock.AnythingOfType("models.Organization"), testOwnerID).
+			Return(models.ErrSlugExists)
+		service := New(mockRepo, "secret", time.Hour)
+
+		_, err := service.CreateOrganization(context.Background(), testOwnerID,
+			&dto.CreateOrgRequest{Name: "Acme", Slug: "acme"})
+		assert.ErrorIs(t, err, ErrSlugExists)
+	})
+}
+
+func TestServiceUpdateMemberRole(t *testing.T) {
+	tests := []struct {
+		name        string
+		actor       *models.Membership
+		target      *models.Membership
+		role        string
+		owners      int
+		expectedErr error
+	}{
+		{
+			name:   "admin promotes member",
+			actor:  member(testAdminID, models.OrgRoleAdmin),
+			target: member(testUserID, models.OrgRoleMember),
+			role:   models.OrgRoleAdmin,
+		},
+		{
+			name:        "admin can't grant owner",
+			actor:       member(testAdminID, models.OrgRoleAdmin),
+			target:      member(testUserID, models.OrgRoleMember),
+			role:        models.OrgRoleOwner,
+			expectedErr: ErrOrgAccessDenied,
+		},
+		{
+			name:        "admin can't demote owner",
+			actor:       member(testAdminID, models.OrgRoleAdmin),
+			target:      member(testOwnerID, models.OrgRoleOwner),
+			role:        models.OrgRoleMember,
+			expectedErr: ErrOrgAccessDenied,
+		},
+		{
+			name:        "member can't change roles",
+			actor:       member(testUserID, models.OrgRoleMember),
+			role:        models.OrgRoleAdmin,
+			expectedErr: ErrOrgAccessDenied,
+		},
+		{
+			name:        "last owner can't be demoted",
+			actor:       member(testOwnerID, models.OrgRoleOwner),
+			target:      member(testOwnerID, models.OrgRoleOwner),
+			role:        models.OrgRoleAdmin,
+			owners:      1,
+			expectedErr: ErrLastOwner,
+		},
+		{
+			name:   "owner demotes another owner",
+			actor:  member(testOwnerID, models.OrgRoleOwner),
+			target: member(testAdminID, models.OrgRoleOwner),
+			role:   models.OrgRoleAdmin,
+			owners: 2,
+		},
+		{
+			name:        "member of another organization",
+			role:        models.OrgRoleAdmin,
+			expectedErr: ErrOrgNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actorID, targetID := testAdminID, testUserID
+			if tt.actor != nil {
+				actorID = tt.actor.UserID
+			}
+			if tt.target != nil {
+				targetID = tt.target.UserID
+			}
+
+			mockRepo := new(mockrepo.MockRepository)
+			if tt.actor == nil {
+				mockRepo.On("GetMembership", mock.Anything, testOrgID, actorID).
+					Return(nil, models.ErrNotFound)
+			} else {
+				mockRepo.On("GetMembership", mock.Anything, testOrgID, actorID).Return(tt.actor, nil).Once()
+			}
+			if tt.target != nil {
+				mockRepo.On("GetMembership", mock.Anything, testOrgID, targetID).Return(tt.target, nil)
+			}
+			if tt.owners > 0 {
+				mockRepo.On("CountMembersWithRole", mock.Anything, testOrgID, models.OrgRoleOwner).Return(tt.owners, nil)
+			}
+			if tt.expectedErr == nil {
+				mockRepo.On("UpdateMemberRole", mock.Anything, testOrgID, targetID, tt.role).Return(nil)
+			}
+			service := New(mockRepo, "secret", time.Hour)
+
+			err := service.UpdateMemberRole(context.Background(), actorID, testOrgID, targetID, tt.role)
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceRemoveMember(t *testing.T) {
+	tests := []struct {
+		name        string
+		actor       *models.Membership
+		target      *models.Membership
+		owners      int
+		expectedErr error
+	}{
+		{
+			name:   "admin removes member",
+			actor:  member(testAdminID, models.OrgRoleAdmin),
+			target: member(testUserID, models.OrgRoleMember),
+		},
+		{
+			name:   "member leaves",
+			actor:  member(testUserID, models.OrgRoleMember),
+			target: member(testUserID, models.OrgRoleMember),
+		},
+		{
+			name:        "member can't remove others",
+			actor:       member(testUserID, models.OrgRoleMember),
+			target:      member(testAdminID, models.OrgRoleAdmin),
+			expectedErr: ErrOrgAccessDenied,
+		},
+		{
+			name:        "admin can't remove owner",
+			actor:       member(testAdminID, models.OrgRoleAdmin),
+			target:      member(testOwnerID, models.OrgRoleOwner),
+			expectedErr: ErrOrgAccessDenied,
+		},
+		{
+			name:        "last owner can't leave",
+			actor:       member(testOwnerID, models.OrgRoleOwner),
+			target:      member(testOwnerID, models.OrgRoleOwner),
+			owners:      1,
+			expectedErr: ErrLastOwner,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			mockRepo.On("GetMembership", mock.Anything, testOrgID, tt.actor.UserID).Return(tt.actor, nil).Once()
+			mockRepo.On("GetMembership", mock.Anything, testOrgID, tt.target.UserID).Return(tt.target, nil).Once()
+			if tt.owners > 0 {
+				mockRepo.On("CountMembersWithRole", mock.Anything, testOrgID, models.OrgRoleOwner).Return(tt.owners, nil)
+			}
+			if tt.expectedErr == nil {
+				mockRepo.On("RemoveMember", mock.Anything, testOrgID, tt.target.UserID).Return(nil)
+			}
+			service := New(mockRepo, "secret", time.Hour)
+
+			err := service.RemoveMember(context.Background(), tt.actor.UserID, testOrgID, tt.target.UserID)
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceMemberErrors(t *testing.T) {
+	t.Run("not a member", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetMembership", mock.Anything, testOrgID, testAdminID).Return(member(testAdminID, models.OrgRoleAdmin), nil)
+		mockRepo.On("GetMembership", mock.Anything, testOrgID, testUserID).Return(nil, models.ErrNotFound)
+		service := New(mockRepo, "secret", time.Hour)
+
+		err := service.UpdateMemberRole(context.Background(), testAdminID, testOrgID, testUserID, models.OrgRoleAdmin)
+		assert.ErrorIs(t, err, ErrMemberNotFound)
+		err = service.RemoveMember(context.Background(), testAdminID, testOrgID, testUserID)
+		assert.ErrorIs(t, err, ErrMemberNotFound)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetMembership", mock.Anything, testOrgID, testAdminID).Return(member(testAdminID, models.OrgRoleAdmin), nil)
+		mockRepo.On("GetMembership", mock.Anything, testOrgID, testUserID).Return(member(testUserID, models.OrgRoleMember), nil)
+		mockRepo.On("UpdateMemberRole", mock.Anything, testOrgID, testUserID, models.OrgRoleAdmin).Return(assert.AnError)
+		mockRepo.On("RemoveMember", mock.Anything, testOrgID, testUserID).Return(assert.AnError)
+		service := New(mockRepo, "secret", time.Hour)
+
+		err := service.UpdateMemberRole(context.Background(), testAdminID, testOrgID, testUserID, models.OrgRoleAdmin)
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.NotErrorIs(t, err, ErrMemberNotFound)
+		err = service.RemoveMember(context.Background(), testAdminID, testOrgID, testUserID)
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.NotErrorIs(t, err, ErrMemberNotFound)
+	})
+}
+
+func TestServiceInvitation(t *testing.T) {
+	invitee := &models.User{ID: testUserID, Email: "Invitee@Example.com"}
+
+	var invitation models.Invitation
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetMembership", mock.Anything, testOrgID, testAdminID).
+		Return(member(testAdminID, models.OrgRoleAdmin), nil)
+	mockRepo.On("CreateInvitation", mock.Anything, mock.AnythingOfType("models.Invitation")).
+		Return(nil).
+		Run(func(args mock.Arguments) {
+			invitation = args.Get(1).(models.Invitation)
+		})
+	m := mailer.NewMemoryMailer()
+	service := New(mockRepo, "secret", time.Hour, WithMailer(m), WithFrontendURL("https://app.example.com"))
+
+	_, err := service.InviteMember(context.Background(), testAdminID, testOrgID,
+		&dto.InviteRequest{Email: "invitee@example.com", Role: models.OrgRoleOwner})
+	assert.ErrorIs(t, err, ErrOrgAccessDenied)
+
+	_, err = service.InviteMember(context.Background(), testAdminID, testOrgID,
+		&dto.InviteRequest{Email: "invitee@example.com", Role: models.OrgRoleMember})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, models.InvitationPending, invitation.Status)
+
+	msg, ok := m.Last()
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, "invitee@example.com", msg.To)
+	assert.Contains(t, msg.Body, "https://app.example.com/invitations/accept?token=")
+	invitationToken := linkToken(t, msg.Body)
+
+	t.Run("another user", func(t *testing.T) {
+		otherID := uuid.New()
+		mockRepo.On("GetInvitation", mock.Anything, testOrgID, invitation.ID).Return(&invitation, nil).Once()
+		mockRepo.On("GetUserByID", mock.Anything, "", otherID).
+			Return(&models.User{ID: otherID, Email: "other@example.com"}, nil).Once()
+
+		_, err := service.AcceptInvitation(context.Background(), otherID,
+			&dto.AcceptInvitationRequest{Token: invitationToken})
+		assert.ErrorIs(t, err, ErrInvalidInvitation)
+	})
+
+	t.Run("accepted", func(t *testing.T) {
+		mockRepo.On("GetInvitation", mock.Anything, testOrgID, invitation.ID).Return(&invitation, nil).Once()
+		mockRepo.On("GetUserByID", mock.Anything, "", invitee.ID).Return(invitee, nil).Once()
+		mockRepo.On("AcceptInvitation", mock.Anything, invitation, invitee.ID).Return(nil).Once()
+
+		accepted, err := service.AcceptInvitation(context.Background(), invitee.ID,
+			&dto.AcceptInvitationRequest{Token: invitationToken})
+		assert.NoError(t, err)
+		assert.Equal(t, testOrgID, accepted.OrgID)
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		revoked := invitation
+		revoked.Status = models.InvitationRevoked
+		mockRepo.On("GetInvitation", mock.Anything, testOrgID, invitation.ID).Return(&revoked, nil).Once()
+
+		_, err := service.AcceptInvitation(context.Background(), invitee.ID,
+			&dto.AcceptInvitationRequest{Token: invitationToken})
+		assert.ErrorIs(t, err, ErrInvalidInvitation)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, err := service.AcceptInvitation(context.Background(), invitee.ID,
+			&dto.AcceptInvitationRequest{Token: "invalid"})
+		assert.ErrorIs(t, err, ErrInvalidInvitation)
+	})
+
+	mockRepo.AssertExpectations(t)
+}
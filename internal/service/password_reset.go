@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/google/uuid"
+)
+
+const (
+	passwordResetPurpose = "password_reset"
+	passwordResetExpiry  = time.Hour
+)
+
+var (
+	// ErrPasswordResetRequired returned on login when an admin required the user to reset the password
+	ErrPasswordResetRequired = errors.New("password reset required")
+
+	// ErrInvalidResetToken returned when the password reset token is invalid, expired or already used
+	ErrInvalidResetToken = errors.New("invalid password reset token")
+)
+
+// ForcePasswordReset blocks logins with the current password of the user
+// and sends the user the link to set a new one
+func (s *Service) ForcePasswordReset(ctx context.Context, userID uuid.UUID) error {
+	if s.mailer == nil {
+		return ErrMailerNotConfigured
+	}
+
+	user, err := s.User(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.SetPasswordResetRequired(ctx, user.ID); err != nil {
+		return fmt.Errorf("require password reset: %w", err)
+	}
+	s.audit(ctx, models.AuditEvent{Type: models.AuditPasswordResetForced, UserID: &user.ID})
+
+	return s.sendPasswordReset(ctx, user)
+}
+
+// ResetPassword sets the new password with the token from the reset link.
+// The token is bound to the current password, so it can be used only once.
+func (s *Service) ResetPassword(ctx context.Context, req *dto.ResetPasswordRequest) error {
+	claims, err := token.ValidateLinkToken(req.Token, passwordResetPurpose, s.SigningKey(ctx))
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	sub, _ := claims["sub"].(string)
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	user, err := s.repo.GetUserByID(ctx, s.tenantID(ctx), userID)
+	if err != nil || claims["pwd"] != crypto.HashToken(user.Password) {
+		return ErrInvalidResetToken
+	}
+
+	if err := s.checkPasswordPolicy(ctx, req.Password); err != nil {
+		return err
+	}
+	hashedPassword, err := crypto.HashPassword(req.Password)
+	if err != nil {
+		return fmt.Errorf("hash password: %w", err)
+	}
+
+	if err := s.repo.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		return fmt.Errorf("update password: %w", err)
+	}
+	s.audit(ctx, models.AuditEvent{Type: models.AuditPasswordChanged, UserID: &user.ID})
+	return nil
+}
+
+// sendPasswordReset sends the link to set a new password
+func (s *Service) sendPasswordReset(ctx context.Context, user *models.User) error {
+	resetToken, err := token.GenerateLinkToken(passwordResetPurpose, map[string]any{
+		"sub": user.ID.String(),
+		"pwd": crypto.HashToken(user.Password),
+	}, s.SigningKey(ctx), passwordResetExpiry)
+	if err != nil {
+		return fmt.Errorf("generate password reset token: %w", err)
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("An administrator requires you to set a new password. "+
+			"Follow the link to set it:\n\n%s/password/reset?token=%s\n\nThe link expires in %s.",
+			s.appURL(ctx), url.QueryEscape(resetToken), passwordResetExpiry),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("send password reset email: %w", err)
+	}
+	return nil
+}
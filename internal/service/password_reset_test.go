@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+func TestServicePasswordReset(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("password123")
+	user := &models.User{
+		ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		Email:    "test@example.com",
+		Password: hashedPassword,
+	}
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+	mockRepo.On("SetPasswordResetRequired", mock.Anything, user.ID).Return(nil)
+	mockRepo.On("UpdatePassword", mock.Anything, user.ID, mock.AnythingOfType("string")).
+		Return(nil).
+		Run(func(args mock.Arguments) {
+			user.Password = args.String(2)
+		})
+	m := mailer.NewMemoryMailer()
+	service := New(mockRepo, "secret", time.Hour, WithMailer(m), WithFrontendURL("https://app.example.com"),
+		WithPasswordPolicy(models.PasswordPolicy{MinLength: 12}))
+
+	assert.NoError(t, service.ForcePasswordReset(context.Background(), user.ID))
+	msg, ok := m.Last()
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, user.Email, msg.To)
+	assert.Contains(t, msg.Body, "https://app.example.com/password/reset?token=")
+	resetToken := linkToken(t, msg.Body)
+
+	err := service.ResetPassword(context.Background(), &dto.ResetPasswordRequest{Token: resetToken, Password: "short pass"})
+	assert.ErrorIs(t, err, ErrWeakPassword)
+
+	err = service.ResetPassword(context.Background(), &dto.ResetPasswordRequest{Token: resetToken, Password: "new long password"})
+	assert.NoError(t, err)
+	assert.NoError(t, crypto.CheckPassword("new long password", user.Password))
+
+	// the link works only once, the password it was issued for has changed
+	err = service.ResetPassword(context.Background(), &dto.ResetPasswordRequest{Token: resetToken, Password: "another long password"})
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+
+	err = service.ResetPassword(context.Background(), &dto.ResetPasswordRequest{Token: "invalid", Password: "new long password"})
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestServiceForcePasswordResetWithoutMailer(t *testing.T) {
+	service := New(new(mockrepo.MockRepository), "secret", time.Hour)
+
+	err := service.ForcePasswordReset(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, ErrMailerNotConfigured)
+}
+
+func TestServiceLoginPasswordResetRequired(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("password123")
+	user := &models.User{
+		ID:                    uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		Email:                 "test@example.com",
+		Password:              hashedPassword,
+		PasswordResetRequired: true,
+	}
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+	mockRepo.On("GetLoginFailure", mock.Anything, mock.Anything).Return(nil, models.ErrNotFound)
+	service := New(mockRepo, "secret", time.Hour)
+
+	_, err := service.Login(context.Background(), &dto.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.ErrorIs(t, err, ErrPasswordResetRequired)
+}
+
+func TestServiceMagicLinkPasswordResetRequired(t *testing.T) {
+	user := &models.User{
+		ID:                    uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		Email:                 "test@example.com",
+		EmailVerified:         true,
+		PasswordResetRequired: true,
+	}
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+	mockRepo.On("CreateMagicLink", mock.Anything, mock.AnythingOfType("models.MagicLink")).Return(nil)
+	mockRepo.On("UseMagicLink", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(nil)
+	m := mailer.NewMemoryMailer()
+	service := New(mockRepo, "secret", time.Hour, WithMailer(m))
+
+	// a login without the password doesn't get around the reset
+	nonce, err := service.RequestMagicLink(context.Background(), &dto.MagicLinkRequest{Email: user.Email})
+	assert.NoError(t, err)
+	msg := sentMessage(t, m)
+	_, err = service.VerifyMagicLink(context.Background(), linkToken(t, msg.Body), nonce)
+	assert.ErrorIs(t, err, ErrPasswordResetRequired)
+	mockRepo.AssertNotCalled(t, "GetUserRoles", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// maxMetadataSize the maximum size of the profile metadata in bytes
+const maxMetadataSize = 4096
+
+var (
+	// ErrUserModified returned when the profile was updated after the version the update is based on
+	ErrUserModified = models.ErrUserModified
+
+	// ErrInvalidMetadata returned when the profile metadata isn't a JSON object or is too large
+	ErrInvalidMetadata = errors.New("metadata must be a JSON object of at most 4096 bytes")
+)
+
+// Profile returns the user's own profile
+func (s *Service) Profile(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	return s.User(ctx, userID)
+}
+
+// UpdateProfile changes the fields of the request present in the user's profile.
+// The version is the update time of the profile the changes are based on,
+// if the profile was updated since then ErrUserModified is returned.
+func (s *Service) UpdateProfile(ctx context.Context, userID uuid.UUID, version time.Time,
+	req *dto.UpdateProfileRequest) (*models.User, error) {
+	if err := forbidImpersonation(ctx); err != nil {
+		return nil, err
+	}
+
+	user, err := s.User(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.UpdatedAt.Equal(version) {
+		return nil, ErrUserModified
+	}
+
+	if req.Username != nil {
+		username := strings.TrimSpace(*req.Username)
+		if !strings.EqualFold(username, user.Username) && s.isReservedUsername(username) {
+			return nil, ErrUsernameReserved
+		}
+		user.Username = username
+	}
+	if req.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*req.DisplayName)
+	}
+	if req.Locale != nil {
+		user.Locale = *req.Locale
+	}
+	if req.Timezone != nil {
+		user.Timezone = *req.Timezone
+	}
+	if req.Metadata != nil {
+		if len(req.Metadata) > maxMetadataSize || !bytes.HasPrefix(bytes.TrimSpace(req.Metadata), []byte("{")) ||
+			!json.Valid(req.Metadata) {
+			return nil, ErrInvalidMetadata
+		}
+		user.Metadata = req.Metadata
+	}
+
+	if err := s.repo.UpdateProfile(ctx, user, version); err != nil {
+		return nil, fmt.Errorf("update profile: %w", err)
+	}
+	s.audit(ctx, models.AuditEvent{Type: models.AuditProfileUpdated, UserID: &user.ID})
+	return user, nil
+}
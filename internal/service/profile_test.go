@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+func TestServiceUpdateProfile(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	version := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	newUser := func() *models.User {
+		return &models.User{ID: userID, Username: "alex", UpdatedAt: version}
+	}
+	displayName, timezone := " Alex Fox ", "Europe/Berlin"
+
+	t.Run("success", func(t *testing.T) {
+		metadata := json.RawMessage(`{"theme":"dark"}`)
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(newUser(), nil)
+		mockRepo.On("UpdateProfile", mock.Anything, &models.User{
+			ID:          userID,
+			Username:    "alex",
+			DisplayName: "Alex Fox",
+			Timezone:    "Europe/Berlin",
+			Metadata:    metadata,
+			UpdatedAt:   version,
+		}, version).Return(nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		user, err := service.UpdateProfile(context.Background(), userID, version, &dto.UpdateProfileRequest{
+			DisplayName: &displayName,
+			Timezone:    &timezone,
+			Metadata:    metadata,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "Alex Fox", user.DisplayName)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("stale version", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(newUser(), nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		_, err := service.UpdateProfile(context.Background(), userID, version.Add(-time.Second),
+			&dto.UpdateProfileRequest{DisplayName: &displayName})
+		assert.ErrorIs(t, err, ErrUserModified)
+		mockRepo.AssertNotCalled(t, "UpdateProfile", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("concurrent update", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(newUser(), nil)
+		mockRepo.On("UpdateProfile", mock.Anything, mock.Anything, version).Return(models.ErrUserModified)
+		service := New(mockRepo, "secret", time.Hour)
+
+		_, err := service.UpdateProfile(context.Background(), userID, version, &dto.UpdateProfileRequest{DisplayName: &displayName})
+		assert.ErrorIs(t, err, ErrUserModified)
+	})
+
+	t.Run("reserved username", func(t *testing.T) {
+		username := "Admin"
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(newUser(), nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		_, err := service.UpdateProfile(context.Background(), userID, version, &dto.UpdateProfileRequest{Username: &username})
+		assert.ErrorIs(t, err, ErrUsernameReserved)
+	})
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		for _, metadata := range []string{`[1,2]`, `"text"`, `{"a":`, `{"a":"` + string(make([]byte, maxMetadataSize)) + `"}`} {
+			mockRepo := new(mockrepo.MockRepository)
+			mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(newUser(), nil)
+			service := New(mockRepo, "secret", time.Hour)
+
+			_, err := service.UpdateProfile(context.Background(), userID, version,
+				&dto.UpdateProfileRequest{Metadata: json.RawMessage(metadata)})
+			assert.ErrorIs(t, err, ErrInvalidMetadata)
+		}
+	})
+}
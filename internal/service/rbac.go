@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrRoleNotFound returned when the role doesn't exist or isn't assigned to the user
+	ErrRoleNotFound = errors.New("role not found")
+
+	// ErrRoleExists returned when a role with the name already exists
+	ErrRoleExists = models.ErrRoleExists
+
+	// ErrUnknownPermission returned when a role grants a permission missing from the catalogue
+	ErrUnknownPermission = errors.New("unknown permission")
+)
+
+// Roles returns the roles of the request's tenant with their permissions
+func (s *Service) Roles(ctx context.Context) ([]models.Role, error) {
+	roles, err := s.repo.ListRoles(ctx, s.tenantID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list roles: %w", err)
+	}
+	return roles, nil
+}
+
+// Permissions returns the catalogue of permissions
+func (s *Service) Permissions(ctx context.Context) ([]models.Permission, error) {
+	permissions, err := s.repo.ListPermissions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+// CreateRole creates a role with the permissions
+func (s *Service) CreateRole(ctx context.Context, req *dto.RoleRequest) (models.Role, error) {
+	role := models.Role{
+		TenantID:    s.tenantID(ctx),
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: uniqueSorted(req.Permissions),
+	}
+	if err := s.checkPermissions(ctx, role.Permissions); err != nil {
+		return models.Role{}, err
+	}
+
+	if err := s.repo.CreateRole(ctx, role); err != nil {
+		return models.Role{}, fmt.Errorf("create role: %w", err)
+	}
+	return role, nil
+}
+
+// UpdateRole replaces the description and the permissions of the role.
+// Tokens issued before keep the old permissions until they expire.
+func (s *Service) UpdateRole(ctx context.Context, name string, req *dto.UpdateRoleRequest) error {
+	role := models.Role{
+		TenantID:    s.tenantID(ctx),
+		Name:        name,
+		Description: req.Description,
+		Permissions: uniqueSorted(req.Permissions),
+	}
+	if err := s.checkPermissions(ctx, role.Permissions); err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdateRole(ctx, role); err != nil {
+		return roleError("update role", err)
+	}
+	return nil
+}
+
+// checkPermissions returns ErrUnknownPermission if a permission is missing from the catalogue.
+// The catalogue is shared by all tenants, so roles can't add to it.
+func (s *Service) checkPermissions(ctx context.Context, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	permissions, err := s.repo.ListPermissions(ctx)
+	if err != nil {
+		return fmt.Errorf("list permissions: %w", err)
+	}
+	for _, name := range names {
+		if !slices.ContainsFunc(permissions, func(p models.Permission) bool { return p.Name == name }) {
+			return fmt.Errorf("%w: %s", ErrUnknownPermission, name)
+		}
+	}
+	return nil
+}
+
+// DeleteRole deletes the role and removes it from the users
+func (s *Service) DeleteRole(ctx context.Context, name string) error {
+	if err := s.repo.DeleteRole(ctx, s.tenantID(ctx), name); err != nil {
+		return roleError("delete role", err)
+	}
+	return nil
+}
+
+// EnsureAdminRoles creates the admin role with all permissions in the tenants
+// which don't have it yet. The migrations create the default tenant's one.
+func (s *Service) EnsureAdminRoles(ctx context.Context) error {
+	permissions, err := s.repo.ListPermissions(ctx)
+	if err != nil {
+		return fmt.Errorf("list permissions: %w", err)
+	}
+	names := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		names = append(names, permission.Name)
+	}
+
+	for tenantID := range s.tenants {
+		err := s.repo.CreateRole(ctx, models.Role{
+			TenantID:    tenantID,
+			Name:        models.RoleAdmin,
+			Description: "Full access to the admin API",
+			Permissions: names,
+		})
+		if err != nil && !errors.Is(err, ErrRoleExists) {
+			return fmt.Errorf("create admin role of tenant %s: %w", tenantID, err)
+		}
+	}
+	return nil
+}
+
+// UserRoles returns the roles assigned to the user
+func (s *Service) UserRoles(ctx context.Context, userID uuid.UUID) ([]models.Role, error) {
+	if _, err := s.repo.GetUserByID(ctx, s.tenantID(ctx), userID); err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	roles, err := s.repo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user roles: %w", err)
+	}
+	return roles, nil
+}
+
+// AssignRole assigns the role to the user
+func (s *Service) AssignRole(ctx context.Context, userID uuid.UUID, role string) error {
+	if _, err := s.repo.GetUserByID(ctx, s.tenantID(ctx), userID); err != nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.repo.AssignRole(ctx, userID, role); err != nil {
+		return roleError("assign role", err)
+	}
+	return nil
+}
+
+// RevokeRole removes the role from the user
+func (s *Service) RevokeRole(ctx context.Context, userID uuid.UUID, role string) error {
+	if _, err := s.repo.GetUserByID(ctx, s.tenantID(ctx), userID); err != nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.repo.RevokeRole(ctx, s.tenantID(ctx), userID, role); err != nil {
+		return roleError("revoke role", err)
+	}
+	return nil
+}
+
+// roleError returns ErrRoleNotFound if the role or its assignment doesn't exist,
+// otherwise the error of the action
+func roleError(action string, err error) error {
+	if errors.Is(err, models.ErrNotFound) {
+		return ErrRoleNotFound
+	}
+	return fmt.Errorf("%s: %w", action, err)
+}
+
+// userAccess returns the names of the user's roles and all permissions they grant
+func (s *Service) userAccess(ctx context.Context, userID uuid.UUID) ([]string, []string, error) {
+	roles, err := s.repo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get user roles: %w", err)
+	}
+
+	names := make([]string, 0, len(roles))
+	var permissions []string
+	for _, role := range roles {
+		names = append(names, role.Name)
+		permissions = append(permissions, role.Permissions...)
+	}
+	return names, uniqueSorted(permissions), nil
+}
+
+func uniqueSorted(values []string) []string {
+	values = append([]string{}, values...)
+	slices.Sort(values)
+	return slices.Compact(values)
+}
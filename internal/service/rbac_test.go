@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+func TestServiceLoginAccessClaims(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("password123")
+	user := &models.User{
+		ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		Email:    "test@example.com",
+		Password: hashedPassword,
+	}
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+	mockRepo.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{}, nil)
+	mockRepo.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{
+		{Name: "admin", Permissions: []string{"users:read", "users:write"}},
+		{Name: "support", Permissions: []string{"users:read", "audit:read"}},
+	}, nil)
+	service := New(mockRepo, "secret", time.Hour)
+
+	resp, err := service.Login(context.Background(), &dto.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.NoError(t, err)
+
+	claims, err := token.ValidateToken(resp.Token, service.JwtSecret())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"admin", "support"}, token.StringsClaim(claims, "roles"))
+	assert.Equal(t, []string{"audit:read", "users:read", "users:write"}, token.StringsClaim(claims, "permissions"))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestServiceCreateRole(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		expected := models.Role{Name: "support", Permissions: []string{"audit:read", "users:read"}}
+		mockRepo.On("ListPermissions", mock.Anything).Return([]models.Permission{{Name: "audit:read"}, {Name: "users:read"}}, nil)
+		mockRepo.On("CreateRole", mock.Anything, expected).Return(nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		role, err := service.CreateRole(context.Background(), &dto.RoleRequest{
+			Name:        "support",
+			Permissions: []string{"users:read", "audit:read", "users:read"},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, role)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("unknown permission", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("ListPermissions", mock.Anything).Return([]models.Permission{{Name: "users:read"}}, nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		_, err := service.CreateRole(context.Background(), &dto.RoleRequest{Name: "support", Permissions: []string{"users:read", "users:reed"}})
+		assert.ErrorIs(t, err, ErrUnknownPermission)
+		err = service.UpdateRole(context.Background(), "support", &dto.UpdateRoleRequest{Permissions: []string{"users:reed"}})
+		assert.ErrorIs(t, err, ErrUnknownPermission)
+		mockRepo.AssertNotCalled(t, "CreateRole", mock.Anything, mock.Anything)
+		mockRepo.AssertNotCalled(t, "UpdateRole", mock.Anything, mock.Anything)
+	})
+
+	t.Run("role exists", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("CreateRole", mock.Anything, mock.AnythingOfType("models.Role")).Return(models.ErrRoleExists)
+		service := New(mockRepo, "secret", time.Hour)
+
+		_, err := service.CreateRole(context.Background(), &dto.RoleRequest{Name: "admin"})
+
+		assert.ErrorIs(t, err, ErrRoleExists)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestServiceTenantRoles(t *testing.T) {
+	acme := &models.Tenant{ID: "acme"}
+	ctx := ContextWithTenant(context.Background(), acme)
+
+	t.Run("create in the tenant", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("CreateRole", mock.Anything, mock.MatchedBy(func(r models.Role) bool {
+			return r.TenantID == "acme" && r.Name == "support"
+		})).Return(nil)
+		service := New(mockRepo, "secret", time.Hour, WithTenants(*acme))
+
+		_, err := service.CreateRole(ctx, &dto.RoleRequest{Name: "support"})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("delete in the tenant", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("DeleteRole", mock.Anything, "acme", "admin").Return(nil)
+		service := New(mockRepo, "secret", time.Hour, WithTenants(*acme))
+
+		assert.NoError(t, service.DeleteRole(ctx, "admin"))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("revoke from a user of another tenant", func(t *testing.T) {
+		userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "acme", userID).Return(nil, models.ErrNotFound)
+		service := New(mockRepo, "secret", time.Hour, WithTenants(*acme))
+
+		assert.ErrorIs(t, service.RevokeRole(ctx, userID, "admin"), ErrUserNotFound)
+		mockRepo.AssertNotCalled(t, "RevokeRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("revoke in the tenant", func(t *testing.T) {
+		userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "acme", userID).Return(&models.User{ID: userID, TenantID: "acme"}, nil)
+		mockRepo.On("RevokeRole", mock.Anything, "acme", userID, "admin").Return(nil)
+		service := New(mockRepo, "secret", time.Hour, WithTenants(*acme))
+
+		assert.NoError(t, service.RevokeRole(ctx, userID, "admin"))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("ensure admin roles", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("ListPermissions", mock.Anything).
+			Return([]models.Permission{{Name: "audit:read"}, {Name: "users:read"}}, nil)
+		mockRepo.On("CreateRole", mock.Anything, models.Role{TenantID: "acme", Name: models.RoleAdmin,
+			Description: "Full access to the admin API", Permissions: []string{"audit:read", "users:read"}}).
+			Return(models.ErrRoleExists)
+		service := New(mockRepo, "secret", time.Hour, WithTenants(*acme))
+
+		assert.NoError(t, service.EnsureAdminRoles(context.Background()))
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestServiceAssignRole(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+	tests := []struct {
+		name        string
+		mockSetup   func(*mockrepo.MockRepository)
+		expectedErr error
+	}{
+		{
+			name: "success",
+			mockSetup: func(mr *mockrepo.MockRepository) {
+				mr.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID}, nil)
+				mr.On("AssignRole", mock.Anything, userID, "admin").Return(nil)
+			},
+		},
+		{
+			name: "user not found",
+			mockSetup: func(mr *mockrepo.MockRepository) {
+				mr.On("GetUserByID", mock.Anything, "", userID).Return(nil, errUserNotFound)
+			},
+			expectedErr: ErrUserNotFound,
+		},
+		{
+			name: "role not found",
+			mockSetup: func(mr *mockrepo.MockRepository) {
+				mr.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID}, nil)
+				mr.On("AssignRole", mock.Anything, userID, "admin").Return(models.ErrNotFound)
+			},
+			expectedErr: ErrRoleNotFound,
+		},
+		{
+			name: "database error",
+			mockSetup: func(mr *mockrepo.MockRepository) {
+				mr.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID}, nil)
+				mr.On("AssignRole", mock.Anything, userID, "admin").Return(assert.AnError)
+			},
+			expectedErr: assert.AnError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			service := New(mockRepo, "secret", time.Hour)
+
+			err := service.AssignRole(context.Background(), userID, "admin")
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceRoleErrors(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("UpdateRole", mock.Anything, mock.AnythingOfType("models.Role")).Return(models.ErrNotFound)
+		mockRepo.On("DeleteRole", mock.Anything, "", "editor").Return(models.ErrNotFound)
+		service := New(mockRepo, "secret", time.Hour)
+
+		err := service.UpdateRole(context.Background(), "editor", &dto.UpdateRoleRequest{})
+		assert.ErrorIs(t, err, ErrRoleNotFound)
+		err = service.DeleteRole(context.Background(), "editor")
+		assert.ErrorIs(t, err, ErrRoleNotFound)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("UpdateRole", mock.Anything, mock.AnythingOfType("models.Role")).Return(assert.AnError)
+		mockRepo.On("DeleteRole", mock.Anything, "", "editor").Return(assert.AnError)
+		service := New(mockRepo, "secret", time.Hour)
+
+		err := service.UpdateRole(context.Background(), "editor", &dto.UpdateRoleRequest{})
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.NotErrorIs(t, err, ErrRoleNotFound)
+		err = service.DeleteRole(context.Background(), "editor")
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.NotErrorIs(t, err, ErrRoleNotFound)
+	})
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/google/uuid"
+)
+
+// RegistrationMode defines who can register
+type RegistrationMode int
+
+const (
+	// RegistrationOpen allows anyone to register
+	RegistrationOpen RegistrationMode = iota
+
+	// RegistrationInviteOnly allows only users with a registration invite to register
+	RegistrationInviteOnly
+
+	// RegistrationDomainRestricted allows only emails of the allowed domains
+	// to register, unless the user has an invite
+	RegistrationDomainRestricted
+)
+
+// defaultInviteExpiry the lifetime of registration invites without an expiration time
+const defaultInviteExpiry = 7 * 24 * time.Hour
+
+var (
+	// ErrInviteRequired returned when registering without an invite if registration is invite-only
+	ErrInviteRequired = errors.New("registration requires an invite")
+
+	// ErrInvalidInvite returned when the registration invite is unknown, used, expired,
+	// issued in another tenant or bound to another email
+	ErrInvalidInvite = models.ErrInvalidInvite
+
+	// ErrInviteNotFound returned when the tenant has no registration invite with the ID
+	ErrInviteNotFound = errors.New("invite not found")
+
+	// ErrEmailDomainNotAllowed returned when the email domain isn't allowed to register
+	ErrEmailDomainNotAllowed = errors.New("email domain is not allowed")
+
+	// ErrDisposableEmail returned when the email belongs to a disposable email service
+	ErrDisposableEmail = errors.New("disposable email addresses are not allowed")
+)
+
+// LoadDomainList reads the file with one domain per line. Empty lines
+// and lines starting with '#' are skipped.
+func LoadDomainList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open domain list: %w", err)
+	}
+	defer file.Close()
+
+	var domains []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, strings.ToLower(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read domain list: %w", err)
+	}
+	return domains, nil
+}
+
+// checkRegistration returns the invite of the registration request, nil without an invite,
+// or the error if the registration isn't allowed
+func (s *Service) checkRegistration(ctx context.Context, req *dto.RegisterRequest) (*models.RegistrationInvite, error) {
+	email := normalizeEmail(req.Email)
+	if req.InviteToken == "" {
+		if s.registrationMode == RegistrationInviteOnly {
+			return nil, ErrInviteRequired
+		}
+		return nil, s.checkEmailDomain(email)
+	}
+
+	invite, err := s.repo.GetRegistrationInviteByHash(ctx, crypto.HashToken(req.InviteToken))
+	if err != nil || invite.TenantID != s.tenantID(ctx) || invite.UsedAt != nil ||
+		!invite.ExpiresAt.After(time.Now()) || (invite.Email != "" && invite.Email != email) {
+		return nil, ErrInvalidInvite
+	}
+	return invite, nil
+}
+
+// checkEmailDomain checks that the email domain is allowed if registration is
+// domain-restricted and isn't a disposable email domain or its subdomain
+func (s *Service) checkEmailDomain(email string) error {
+	domain := email[strings.LastIndex(email, "@")+1:]
+	if s.registrationMode == RegistrationDomainRestricted && !slices.Contains(s.allowedDomains, domain) {
+		return ErrEmailDomainNotAllowed
+	}
+
+	for parent := domain; parent != ""; _, parent, _ = strings.Cut(parent, ".") {
+		if _, ok := s.disposableDomains[parent]; ok {
+			return ErrDisposableEmail
+		}
+	}
+	return nil
+}
+
+// CreateInvite creates a single-use registration invite. The roles must exist,
+// the organization role defaults to member. The token is returned only here,
+// only its hash is stored.
+func (s *Service) CreateInvite(ctx context.Context, createdBy uuid.UUID, req *dto.CreateInviteRequest) (*dto.InviteCreated, error) {
+	expiresAt := time.Now().Add(defaultInviteExpiry)
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			return nil, ErrInvalidExpiry
+		}
+		expiresAt = *req.ExpiresAt
+	}
+
+	roles := uniqueSorted(req.Roles)
+	if len(roles) > 0 {
+		existing, err := s.repo.ListRoles(ctx, s.tenantID(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("list roles: %w", err)
+		}
+		for _, role := range roles {
+			if !slices.ContainsFunc(existing, func(r models.Role) bool { return r.Name == role }) {
+				return nil, fmt.Errorf("%w: %s", ErrRoleNotFound, role)
+			}
+		}
+	}
+
+	orgRole := ""
+	if req.OrgID != nil {
+		// the organization must belong to the tenant the invite is created in
+		_, err := s.repo.GetOrganization(ctx, s.tenantID(ctx), *req.OrgID)
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, ErrOrgNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get organization: %w", err)
+		}
+		orgRole = req.OrgRole
+		if orgRole == "" {
+			orgRole = models.OrgRoleMember
+		}
+	}
+
+	token, err := crypto.GenerateRandomString(32)
+	if err != nil {
+		return nil, fmt.Errorf("generate invite token: %w", err)
+	}
+
+	invite := models.RegistrationInvite{
+		ID:        uuid.New(),
+		TenantID:  s.tenantID(ctx),
+		TokenHash: crypto.HashToken(token),
+		Email:     normalizeEmail(req.Email),
+		Roles:     roles,
+		OrgID:     req.OrgID,
+		OrgRole:   orgRole,
+		CreatedBy: &createdBy,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.CreateRegistrationInvite(ctx, invite); err != nil {
+		return nil, fmt.Errorf("create invite: %w", err)
+	}
+
+	s.audit(ctx, models.AuditEvent{Type: models.AuditInviteCreated, UserID: &createdBy, Identifier: invite.Email})
+	return &dto.InviteCreated{
+		RegistrationInvite: invite,
+		Token:              token,
+		URL:                s.publicURL(ctx) + "/register?invite=" + url.QueryEscape(token),
+	}, nil
+}
+
+// Invites returns the registration invites of the tenant
+func (s *Service) Invites(ctx context.Context) ([]models.RegistrationInvite, error) {
+	invites, err := s.repo.ListRegistrationInvites(ctx, s.tenantID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list invites: %w", err)
+	}
+	return invites, nil
+}
+
+// RevokeInvite deletes the registration invite, so it can't be used
+func (s *Service) RevokeInvite(ctx context.Context, id uuid.UUID) error {
+	if err := s.repo.DeleteRegistrationInvite(ctx, s.tenantID(ctx), id); err != nil {
+		return ErrInviteNotFound
+	}
+	s.audit(ctx, models.AuditEvent{Type: models.AuditInviteRevoked})
+	return nil
+}
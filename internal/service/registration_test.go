@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+func TestServiceRegisterModes(t *testing.T) {
+	validInvite := &models.RegistrationInvite{
+		ID:        uuid.MustParse("00000000-0000-0000-0000-000000000002"),
+		Email:     "invited@example.com",
+		Roles:     []string{"editor"},
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	usedAt := time.Now()
+
+	tests := []struct {
+		name        string
+		opts        []Option
+		req         dto.RegisterRequest
+		mockSetup   func(*mockrepo.MockRepository)
+		expectedErr error
+	}{
+		{
+			name:        "invite required",
+			opts:        []Option{WithRegistrationMode(RegistrationInviteOnly)},
+			req:         dto.RegisterRequest{Username: "alex", Email: "alex@example.com", Password: "password123"},
+			mockSetup:   func(m *mockrepo.MockRepository) {},
+			expectedErr: ErrInviteRequired,
+		},
+		{
+			name: "invite",
+			opts: []Option{WithRegistrationMode(RegistrationInviteOnly)},
+			req:  dto.RegisterRequest{Username: "alex", Email: "Invited@example.com", Password: "password123", InviteToken: "token"},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetRegistrationInviteByHash", mock.Anything, crypto.HashToken("token")).Return(validInvite, nil)
+				m.On("CreateInvitedUser", mock.Anything, mock.MatchedBy(func(user models.User) bool {
+					return user.Email == "invited@example.com"
+				}), *validInvite).Return(models.User{ID: uuid.New()}, nil)
+			},
+		},
+		{
+			name: "invite for another email",
+			req:  dto.RegisterRequest{Username: "alex", Email: "alex@example.com", Password: "password123", InviteToken: "token"},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetRegistrationInviteByHash", mock.Anything, crypto.HashToken("token")).Return(validInvite, nil)
+			},
+			expectedErr: ErrInvalidInvite,
+		},
+		{
+			name: "used invite",
+			req:  dto.RegisterRequest{Username: "alex", Email: "alex@example.com", Password: "password123", InviteToken: "token"},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetRegistrationInviteByHash", mock.Anything, crypto.HashToken("token")).
+					Return(&models.RegistrationInvite{ExpiresAt: time.Now().Add(time.Hour), UsedAt: &usedAt}, nil)
+			},
+			expectedErr: ErrInvalidInvite,
+		},
+		{
+			name: "unknown invite",
+			req:  dto.RegisterRequest{Username: "alex", Email: "alex@example.com", Password: "password123", InviteToken: "token"},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("GetRegistrationInviteByHash", mock.Anything, crypto.HashToken("token")).
+					Return(nil, models.ErrNotFound)
+			},
+			expectedErr: ErrInvalidInvite,
+		},
+		{
+			name: "allowed domain",
+			opts: []Option{WithRegistrationMode(RegistrationDomainRestricted, "Example.com")},
+			req:  dto.RegisterRequest{Username: "alex", Email: "alex@example.com", Password: "password123"},
+			mockSetup: func(m *mockrepo.MockRepository) {
+				m.On("CreateUser", mock.Anything, mock.AnythingOfType("*models.User")).Return(nil)
+			},
+		},
+		{
+			name:        "domain not allowed",
+			opts:        []Option{WithRegistrationMode(RegistrationDomainRestricted, "example.com")},
+			req:         dto.RegisterRequest{Username: "alex", Email: "alex@mail.example.com", Password: "password123"},
+			mockSetup:   func(m *mockrepo.MockRepository) {},
+			expectedErr: ErrEmailDomainNotAllowed,
+		},
+		{
+			name:        "disposable subdomain",
+			opts:        []Option{WithDisposableEmailDomains("mailinator.com")},
+			req:         dto.RegisterRequest{Username: "alex", Email: "alex@eu.Mailinator.com", Password: "password123"},
+			mockSetup:   func(m *mockrepo.MockRepository) {},
+			expectedErr: ErrDisposableEmail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			tt.mockSetup(mockRepo)
+			sink := &memorySink{}
+			service := New(mockRepo, "secret", time.Hour, append(tt.opts, WithAuditSink(sink))...)
+
+			_, err := service.Register(context.Background(), &tt.req)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Empty(t, sink.events)
+			} else {
+				assert.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceCreateInvite(t *testing.T) {
+	adminID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	orgID := uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	past := time.Now().Add(-time.Hour)
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("ListRoles", mock.Anything, "").Return([]models.Role{{Name: "editor"}}, nil)
+		mockRepo.On("GetOrganization", mock.Anything, "", orgID).Return(&models.Organization{ID: orgID}, nil)
+		mockRepo.On("CreateRegistrationInvite", mock.Anything, mock.MatchedBy(func(invite models.RegistrationInvite) bool {
+			return invite.Email == "new@example.com" && invite.OrgRole == models.OrgRoleMember &&
+				*invite.CreatedBy == adminID && len(invite.TokenHash) == 64
+		})).Return(nil)
+		sink := &memorySink{}
+		service := New(mockRepo, "secret", time.Hour, WithBaseURL("https://auth.example.com"), WithAuditSink(sink))
+
+		created, err := service.CreateInvite(context.Background(), adminID, &dto.CreateInviteRequest{
+			Email: "New@example.com",
+			Roles: []string{"editor", "editor"},
+			OrgID: &orgID,
+		})
+		if assert.NoError(t, err) {
+			assert.Equal(t, []string{"editor"}, created.Roles)
+			assert.Equal(t, crypto.HashToken(created.Token), created.TokenHash)
+			assert.Equal(t, "https://auth.example.com/register?invite="+created.Token, created.URL)
+			assert.WithinDuration(t, time.Now().Add(defaultInviteExpiry), created.ExpiresAt, time.Minute)
+		}
+		if assert.Len(t, sink.events, 1) {
+			assert.Equal(t, models.AuditInviteCreated, sink.events[0].Type)
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("unknown role", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("ListRoles", mock.Anything, "").Return([]models.Role{{Name: "editor"}}, nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		_, err := service.CreateInvite(context.Background(), adminID, &dto.CreateInviteRequest{Roles: []string{"owner"}})
+		assert.ErrorIs(t, err, ErrRoleNotFound)
+	})
+
+	t.Run("unknown organization", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetOrganization", mock.Anything, "", orgID).Return(nil, models.ErrNotFound)
+		service := New(mockRepo, "secret", time.Hour)
+
+		_, err := service.CreateInvite(context.Background(), adminID, &dto.CreateInviteRequest{OrgID: &orgID})
+		assert.ErrorIs(t, err, ErrOrgNotFound)
+	})
+
+	t.Run("organization of another tenant", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetOrganization", mock.Anything, "acme", orgID).Return(nil, models.ErrNotFound)
+		service := New(mockRepo, "secret", time.Hour, WithTenants(models.Tenant{ID: "acme"}))
+
+		ctx := ContextWithTenant(context.Background(), &models.Tenant{ID: "acme"})
+		_, err := service.CreateInvite(ctx, adminID, &dto.CreateInviteRequest{OrgID: &orgID})
+		assert.ErrorIs(t, err, ErrOrgNotFound)
+		mockRepo.AssertNotCalled(t, "CreateRegistrationInvite", mock.Anything, mock.Anything)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetOrganization", mock.Anything, "", orgID).Return(nil, assert.AnError)
+		service := New(mockRepo, "secret", time.Hour)
+
+		_, err := service.CreateInvite(context.Background(), adminID, &dto.CreateInviteRequest{OrgID: &orgID})
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.NotErrorIs(t, err, ErrOrgNotFound)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		service := New(new(mockrepo.MockRepository), "secret", time.Hour)
+
+		_, err := service.CreateInvite(context.Background(), adminID, &dto.CreateInviteRequest{ExpiresAt: &past})
+		assert.ErrorIs(t, err, ErrInvalidExpiry)
+	})
+}
+
+func TestLoadDomainList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disposable.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("# disposable domains\nMailinator.com\n\n  10minutemail.com \n"), 0o600))
+
+	domains, err := LoadDomainList(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"mailinator.com", "10minutemail.com"}, domains)
+
+	_, err = LoadDomainList(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
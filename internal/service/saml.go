@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/saml"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/google/uuid"
+)
+
+const (
+	samlStatePurpose = "saml_request"
+
+	// SAMLLoginExpiry the time the user has to sign in with the identity provider
+	SAMLLoginExpiry = 10 * time.Minute
+)
+
+// ErrInvalidSAMLLogin returned when the response doesn't belong to a login started
+// in the browser, or the assertion is invalid or has already been used
+var ErrInvalidSAMLLogin = errors.New("invalid or expired saml login")
+
+// samlIdP returns the identity provider of the request's tenant
+func (s *Service) samlIdP(ctx context.Context, name string) (*saml.IdentityProvider, error) {
+	idp, ok := s.samlIdPs[s.tenantID(ctx)][name]
+	if !ok {
+		return nil, ErrProviderNotFound
+	}
+	return idp, nil
+}
+
+// samlServiceProvider returns our entity ID and assertion consumer service
+// at the identity provider
+func (s *Service) samlServiceProvider(ctx context.Context, name string) saml.ServiceProvider {
+	base := s.publicURL(ctx) + "/login/saml/" + name
+	return saml.ServiceProvider{EntityID: base + "/metadata", ACSURL: base + "/acs"}
+}
+
+// SAMLProviders returns the names of the identity providers of the request's tenant
+func (s *Service) SAMLProviders(ctx context.Context) []string {
+	idps := s.samlIdPs[s.tenantID(ctx)]
+	names := make([]string, 0, len(idps))
+	for name := range idps {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SAMLMetadata returns the service provider metadata to register at the identity provider
+func (s *Service) SAMLMetadata(ctx context.Context, name string) ([]byte, error) {
+	if _, err := s.samlIdP(ctx, name); err != nil {
+		return nil, err
+	}
+
+	metadata, err := saml.Metadata(s.samlServiceProvider(ctx, name))
+	if err != nil {
+		return nil, fmt.Errorf("build metadata: %w", err)
+	}
+	return metadata, nil
+}
+
+// StartSAMLLogin returns the URL of the identity provider's login page with the
+// authentication request and a state which must be stored in the browser and
+// presented with the response
+func (s *Service) StartSAMLLogin(ctx context.Context, name string) (authURL, state string, err error) {
+	idp, err := s.samlIdP(ctx, name)
+	if err != nil {
+		return "", "", err
+	}
+
+	requestID, err := saml.NewRequestID()
+	if err != nil {
+		return "", "", err
+	}
+	state, err = token.GenerateLinkToken(samlStatePurpose, map[string]any{
+		"idp":        name,
+		"request_id": requestID,
+	}, s.SigningKey(ctx), SAMLLoginExpiry)
+	if err != nil {
+		return "", "", fmt.Errorf("generate state: %w", err)
+	}
+
+	authURL, err = idp.AuthnRequestURL(s.samlServiceProvider(ctx, name), requestID, time.Now())
+	if err != nil {
+		return "", "", fmt.Errorf("build authn request: %w", err)
+	}
+	return authURL, state, nil
+}
+
+// CompleteSAMLLogin validates the identity provider's response to the request of the state
+// and performs user authentication. Each assertion logs in once.
+func (s *Service) CompleteSAMLLogin(ctx context.Context, name, samlResponse, state string) (*dto.Response, error) {
+	idp, err := s.samlIdP(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := token.ValidateLinkToken(state, samlStatePurpose, s.SigningKey(ctx))
+	if err != nil || claims["idp"] != name {
+		return nil, ErrInvalidSAMLLogin
+	}
+	requestID, _ := claims["request_id"].(string)
+
+	method := "saml:" + name
+	assertion, err := idp.ParseResponse(s.samlServiceProvider(ctx, name), samlResponse, requestID, time.Now())
+	if err != nil {
+		s.auditLoginFailed(ctx, nil, "", method, models.AuditReasonInvalidExternal)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSAMLLogin, err)
+	}
+	if err := s.repo.UseSAMLAssertion(ctx, s.tenantID(ctx), assertion.ID, assertion.ExpiresAt); err != nil {
+		s.auditLoginFailed(ctx, nil, "", method, models.AuditReasonInvalidExternal)
+		return nil, ErrInvalidSAMLLogin
+	}
+
+	user, err := s.externalUser(ctx, ExternalIdentity{
+		Provider:    method,
+		Subject:     assertion.NameID,
+		Email:       assertion.Email,
+		Username:    assertion.Username,
+		DisplayName: assertion.DisplayName,
+	}, idp.TrustsEmail())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(ctx, user, []string{token.AMRFederated}, uuid.Nil)
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/saml"
+	"github.com/AlexFox86/auth-service/internal/pkg/saml/samltest"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+// samlLogin starts the login with the stub IdP, signs the user in there
+// and returns the response and the state
+func samlLogin(t *testing.T, ctx context.Context, service *Service, stub *samltest.IdP, nameID string, attributes map[string]string) (samlResponse, state string) {
+	t.Helper()
+
+	authURL, state, err := service.StartSAMLLogin(ctx, "okta")
+	assert.NoError(t, err)
+	samlResponse, err = stub.Respond(authURL, nameID, attributes)
+	assert.NoError(t, err)
+	return samlResponse, state
+}
+
+func TestServiceSAMLLogin(t *testing.T) {
+	stub, err := samltest.NewIdP()
+	assert.NoError(t, err)
+	config := stub.Config("okta", "")
+	untrusted, err := saml.NewIdentityProvider(config)
+	assert.NoError(t, err)
+	config.TrustEmail = true
+	idp, err := saml.NewIdentityProvider(config)
+	assert.NoError(t, err)
+
+	user := &models.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), Username: "alex", Email: "alex@example.com"}
+	attributes := map[string]string{"email": "Alex@example.com", "displayName": "Alex Fox"}
+
+	t.Run("new user", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("UseSAMLAssertion", mock.Anything, "", mock.Anything, mock.Anything).Return(nil)
+		mockRepo.On("GetUserIdentity", mock.Anything, "", "saml:okta", "00u1abcd").Return(nil, models.ErrNotFound)
+		mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(nil, models.ErrNotFound)
+		mockRepo.On("CreateUser", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+			return u.Username == "alex" && u.Email == user.Email && u.EmailVerified && u.DisplayName == "Alex Fox"
+		})).Return(nil)
+		mockRepo.On("CreateUserIdentity", mock.Anything, mock.MatchedBy(func(i models.UserIdentity) bool {
+			return i.Provider == "saml:okta" && i.Subject == "00u1abcd"
+		})).Return(nil)
+		mockRepo.On("GetMFAMethods", mock.Anything, mock.Anything).Return([]models.MFAMethod{}, nil)
+		mockRepo.On("GetUserRoles", mock.Anything, mock.Anything).Return([]models.Role{}, nil)
+		service := New(mockRepo, "secret", time.Hour, WithBaseURL("https://auth.example.com"), WithSAMLIdPs(idp))
+
+		samlResponse, state := samlLogin(t, context.Background(), service, stub, "00u1abcd", attributes)
+		resp, err := service.CompleteSAMLLogin(context.Background(), "okta", samlResponse, state)
+		if assert.NoError(t, err) {
+			claims, err := token.ValidateToken(resp.Token, service.JwtSecret())
+			assert.NoError(t, err)
+			assert.Equal(t, []string{token.AMRFederated}, token.StringsClaim(claims, "amr"))
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("linked identity", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("UseSAMLAssertion", mock.Anything, "", mock.Anything, mock.Anything).Return(nil)
+		mockRepo.On("GetUserIdentity", mock.Anything, "", "saml:okta", "00u1abcd").Return(&models.UserIdentity{UserID: user.ID}, nil)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+		mockRepo.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{}, nil)
+		mockRepo.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{}, nil)
+		service := New(mockRepo, "secret", time.Hour, WithBaseURL("https://auth.example.com"), WithSAMLIdPs(idp))
+
+		samlResponse, state := samlLogin(t, context.Background(), service, stub, "00u1abcd", nil)
+		resp, err := service.CompleteSAMLLogin(context.Background(), "okta", samlResponse, state)
+		if assert.NoError(t, err) {
+			claims, err := token.ValidateToken(resp.Token, service.JwtSecret())
+			assert.NoError(t, err)
+			assert.Equal(t, user.ID.String(), claims["sub"])
+		}
+	})
+
+	t.Run("untrusted email", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("UseSAMLAssertion", mock.Anything, "", mock.Anything, mock.Anything).Return(nil)
+		mockRepo.On("GetUserIdentity", mock.Anything, "", "saml:okta", "00u1abcd").Return(nil, models.ErrNotFound)
+		service := New(mockRepo, "secret", time.Hour, WithBaseURL("https://auth.example.com"), WithSAMLIdPs(untrusted))
+
+		// any user of the IdP could claim a local account with the email attribute
+		samlResponse, state := samlLogin(t, context.Background(), service, stub, "00u1abcd", attributes)
+		_, err := service.CompleteSAMLLogin(context.Background(), "okta", samlResponse, state)
+		assert.ErrorIs(t, err, ErrExternalEmailNotVerified)
+		mockRepo.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("replayed assertion", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("UseSAMLAssertion", mock.Anything, "", mock.Anything, mock.Anything).Return(errors.New("saml assertion already used"))
+		sink := &memorySink{}
+		service := New(mockRepo, "secret", time.Hour, WithBaseURL("https://auth.example.com"),
+			WithSAMLIdPs(idp), WithAuditSink(sink))
+
+		samlResponse, state := samlLogin(t, context.Background(), service, stub, "00u1abcd", attributes)
+		_, err := service.CompleteSAMLLogin(context.Background(), "okta", samlResponse, state)
+		assert.ErrorIs(t, err, ErrInvalidSAMLLogin)
+		mockRepo.AssertNotCalled(t, "GetUserIdentity", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		if assert.Len(t, sink.events, 1) {
+			assert.Equal(t, models.AuditLoginFailed, sink.events[0].Type)
+			assert.Equal(t, "saml:okta", sink.events[0].Method)
+		}
+	})
+
+	t.Run("state of another login", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		service := New(mockRepo, "secret", time.Hour, WithBaseURL("https://auth.example.com"), WithSAMLIdPs(idp))
+
+		samlResponse, _ := samlLogin(t, context.Background(), service, stub, "00u1abcd", attributes)
+		_, otherState, err := service.StartSAMLLogin(context.Background(), "okta")
+		assert.NoError(t, err)
+		_, err = service.CompleteSAMLLogin(context.Background(), "okta", samlResponse, otherState)
+		assert.ErrorIs(t, err, ErrInvalidSAMLLogin)
+		mockRepo.AssertNotCalled(t, "UseSAMLAssertion", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("idp of another tenant", func(t *testing.T) {
+		tenantIdP, err := saml.NewIdentityProvider(stub.Config("okta", "acme"))
+		assert.NoError(t, err)
+		service := New(new(mockrepo.MockRepository), "secret", time.Hour, WithBaseURL("https://auth.example.com"),
+			WithSAMLIdPs(tenantIdP))
+
+		_, _, err = service.StartSAMLLogin(context.Background(), "okta")
+		assert.ErrorIs(t, err, ErrProviderNotFound)
+
+		ctx := ContextWithTenant(context.Background(), &models.Tenant{ID: "acme"})
+		metadata, err := service.SAMLMetadata(ctx, "okta")
+		if assert.NoError(t, err) {
+			assert.Contains(t, string(metadata), `entityID="https://auth.example.com/t/acme/login/saml/okta/metadata"`)
+		}
+		assert.Equal(t, []string{"okta"}, service.SAMLProviders(ctx))
+	})
+}
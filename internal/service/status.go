@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrAccountSuspended returned when a suspended user authenticates
+	ErrAccountSuspended = errors.New("account suspended")
+
+	// ErrAccountPending returned when a user whose account isn't activated yet authenticates
+	ErrAccountPending = errors.New("account pending")
+
+	// ErrInvalidStatusUntil returned when the status of a user is set until a past time,
+	// or the active status is set with a time
+	ErrInvalidStatusUntil = errors.New("until must be in the future and is only allowed for inactive statuses")
+)
+
+// accountBlocked returns the audit reason and the error if the user's account
+// can't be used, the error is nil otherwise
+func accountBlocked(user *models.User) (string, error) {
+	if user.DeletedAt != nil {
+		return models.AuditReasonDeleted, ErrAccountDeleted
+	}
+
+	switch user.CurrentStatus(time.Now()) {
+	case models.UserStatusDisabled:
+		return models.AuditReasonDisabled, ErrAccountDisabled
+	case models.UserStatusSuspended:
+		return models.AuditReasonSuspended, ErrAccountSuspended
+	case models.UserStatusPending:
+		return models.AuditReasonPending, ErrAccountPending
+	}
+	return "", nil
+}
+
+// CheckAccount returns the error if the user doesn't exist or the user's account
+// can't be used, so tokens issued before stop working
+func (s *Service) CheckAccount(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.User(ctx, userID)
+	if err != nil {
+		return err
+	}
+	_, err = accountBlocked(user)
+	return err
+}
+
+// SetUserStatus changes the status of the user. A status other than active
+// can be set until a time, the user is active again after it.
+func (s *Service) SetUserStatus(ctx context.Context, userID uuid.UUID, req *dto.SetUserStatusRequest) (*models.User, error) {
+	if req.Until != nil && (req.Status == models.UserStatusActive || !req.Until.After(time.Now())) {
+		return nil, ErrInvalidStatusUntil
+	}
+
+	user, err := s.User(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setStatus(ctx, user, req.Status, req.Reason, req.Until); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// setStatus saves the status of the user if it differs from the current one
+// and records the change
+func (s *Service) setStatus(ctx context.Context, user *models.User, status, reason string, until *time.Time) error {
+	sameUntil := user.StatusUntil == nil && until == nil ||
+		user.StatusUntil != nil && until != nil && user.StatusUntil.Equal(*until)
+	if user.Status == status && user.StatusReason == reason && sameUntil {
+		return nil
+	}
+
+	if err := s.repo.SetUserStatus(ctx, user.ID, status, reason, until); err != nil {
+		return fmt.Errorf("set user status: %w", err)
+	}
+	user.Status, user.StatusReason, user.StatusUntil = status, reason, until
+	s.audit(ctx, models.AuditEvent{Type: models.AuditStatusChanged, UserID: &user.ID, Reason: status})
+	return nil
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+func TestServiceSetUserStatus(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	until := time.Now().Add(24 * time.Hour)
+
+	t.Run("suspend", func(t *testing.T) {
+		sink := &memorySink{}
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID, Status: models.UserStatusActive}, nil)
+		mockRepo.On("SetUserStatus", mock.Anything, userID, models.UserStatusSuspended, "spam", &until).Return(nil)
+		service := New(mockRepo, "secret", time.Hour, WithAuditSink(sink))
+
+		user, err := service.SetUserStatus(context.Background(), userID,
+			&dto.SetUserStatusRequest{Status: models.UserStatusSuspended, Reason: "spam", Until: &until})
+		assert.NoError(t, err)
+		assert.Equal(t, models.UserStatusSuspended, user.Status)
+		mockRepo.AssertExpectations(t)
+		if assert.Len(t, sink.events, 1) {
+			assert.Equal(t, models.AuditStatusChanged, sink.events[0].Type)
+			assert.Equal(t, models.UserStatusSuspended, sink.events[0].Reason)
+		}
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		sink := &memorySink{}
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).
+			Return(&models.User{ID: userID, Status: models.UserStatusSuspended, StatusReason: "spam", StatusUntil: &until}, nil)
+		service := New(mockRepo, "secret", time.Hour, WithAuditSink(sink))
+
+		_, err := service.SetUserStatus(context.Background(), userID,
+			&dto.SetUserStatusRequest{Status: models.UserStatusSuspended, Reason: "spam", Until: &until})
+		assert.NoError(t, err)
+		assert.Empty(t, sink.events)
+		mockRepo.AssertNotCalled(t, "SetUserStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("invalid until", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour)
+		service := New(new(mockrepo.MockRepository), "secret", time.Hour)
+
+		for _, req := range []dto.SetUserStatusRequest{
+			{Status: models.UserStatusSuspended, Until: &past},
+			{Status: models.UserStatusActive, Until: &until},
+		} {
+			_, err := service.SetUserStatus(context.Background(), userID, &req)
+			assert.ErrorIs(t, err, ErrInvalidStatusUntil)
+		}
+	})
+}
+
+func TestServiceCheckAccount(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		user        *models.User
+		expectedErr error
+	}{
+		{name: "active", user: &models.User{Status: models.UserStatusActive}},
+		{name: "suspension ended", user: &models.User{Status: models.UserStatusSuspended, StatusUntil: &past}},
+		{
+			name:        "suspended",
+			user:        &models.User{Status: models.UserStatusSuspended, StatusUntil: &future},
+			expectedErr: ErrAccountSuspended,
+		},
+		{name: "pending", user: &models.User{Status: models.UserStatusPending}, expectedErr: ErrAccountPending},
+		{name: "disabled", user: &models.User{Status: models.UserStatusDisabled}, expectedErr: ErrAccountDisabled},
+		{name: "deleted", user: &models.User{Status: models.UserStatusActive, DeletedAt: &past}, expectedErr: ErrAccountDeleted},
+		{name: "not found", expectedErr: ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockrepo.MockRepository)
+			if tt.user != nil {
+				mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(tt.user, nil)
+			} else {
+				mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(nil, ErrUserNotFound)
+			}
+			service := New(mockRepo, "secret", time.Hour)
+
+			err := service.CheckAccount(context.Background(), userID)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
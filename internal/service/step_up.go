@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/google/uuid"
+)
+
+const defaultStepUpExpiry = 5 * time.Minute
+
+// StepUpExpiry returns the lifetime of elevated tokens
+func (s *Service) StepUpExpiry() time.Duration {
+	if s.stepUpExpiry == 0 {
+		return defaultStepUpExpiry
+	}
+	return s.stepUpExpiry
+}
+
+// StepUp re-authenticates the user and issues a short-lived elevated token
+// with a fresh auth_time. A non-nil orgID keeps the token scoped to the organization
+// the user must still be a member of. If the user has MFA methods, the second factor
+// is required and the elevated token is issued by VerifyLoginOTP.
+func (s *Service) StepUp(ctx context.Context, userID, orgID uuid.UUID, req *dto.StepUpRequest) (*dto.Response, error) {
+	if err := forbidImpersonation(ctx); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, s.tenantID(ctx), userID)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	// the password is guessed against the same lockout as on login
+	key := loginFailureKey(s.tenantID(ctx), user, "")
+	failure, err := s.checkLoginLock(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := crypto.CheckPassword(req.Password, user.Password); err != nil {
+		s.recordLoginFailure(ctx, key, user)
+		return nil, ErrInvalidCredentials
+	}
+	s.resetLoginFailures(ctx, key, failure)
+
+	membership, err := s.orgMembership(ctx, orgID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	amr := []string{token.AMRPassword}
+	challenge, err := s.mfaChallenge(ctx, user, amr, true, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if challenge != nil {
+		return nil, &MFARequiredError{Challenge: *challenge}
+	}
+
+	resp, err := s.newResponse(ctx, user, amr, s.StepUpExpiry(), membership)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, models.AuditEvent{Type: models.AuditStepUp, UserID: &user.ID, Method: token.AMRPassword})
+	return resp, nil
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+func TestServiceStepUp(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("password123")
+	user := &models.User{
+		ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		Username: "testuser",
+		Password: hashedPassword,
+	}
+
+	t.Run("without mfa", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+		mockRepo.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{}, nil)
+		mockRepo.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{}, nil)
+
+		service := New(mockRepo, "secret", time.Hour, WithStepUpExpiry(time.Minute))
+		resp, err := service.StepUp(context.Background(), user.ID, uuid.Nil, &dto.StepUpRequest{Password: "password123"})
+		assert.NoError(t, err)
+
+		claims, err := token.ValidateToken(resp.Token, service.JwtSecret())
+		assert.NoError(t, err)
+		assert.Equal(t, token.ACRSingleFactor, claims["acr"])
+		assert.InDelta(t, time.Now().Add(time.Minute).Unix(), claims["exp"], 2)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+
+		service := New(mockRepo, "secret", time.Hour)
+		resp, err := service.StepUp(context.Background(), user.ID, uuid.Nil, &dto.StepUpRequest{Password: "wrongpassword"})
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		assert.Nil(t, resp)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		userKey := "user:" + user.ID.String()
+		until := time.Now().Add(time.Minute)
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+		mockRepo.On("GetLoginFailure", mock.Anything, userKey).
+			Return(&models.LoginFailure{Key: userKey, Attempts: 10, LockedUntil: &until}, nil)
+
+		service := New(mockRepo, "secret", time.Hour, WithLockoutPolicy(DefaultLockoutPolicy()))
+		_, err := service.StepUp(context.Background(), user.ID, uuid.Nil, &dto.StepUpRequest{Password: "password123"})
+		assert.ErrorIs(t, err, ErrAccountLocked)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("wrong password counts as a failed login", func(t *testing.T) {
+		userKey := "user:" + user.ID.String()
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+		mockRepo.On("GetLoginFailure", mock.Anything, userKey).Return(&models.LoginFailure{Key: userKey, Attempts: 9}, nil)
+		mockRepo.On("IncrementLoginFailures", mock.Anything, userKey, mock.AnythingOfType("time.Time")).Return(10, nil)
+		mockRepo.On("LockLogin", mock.Anything, userKey, mock.AnythingOfType("time.Time")).Return(nil)
+
+		service := New(mockRepo, "secret", time.Hour, WithLockoutPolicy(DefaultLockoutPolicy()))
+		_, err := service.StepUp(context.Background(), user.ID, uuid.Nil, &dto.StepUpRequest{Password: "wrongpassword"})
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("with mfa", func(t *testing.T) {
+		method := models.MFAMethod{ID: uuid.New(), UserID: user.ID, Type: models.MFAMethodSMS, Confirmed: true}
+		codeHash, _ := crypto.HashPassword("123456")
+		otp := &models.OTPCode{ID: uuid.New(), CodeHash: codeHash}
+
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+		mockRepo.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{method}, nil)
+		mockRepo.On("GetActiveOTPCode", mock.Anything, method.ID).Return(otp, nil)
+		mockRepo.On("IncrementOTPAttempts", mock.Anything, otp.ID, maxOTPAttempts).Return(nil)
+		mockRepo.On("UseOTPCode", mock.Anything, otp.ID).Return(nil)
+		mockRepo.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{}, nil)
+
+		service := New(mockRepo, "secret", time.Hour)
+		_, err := service.StepUp(context.Background(), user.ID, uuid.Nil, &dto.StepUpRequest{Password: "password123"})
+
+		var mfaErr *MFARequiredError
+		if !assert.True(t, errors.As(err, &mfaErr)) {
+			return
+		}
+
+		resp, err := service.VerifyLoginOTP(context.Background(), &dto.MFAVerifyRequest{
+			ChallengeToken: mfaErr.Challenge.ChallengeToken,
+			MethodID:       method.ID,
+			Code:           "123456",
+		})
+		assert.NoError(t, err)
+
+		claims, err := token.ValidateToken(resp.Token, service.JwtSecret())
+		assert.NoError(t, err)
+		assert.Equal(t, token.ACRMultiFactor, claims["acr"])
+		assert.Equal(t, []string{token.AMRPassword, token.AMROTP, token.AMRSMS, token.AMRMFA}, token.StringsClaim(claims, "amr"))
+		assert.InDelta(t, time.Now().Add(defaultStepUpExpiry).Unix(), claims["exp"], 2)
+		mockRepo.AssertExpectations(t)
+	})
+	t.Run("keeps the organization", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+		mockRepo.On("GetMembership", mock.Anything, testOrgID, user.ID).
+			Return(member(user.ID, models.OrgRoleAdmin), nil)
+		mockRepo.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{}, nil)
+		mockRepo.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{}, nil)
+
+		service := New(mockRepo, "secret", time.Hour)
+		resp, err := service.StepUp(context.Background(), user.ID, testOrgID, &dto.StepUpRequest{Password: "password123"})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		claims, err := token.ValidateToken(resp.Token, service.JwtSecret())
+		assert.NoError(t, err)
+		assert.Equal(t, testOrgID.String(), claims["org_id"])
+		assert.Equal(t, models.OrgRoleAdmin, claims["org_role"])
+	})
+
+	t.Run("no longer a member", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", user.ID).Return(user, nil)
+		mockRepo.On("GetMembership", mock.Anything, testOrgID, user.ID).Return(nil, models.ErrNotFound)
+
+		service := New(mockRepo, "secret", time.Hour)
+		_, err := service.StepUp(context.Background(), user.ID, testOrgID, &dto.StepUpRequest{Password: "password123"})
+		assert.ErrorIs(t, err, ErrNotOrgMember)
+	})
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/AlexFox86/auth-service/internal/models"
+)
+
+// ErrWeakPassword returned when the password doesn't satisfy the password policy
+var ErrWeakPassword = errors.New("password doesn't satisfy the policy")
+
+type tenantContextKey struct{}
+
+// ContextWithTenant returns the context the service operates in the tenant with
+func ContextWithTenant(ctx context.Context, tenant *models.Tenant) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant of the request, nil for the default tenant
+func TenantFromContext(ctx context.Context) *models.Tenant {
+	tenant, _ := ctx.Value(tenantContextKey{}).(*models.Tenant)
+	return tenant
+}
+
+// MultiTenant reports whether tenants are configured
+func (s *Service) MultiTenant() bool {
+	return len(s.tenants) > 0
+}
+
+// TenantByID returns the configured tenant with the ID
+func (s *Service) TenantByID(id string) (*models.Tenant, bool) {
+	tenant, ok := s.tenants[id]
+	return tenant, ok
+}
+
+// TenantByHost returns the configured tenant served on the host
+func (s *Service) TenantByHost(host string) (*models.Tenant, bool) {
+	id, ok := s.tenantHosts[strings.ToLower(host)]
+	if !ok {
+		return nil, false
+	}
+	return s.TenantByID(id)
+}
+
+// SigningKey returns the key tokens of the request's tenant are signed with.
+// Tenants without their own secret get a key derived from the service secret,
+// so tokens of one tenant are never valid in another.
+func (s *Service) SigningKey(ctx context.Context) []byte {
+	tenant := TenantFromContext(ctx)
+	if tenant == nil || tenant.ID == models.DefaultTenantID {
+		return s.jwtSecret
+	}
+	if tenant.JWTSecret != "" {
+		return []byte(tenant.JWTSecret)
+	}
+
+	mac := hmac.New(sha256.New, s.jwtSecret)
+	mac.Write([]byte("tenant:" + tenant.ID))
+	return mac.Sum(nil)
+}
+
+// accessTokenExpiry returns the lifetime of access tokens of the request's tenant
+func (s *Service) accessTokenExpiry(ctx context.Context) time.Duration {
+	if tenant := TenantFromContext(ctx); tenant != nil && tenant.TokenExpiry > 0 {
+		return tenant.TokenExpiry
+	}
+	return s.tokenExpiry
+}
+
+// publicURL returns the base URL of the links sent to the users of the request's tenant.
+// Tenants without their own URL are served under the /t/{tenant} path prefix.
+func (s *Service) publicURL(ctx context.Context) string {
+	tenant := TenantFromContext(ctx)
+	if tenant == nil || tenant.ID == models.DefaultTenantID {
+		return s.baseURL
+	}
+	if tenant.BaseURL != "" {
+		return tenant.BaseURL
+	}
+	return s.baseURL + "/t/" + tenant.ID
+}
+
+// appURL returns the base URL of the web app pages the links sent to the users
+// of the request's tenant open. Tenants are under the /t/{tenant} path prefix, without
+// the web app the links fall back to the public URL.
+func (s *Service) appURL(ctx context.Context) string {
+	if s.frontendURL == "" {
+		return s.publicURL(ctx)
+	}
+	tenant := TenantFromContext(ctx)
+	if tenant == nil || tenant.ID == models.DefaultTenantID {
+		return s.frontendURL
+	}
+	return s.frontendURL + "/t/" + tenant.ID
+}
+
+// tenantID returns the ID of the request's tenant
+func (s *Service) tenantID(ctx context.Context) string {
+	if tenant := TenantFromContext(ctx); tenant != nil {
+		return tenant.ID
+	}
+	return models.DefaultTenantID
+}
+
+// checkPasswordPolicy checks the new password against the policy of the request's tenant.
+// The zero fields of the tenant's policy fall back to the policy of the service.
+func (s *Service) checkPasswordPolicy(ctx context.Context, password string) error {
+	policy := s.passwordPolicy
+	if tenant := TenantFromContext(ctx); tenant != nil {
+		if tenant.PasswordPolicy.MinLength > 0 {
+			policy.MinLength = tenant.PasswordPolicy.MinLength
+		}
+		policy.RequireUppercase = policy.RequireUppercase || tenant.PasswordPolicy.RequireUppercase
+		policy.RequireLowercase = policy.RequireLowercase || tenant.PasswordPolicy.RequireLowercase
+		policy.RequireDigit = policy.RequireDigit || tenant.PasswordPolicy.RequireDigit
+		policy.RequireSymbol = policy.RequireSymbol || tenant.PasswordPolicy.RequireSymbol
+	}
+
+	if len([]rune(password)) < policy.MinLength {
+		return fmt.Errorf("%w: at least %d characters required", ErrWeakPassword, policy.MinLength)
+	}
+
+	checks := []struct {
+		required bool
+		is       func(rune) bool
+		name     string
+	}{
+		{policy.RequireUppercase, unicode.IsUpper, "an uppercase letter"},
+		{policy.RequireLowercase, unicode.IsLower, "a lowercase letter"},
+		{policy.RequireDigit, unicode.IsDigit, "a digit"},
+		{policy.RequireSymbol, isSymbol, "a symbol"},
+	}
+	for _, check := range checks {
+		if check.required && !strings.ContainsFunc(password, check.is) {
+			return fmt.Errorf("%w: %s required", ErrWeakPassword, check.name)
+		}
+	}
+	return nil
+}
+
+func isSymbol(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r)
+}
+
+// tenantConfig the tenant in the tenants file
+type tenantConfig struct {
+	ID             string                `json:"id"`
+	Name           string                `json:"name"`
+	Hosts          []string              `json:"hosts"`
+	BaseURL        string                `json:"base_url"`
+	JWTSecret      string                `json:"jwt_secret"`
+	TokenExpiry    string                `json:"token_expiry"`
+	PasswordPolicy models.PasswordPolicy `json:"password_policy"`
+}
+
+// LoadTenants reads the tenants from the JSON file with an array of tenants:
+//
+//	[{"id": "acme", "hosts": ["auth.acme.com"], "base_url": "https://auth.acme.com",
+//	  "jwt_secret": "...", "token_expiry": "1h",
+//	  "password_policy": {"min_length": 12, "require_digit": true}}]
+func LoadTenants(path string) ([]models.Tenant, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read tenants file: %w", err)
+	}
+
+	var configs []tenantConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("parse tenants file: %w", err)
+	}
+
+	tenants := make([]models.Tenant, 0, len(configs))
+	seen := make(map[string]bool)
+	for _, config := range configs {
+		if config.ID == models.DefaultTenantID || strings.ContainsAny(config.ID, "/ ") {
+			return nil, fmt.Errorf("invalid tenant id %q", config.ID)
+		}
+		if seen[config.ID] {
+			return nil, fmt.Errorf("duplicate tenant id %q", config.ID)
+		}
+		seen[config.ID] = true
+
+		tenant := models.Tenant{
+			ID:             config.ID,
+			Name:           config.Name,
+			Hosts:          config.Hosts,
+			BaseURL:        strings.TrimSuffix(config.BaseURL, "/"),
+			JWTSecret:      config.JWTSecret,
+			PasswordPolicy: config.PasswordPolicy,
+		}
+		if config.TokenExpiry != "" {
+			if tenant.TokenExpiry, err = time.ParseDuration(config.TokenExpiry); err != nil {
+				return nil, fmt.Errorf("tenant %s token expiry: %w", config.ID, err)
+			}
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, nil
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/token"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+func TestLoadTenants(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		path := filepath.Join(t.TempDir(), "tenants.json")
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	t.Run("success", func(t *testing.T) {
+		path := write(t, `[
+			{"id": "acme", "hosts": ["auth.acme.com"], "base_url": "https://auth.acme.com/",
+			 "jwt_secret": "acme-secret", "token_expiry": "15m",
+			 "password_policy": {"min_length": 12, "require_digit": true}},
+			{"id": "globex"}
+		]`)
+
+		tenants, err := LoadTenants(path)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Tenant{
+			{
+				ID:             "acme",
+				Hosts:          []string{"auth.acme.com"},
+				BaseURL:        "https://auth.acme.com",
+				JWTSecret:      "acme-secret",
+				TokenExpiry:    15 * time.Minute,
+				PasswordPolicy: models.PasswordPolicy{MinLength: 12, RequireDigit: true},
+			},
+			{ID: "globex"},
+		}, tenants)
+	})
+
+	for name, content := range map[string]string{
+		"missing id":   `[{"name": "Acme"}]`,
+		"duplicate id": `[{"id": "acme"}, {"id": "acme"}]`,
+		"invalid id":   `[{"id": "acme/eu"}]`,
+		"invalid ttl":  `[{"id": "acme", "token_expiry": "soon"}]`,
+		"invalid json": `{"id": "acme"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadTenants(write(t, content))
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadTenants(filepath.Join(t.TempDir(), "missing.json"))
+		assert.Error(t, err)
+	})
+}
+
+func TestServiceTenants(t *testing.T) {
+	acme := models.Tenant{ID: "acme", Hosts: []string{"Auth.Acme.com"}, TokenExpiry: 15 * time.Minute}
+	globex := models.Tenant{ID: "globex", JWTSecret: "globex-secret"}
+	service := New(new(mockrepo.MockRepository), "secret", time.Hour, WithTenants(acme, globex))
+
+	assert.True(t, service.MultiTenant())
+	tenant, ok := service.TenantByHost("auth.acme.com")
+	assert.True(t, ok)
+	assert.Equal(t, "acme", tenant.ID)
+	_, ok = service.TenantByHost("auth.example.com")
+	assert.False(t, ok)
+	_, ok = service.TenantByID("initech")
+	assert.False(t, ok)
+
+	acmeCtx := ContextWithTenant(context.Background(), &acme)
+	globexCtx := ContextWithTenant(context.Background(), &globex)
+
+	// every tenant has its own key, the default tenant keeps the service secret
+	assert.Equal(t, []byte("secret"), service.SigningKey(context.Background()))
+	assert.Equal(t, []byte("globex-secret"), service.SigningKey(globexCtx))
+	assert.NotEqual(t, service.SigningKey(context.Background()), service.SigningKey(acmeCtx))
+	assert.Equal(t, service.SigningKey(acmeCtx), service.SigningKey(ContextWithTenant(context.Background(), &acme)))
+
+	assert.Equal(t, 15*time.Minute, service.accessTokenExpiry(acmeCtx))
+	assert.Equal(t, time.Hour, service.accessTokenExpiry(globexCtx))
+
+	// links open the web app, which isn't served by the tenant's own URL
+	app := New(new(mockrepo.MockRepository), "secret", time.Hour, WithTenants(acme, globex),
+		WithBaseURL("https://auth.example.com"), WithFrontendURL("https://app.example.com"))
+	assert.Equal(t, "https://app.example.com", app.appURL(context.Background()))
+	assert.Equal(t, "https://app.example.com/t/acme", app.appURL(acmeCtx))
+	assert.Equal(t, "https://auth.example.com/t/acme", app.publicURL(acmeCtx))
+	withoutApp := New(new(mockrepo.MockRepository), "secret", time.Hour, WithBaseURL("https://auth.example.com"))
+	assert.Equal(t, "https://auth.example.com", withoutApp.appURL(context.Background()))
+
+	assert.False(t, New(new(mockrepo.MockRepository), "secret", time.Hour).MultiTenant())
+}
+
+func TestServiceLoginTenant(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("password123")
+	acme := models.Tenant{ID: "acme", TokenExpiry: 15 * time.Minute}
+	ctx := ContextWithTenant(context.Background(), &acme)
+	user := &models.User{
+		ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		TenantID: acme.ID,
+		Email:    "test@example.com",
+		Password: hashedPassword,
+	}
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByEmail", mock.Anything, acme.ID, user.Email).Return(user, nil)
+	mockRepo.On("GetMFAMethods", mock.Anything, user.ID).Return([]models.MFAMethod{}, nil)
+	mockRepo.On("GetUserRoles", mock.Anything, user.ID).Return([]models.Role{}, nil)
+	service := New(mockRepo, "secret", time.Hour, WithTenants(acme))
+
+	resp, err := service.Login(ctx, &dto.LoginRequest{Email: user.Email, Password: "password123"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the token is valid only in the tenant it was issued in
+	_, err = token.ValidateToken(resp.Token, service.JwtSecret())
+	assert.Error(t, err)
+
+	claims, err := token.ValidateToken(resp.Token, service.SigningKey(ctx))
+	assert.NoError(t, err)
+	assert.Equal(t, acme.ID, claims["tid"])
+	assert.InDelta(t, float64(time.Now().Add(15*time.Minute).Unix()), claims["exp"], 5)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestServiceRegisterTenant(t *testing.T) {
+	acme := models.Tenant{ID: "acme", PasswordPolicy: models.PasswordPolicy{MinLength: 12, RequireDigit: true}}
+	ctx := ContextWithTenant(context.Background(), &acme)
+
+	t.Run("weak password", func(t *testing.T) {
+		service := New(new(mockrepo.MockRepository), "secret", time.Hour, WithTenants(acme))
+
+		_, err := service.Register(ctx, &dto.RegisterRequest{
+			Username: "testuser",
+			Email:    "test@example.com",
+			Password: "password123",
+		})
+		assert.ErrorIs(t, err, ErrWeakPassword)
+	})
+
+	t.Run("created in the tenant", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("CreateUser", mock.Anything, mock.MatchedBy(func(user *models.User) bool {
+			return user.TenantID == acme.ID
+		})).Return(nil)
+		service := New(mockRepo, "secret", time.Hour, WithTenants(acme))
+
+		_, err := service.Register(ctx, &dto.RegisterRequest{
+			Username: "testuser",
+			Email:    "test@example.com",
+			Password: "long password 123",
+		})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestCheckPasswordPolicy(t *testing.T) {
+	strict := models.PasswordPolicy{
+		MinLength:        10,
+		RequireUppercase: true,
+		RequireLowercase: true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+	}
+
+	tests := []struct {
+		name     string
+		policy   models.PasswordPolicy
+		password string
+		valid    bool
+	}{
+		{name: "no policy", password: "a", valid: true},
+		{name: "strict", policy: strict, password: "Passw0rd!long", valid: true},
+		{name: "too short", policy: strict, password: "Pa0!", valid: false},
+		{name: "no uppercase", policy: strict, password: "passw0rd!long", valid: false},
+		{name: "no lowercase", policy: strict, password: "PASSW0RD!LONG", valid: false},
+		{name: "no digit", policy: strict, password: "Password!long", valid: false},
+		{name: "no symbol", policy: strict, password: "Passw0rdlong", valid: false},
+		{name: "length in characters", policy: models.PasswordPolicy{MinLength: 4}, password: "пар", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := New(new(mockrepo.MockRepository), "secret", time.Hour, WithPasswordPolicy(tt.policy))
+
+			err := service.checkPasswordPolicy(context.Background(), tt.password)
+
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrWeakPassword)
+			}
+		})
+	}
+}
+
+func TestCheckTenantPasswordPolicy(t *testing.T) {
+	service := New(new(mockrepo.MockRepository), "secret", time.Hour,
+		WithPasswordPolicy(models.PasswordPolicy{MinLength: 10, RequireDigit: true}))
+
+	tests := []struct {
+		name     string
+		policy   models.PasswordPolicy
+		password string
+		valid    bool
+	}{
+		{name: "service policy", password: "password12", valid: true},
+		{name: "service length", policy: models.PasswordPolicy{RequireSymbol: true}, password: "pass12!", valid: false},
+		{name: "service digit", policy: models.PasswordPolicy{RequireSymbol: true}, password: "password!!", valid: false},
+		{name: "tenant symbol", policy: models.PasswordPolicy{RequireSymbol: true}, password: "password12", valid: false},
+		{name: "both", policy: models.PasswordPolicy{RequireSymbol: true}, password: "password1!", valid: true},
+		{name: "tenant length", policy: models.PasswordPolicy{MinLength: 6}, password: "pass12", valid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := ContextWithTenant(context.Background(), &models.Tenant{ID: "acme", PasswordPolicy: tt.policy})
+
+			err := service.checkPasswordPolicy(ctx, tt.password)
+
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrWeakPassword)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultUsersLimit = 50
+	maxUsersLimit     = 500
+)
+
+var (
+	// ErrAccountDisabled returned when a disabled user authenticates
+	ErrAccountDisabled = errors.New("account disabled")
+
+	// ErrInvalidSort returned when users are sorted by an unknown field
+	ErrInvalidSort = errors.New("invalid sort field")
+)
+
+// Users returns a page of the users of the tenant matching the filter
+// with the number of all matching users
+func (s *Service) Users(ctx context.Context, filter models.UserFilter) (*dto.UserPage, error) {
+	switch filter.Sort {
+	case "", models.UserSortCreatedAt, models.UserSortUsername, models.UserSortEmail:
+	default:
+		return nil, ErrInvalidSort
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultUsersLimit
+	}
+	filter.Limit = min(filter.Limit, maxUsersLimit)
+	filter.TenantID = s.tenantID(ctx)
+	filter.Search = strings.TrimSpace(filter.Search)
+
+	users, err := s.repo.ListUsers(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	total, err := s.repo.CountUsers(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("count users: %w", err)
+	}
+
+	return &dto.UserPage{Users: users, Total: total, Limit: filter.Limit, Offset: filter.Offset}, nil
+}
+
+// User returns the user of the tenant
+func (s *Service) User(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	user, err := s.repo.GetUserByID(ctx, s.tenantID(ctx), userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// UpdateUser changes the username and email of the user. A changed email
+// is not verified, the user is sent the verification link if a mailer is configured.
+func (s *Service) UpdateUser(ctx context.Context, userID uuid.UUID, req *dto.UpdateUserRequest) (*models.User, error) {
+	user, err := s.User(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	emailChanged := false
+	if req.Username != nil {
+		user.Username = strings.TrimSpace(*req.Username)
+	}
+	if req.Email != nil {
+		if email := normalizeEmail(*req.Email); !strings.EqualFold(email, user.Email) {
+			user.Email = email
+			user.EmailVerified = false
+			emailChanged = true
+		}
+	}
+
+	if err := s.repo.UpdateUser(ctx, user); err != nil {
+		return nil, fmt.Errorf("update user: %w", err)
+	}
+	s.audit(ctx, models.AuditEvent{Type: models.AuditUserUpdated, UserID: &user.ID})
+
+	if emailChanged && s.mailer != nil {
+		// the user can request the link again, so a failure doesn't fail the update
+		if err := s.sendEmailVerification(ctx, user); err != nil {
+			log.Printf("send email verification to user %s: %v", user.ID, err)
+		}
+	}
+	return user, nil
+}
+
+// DisableUser blocks the user until an admin enables it again
+func (s *Service) DisableUser(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.User(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.setStatus(ctx, user, models.UserStatusDisabled, "", nil)
+}
+
+// EnableUser makes the user active again
+func (s *Service) EnableUser(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.User(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.setStatus(ctx, user, models.UserStatusActive, "", nil)
+}
+
+// DeleteUser deletes the user with the user's MFA methods, API keys,
+// roles and memberships
+func (s *Service) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.User(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteUser(ctx, user.ID); err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+	s.audit(ctx, models.AuditEvent{Type: models.AuditUserDeleted, UserID: &user.ID, Identifier: user.Username})
+	return nil
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlexFox86/auth-service/internal/delivery/dto"
+	"github.com/AlexFox86/auth-service/internal/models"
+	"github.com/AlexFox86/auth-service/internal/pkg/crypto"
+	"github.com/AlexFox86/auth-service/internal/pkg/mailer"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mockrepo "github.com/AlexFox86/auth-service/internal/repository/mock"
+)
+
+func TestServiceUsers(t *testing.T) {
+	users := []models.User{{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), Username: "alex"}}
+
+	t.Run("defaults", func(t *testing.T) {
+		expected := models.UserFilter{Search: "alex", Sort: models.UserSortUsername, Desc: true, Limit: defaultUsersLimit}
+
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("ListUsers", mock.Anything, expected).Return(users, nil)
+		mockRepo.On("CountUsers", mock.Anything, expected).Return(21, nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		page, err := service.Users(context.Background(), models.UserFilter{Search: " alex ", Sort: "username", Desc: true})
+		assert.NoError(t, err)
+		assert.Equal(t, &dto.UserPage{Users: users, Total: 21, Limit: defaultUsersLimit}, page)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("limit capped", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("ListUsers", mock.Anything, mock.MatchedBy(func(filter models.UserFilter) bool {
+			return filter.Limit == maxUsersLimit && filter.Offset == 1000
+		})).Return(users, nil)
+		mockRepo.On("CountUsers", mock.Anything, mock.Anything).Return(1, nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		_, err := service.Users(context.Background(), models.UserFilter{Limit: 10000, Offset: 1000})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("invalid sort", func(t *testing.T) {
+		service := New(new(mockrepo.MockRepository), "secret", time.Hour)
+
+		_, err := service.Users(context.Background(), models.UserFilter{Sort: "password"})
+		assert.ErrorIs(t, err, ErrInvalidSort)
+	})
+}
+
+func TestServiceUpdateUser(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	newUser := func() *models.User {
+		return &models.User{ID: userID, Username: "alex", Email: "alex@example.com", EmailVerified: true}
+	}
+	username, email := "alexander", " Alex.New@Example.com"
+
+	t.Run("email changed", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		m := mailer.NewMemoryMailer()
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(newUser(), nil)
+		mockRepo.On("UpdateUser", mock.Anything, &models.User{
+			ID:       userID,
+			Username: "alexander",
+			Email:    "alex.new@example.com",
+		}).Return(nil)
+		service := New(mockRepo, "secret", time.Hour, WithMailer(m))
+
+		user, err := service.UpdateUser(context.Background(), userID, &dto.UpdateUserRequest{Username: &username, Email: &email})
+		assert.NoError(t, err)
+		assert.False(t, user.EmailVerified)
+		mockRepo.AssertExpectations(t)
+
+		msg, ok := m.Last()
+		if assert.True(t, ok) {
+			assert.Equal(t, "alex.new@example.com", msg.To)
+		}
+	})
+
+	t.Run("same email keeps verification", func(t *testing.T) {
+		sameEmail := "ALEX@example.com"
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(newUser(), nil)
+		mockRepo.On("UpdateUser", mock.Anything, newUser()).Return(nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		user, err := service.UpdateUser(context.Background(), userID, &dto.UpdateUserRequest{Email: &sameEmail})
+		assert.NoError(t, err)
+		assert.True(t, user.EmailVerified)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("username taken", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(newUser(), nil)
+		mockRepo.On("UpdateUser", mock.Anything, mock.Anything).Return(models.ErrUsernameExists)
+		service := New(mockRepo, "secret", time.Hour)
+
+		_, err := service.UpdateUser(context.Background(), userID, &dto.UpdateUserRequest{Username: &username})
+		assert.ErrorIs(t, err, ErrUsernameExists)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(nil, models.ErrNotFound)
+		service := New(mockRepo, "secret", time.Hour)
+
+		_, err := service.UpdateUser(context.Background(), userID, &dto.UpdateUserRequest{Username: &username})
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestServiceDisableUser(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+	t.Run("disable", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID, Status: models.UserStatusActive}, nil)
+		mockRepo.On("SetUserStatus", mock.Anything, userID, models.UserStatusDisabled, "", (*time.Time)(nil)).Return(nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		assert.NoError(t, service.DisableUser(context.Background(), userID))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("already disabled", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID, Status: models.UserStatusDisabled}, nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		assert.NoError(t, service.DisableUser(context.Background(), userID))
+		mockRepo.AssertNotCalled(t, "SetUserStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("enable", func(t *testing.T) {
+		mockRepo := new(mockrepo.MockRepository)
+		mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID, Status: models.UserStatusDisabled}, nil)
+		mockRepo.On("SetUserStatus", mock.Anything, userID, models.UserStatusActive, "", (*time.Time)(nil)).Return(nil)
+		service := New(mockRepo, "secret", time.Hour)
+
+		assert.NoError(t, service.EnableUser(context.Background(), userID))
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestServiceLoginDisabled(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("password123")
+	user := &models.User{
+		ID:       uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		Email:    "test@example.com",
+		Password: hashedPassword,
+		Status:   models.UserStatusDisabled,
+	}
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByEmail", mock.Anything, "", user.Email).Return(user, nil)
+	mockRepo.On("GetLoginFailure", mock.Anything, mock.Anything).Return(nil, models.ErrNotFound)
+	service := New(mockRepo, "secret", time.Hour)
+
+	_, err := service.Login(context.Background(), &dto.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.ErrorIs(t, err, ErrAccountDisabled)
+	mockRepo.AssertNotCalled(t, "GetMFAMethods", mock.Anything, mock.Anything)
+}
+
+func TestServiceDeleteUser(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+	mockRepo := new(mockrepo.MockRepository)
+	mockRepo.On("GetUserByID", mock.Anything, "", userID).Return(&models.User{ID: userID}, nil)
+	mockRepo.On("DeleteUser", mock.Anything, userID).Return(nil)
+	service := New(mockRepo, "secret", time.Hour)
+
+	assert.NoError(t, service.DeleteUser(context.Background(), userID))
+	mockRepo.AssertExpectations(t)
+}
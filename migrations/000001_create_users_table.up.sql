@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS users (
+    id         UUID PRIMARY KEY,
+    username   TEXT        NOT NULL,
+    email      TEXT        NOT NULL UNIQUE,
+    password   TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS otp_codes;
+DROP TABLE IF EXISTS mfa_methods;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS mfa_methods (
+    id          UUID PRIMARY KEY,
+    user_id     UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    type        TEXT        NOT NULL,
+    destination TEXT        NOT NULL,
+    confirmed   BOOLEAN     NOT NULL DEFAULT FALSE,
+    created_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS mfa_methods_user_id_idx ON mfa_methods (user_id);
+
+CREATE TABLE IF NOT EXISTS otp_codes (
+    id         UUID PRIMARY KEY,
+    user_id    UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    method_id  UUID        NOT NULL REFERENCES mfa_methods (id) ON DELETE CASCADE,
+    code_hash  TEXT        NOT NULL,
+    attempts   INTEGER     NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS otp_codes_user_id_created_at_idx ON otp_codes (user_id, created_at);
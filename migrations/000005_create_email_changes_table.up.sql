@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS email_changes (
+    id           UUID PRIMARY KEY,
+    user_id      UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    old_email    TEXT        NOT NULL,
+    new_email    TEXT        NOT NULL,
+    status       TEXT        NOT NULL,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    confirmed_at TIMESTAMPTZ,
+    reverted_at  TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS email_changes_user_id_idx ON email_changes (user_id);
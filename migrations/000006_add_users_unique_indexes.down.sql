@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS users_username_key;
+DROP INDEX IF EXISTS users_email_key;
+
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
@@ -0,0 +1,24 @@
+-- accounts whose emails or usernames differ only in case or spaces must be merged by hand
+-- before the unique indexes can be created, the migration lists them instead of picking one
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(value, ', ') INTO duplicates FROM (
+        SELECT 'email ' || lower(trim(email)) AS value FROM users
+        GROUP BY lower(trim(email)) HAVING count(*) > 1
+        UNION ALL
+        SELECT 'username ' || lower(username) FROM users
+        GROUP BY lower(username) HAVING count(*) > 1
+    ) d;
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'users with duplicate emails or usernames: %', duplicates;
+    END IF;
+END $$;
+
+UPDATE users SET email = lower(trim(email));
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users (lower(email));
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_key ON users (lower(username));
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+    id         UUID PRIMARY KEY,
+    type       TEXT        NOT NULL,
+    user_id    UUID,
+    identifier TEXT        NOT NULL DEFAULT '',
+    method     TEXT        NOT NULL DEFAULT '',
+    reason     TEXT        NOT NULL DEFAULT '',
+    ip         TEXT        NOT NULL DEFAULT '',
+    user_agent TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_created_at_idx ON audit_events (created_at);
+CREATE INDEX IF NOT EXISTS audit_events_user_id_idx ON audit_events (user_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_events_type_idx ON audit_events (type, created_at);
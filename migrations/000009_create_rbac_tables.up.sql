@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS permissions (
+    name        TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS roles (
+    name        TEXT PRIMARY KEY,
+    description TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_name       TEXT NOT NULL REFERENCES roles (name) ON DELETE CASCADE,
+    permission_name TEXT NOT NULL REFERENCES permissions (name) ON DELETE CASCADE,
+    PRIMARY KEY (role_name, permission_name)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id   UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role_name TEXT NOT NULL REFERENCES roles (name) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_name)
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('users:read', 'Read users'),
+    ('users:write', 'Manage users'),
+    ('audit:read', 'Read the audit log'),
+    ('roles:manage', 'Manage roles and assign them to users')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO roles (name, description, created_at)
+VALUES ('admin', 'Full access to the admin API', now())
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_name, permission_name)
+SELECT 'admin', name FROM permissions
+ON CONFLICT DO NOTHING;
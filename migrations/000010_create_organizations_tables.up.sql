@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS organizations (
+    id         UUID PRIMARY KEY,
+    name       TEXT        NOT NULL,
+    slug       TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT organizations_slug_key UNIQUE (slug)
+);
+
+CREATE TABLE IF NOT EXISTS memberships (
+    org_id     UUID        NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id    UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role       TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS memberships_user_id_idx ON memberships (user_id);
+
+CREATE TABLE IF NOT EXISTS org_invitations (
+    id          UUID PRIMARY KEY,
+    org_id      UUID        NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    email       TEXT        NOT NULL,
+    role        TEXT        NOT NULL,
+    invited_by  UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    status      TEXT        NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS org_invitations_org_id_idx ON org_invitations (org_id);
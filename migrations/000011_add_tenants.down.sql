@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS audit_events_tenant_id_created_at_idx;
+
+ALTER TABLE audit_events DROP COLUMN IF EXISTS tenant_id;
+
+DROP INDEX IF EXISTS users_username_key;
+DROP INDEX IF EXISTS users_email_key;
+
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users (lower(email));
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_key ON users (lower(username));
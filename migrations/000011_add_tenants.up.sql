@@ -0,0 +1,11 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+
+DROP INDEX IF EXISTS users_email_key;
+DROP INDEX IF EXISTS users_username_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users (tenant_id, lower(email));
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_key ON users (tenant_id, lower(username));
+
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS audit_events_tenant_id_created_at_idx ON audit_events (tenant_id, created_at);
@@ -0,0 +1,7 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMPTZ;
+
+UPDATE users SET disabled_at = updated_at WHERE status = 'disabled';
+
+ALTER TABLE users DROP COLUMN IF EXISTS status_until;
+ALTER TABLE users DROP COLUMN IF EXISTS status_reason;
+ALTER TABLE users DROP COLUMN IF EXISTS status;
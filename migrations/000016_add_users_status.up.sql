@@ -0,0 +1,7 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_until TIMESTAMPTZ;
+
+UPDATE users SET status = 'disabled' WHERE disabled_at IS NOT NULL;
+
+ALTER TABLE users DROP COLUMN IF EXISTS disabled_at;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS registration_invites (
+    id         UUID PRIMARY KEY,
+    tenant_id  TEXT        NOT NULL DEFAULT '',
+    token_hash TEXT        NOT NULL,
+    email      TEXT        NOT NULL DEFAULT '',
+    roles      TEXT[]      NOT NULL DEFAULT '{}',
+    org_id     UUID REFERENCES organizations (id) ON DELETE CASCADE,
+    org_role   TEXT        NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users (id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_by    UUID REFERENCES users (id) ON DELETE SET NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT registration_invites_token_hash_key UNIQUE (token_hash)
+);
+
+CREATE INDEX IF NOT EXISTS registration_invites_tenant_id_idx ON registration_invites (tenant_id, created_at);
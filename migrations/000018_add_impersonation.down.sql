@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE name = 'users:impersonate';
+
+ALTER TABLE audit_events DROP COLUMN IF EXISTS actor_id;
@@ -0,0 +1,9 @@
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS actor_id UUID;
+
+INSERT INTO permissions (name, description)
+VALUES ('users:impersonate', 'Act as users to see what they see')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_name, permission_name)
+VALUES ('admin', 'users:impersonate')
+ON CONFLICT DO NOTHING;
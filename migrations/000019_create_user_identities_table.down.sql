@@ -0,0 +1,3 @@
+ALTER TABLE users ALTER COLUMN password DROP DEFAULT;
+
+DROP TABLE IF EXISTS user_identities;
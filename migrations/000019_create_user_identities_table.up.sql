@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS user_identities (
+    id         UUID PRIMARY KEY,
+    tenant_id  TEXT        NOT NULL DEFAULT '',
+    user_id    UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    provider   TEXT        NOT NULL,
+    subject    TEXT        NOT NULL,
+    email      TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT user_identities_provider_subject_key UNIQUE (tenant_id, provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities (user_id);
+
+-- users signing in only with external identities have no password
+ALTER TABLE users ALTER COLUMN password SET DEFAULT '';
@@ -0,0 +1,7 @@
+-- IDs of the SAML assertions logged in with, kept until they expire to reject replays
+CREATE TABLE IF NOT EXISTS saml_assertions (
+    tenant_id  TEXT        NOT NULL DEFAULT '',
+    id         TEXT        NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (tenant_id, id)
+);
@@ -0,0 +1,25 @@
+ALTER TABLE organizations DROP CONSTRAINT IF EXISTS organizations_slug_key;
+DELETE FROM organizations WHERE tenant_id <> '';
+ALTER TABLE organizations DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE organizations ADD CONSTRAINT organizations_slug_key UNIQUE (slug);
+
+ALTER TABLE user_roles DROP CONSTRAINT IF EXISTS user_roles_role_fkey;
+ALTER TABLE role_permissions DROP CONSTRAINT IF EXISTS role_permissions_role_fkey;
+
+DELETE FROM role_permissions WHERE tenant_id <> '';
+DELETE FROM roles WHERE tenant_id <> '';
+DELETE FROM user_roles ur WHERE NOT EXISTS (SELECT 1 FROM roles r WHERE r.name = ur.role_name);
+
+ALTER TABLE role_permissions DROP CONSTRAINT IF EXISTS role_permissions_pkey;
+ALTER TABLE role_permissions DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE role_permissions ADD CONSTRAINT role_permissions_pkey PRIMARY KEY (role_name, permission_name);
+ALTER TABLE user_roles DROP COLUMN IF EXISTS tenant_id;
+
+ALTER TABLE roles DROP CONSTRAINT IF EXISTS roles_pkey;
+ALTER TABLE roles DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE roles ADD CONSTRAINT roles_pkey PRIMARY KEY (name);
+
+ALTER TABLE role_permissions ADD CONSTRAINT role_permissions_role_name_fkey
+    FOREIGN KEY (role_name) REFERENCES roles (name) ON DELETE CASCADE;
+ALTER TABLE user_roles ADD CONSTRAINT user_roles_role_name_fkey
+    FOREIGN KEY (role_name) REFERENCES roles (name) ON DELETE CASCADE;
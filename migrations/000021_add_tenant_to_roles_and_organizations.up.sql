@@ -0,0 +1,38 @@
+-- roles and organizations belong to a tenant like their users
+ALTER TABLE role_permissions DROP CONSTRAINT IF EXISTS role_permissions_role_name_fkey;
+ALTER TABLE user_roles DROP CONSTRAINT IF EXISTS user_roles_role_name_fkey;
+
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE roles DROP CONSTRAINT IF EXISTS roles_pkey;
+ALTER TABLE roles ADD CONSTRAINT roles_pkey PRIMARY KEY (tenant_id, name);
+
+ALTER TABLE role_permissions ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE role_permissions DROP CONSTRAINT IF EXISTS role_permissions_pkey;
+ALTER TABLE role_permissions ADD CONSTRAINT role_permissions_pkey PRIMARY KEY (tenant_id, role_name, permission_name);
+
+ALTER TABLE user_roles ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+UPDATE user_roles ur SET tenant_id = u.tenant_id FROM users u WHERE u.id = ur.user_id;
+
+-- the roles assigned to the users of other tenants are copied to those tenants
+INSERT INTO roles (tenant_id, name, description, created_at)
+SELECT DISTINCT ur.tenant_id, r.name, r.description, r.created_at
+FROM user_roles ur
+JOIN roles r ON r.tenant_id = '' AND r.name = ur.role_name
+WHERE ur.tenant_id <> ''
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (tenant_id, role_name, permission_name)
+SELECT DISTINCT ur.tenant_id, rp.role_name, rp.permission_name
+FROM user_roles ur
+JOIN role_permissions rp ON rp.tenant_id = '' AND rp.role_name = ur.role_name
+WHERE ur.tenant_id <> ''
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE role_permissions ADD CONSTRAINT role_permissions_role_fkey
+    FOREIGN KEY (tenant_id, role_name) REFERENCES roles (tenant_id, name) ON DELETE CASCADE;
+ALTER TABLE user_roles ADD CONSTRAINT user_roles_role_fkey
+    FOREIGN KEY (tenant_id, role_name) REFERENCES roles (tenant_id, name) ON DELETE CASCADE;
+
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE organizations DROP CONSTRAINT IF EXISTS organizations_slug_key;
+ALTER TABLE organizations ADD CONSTRAINT organizations_slug_key UNIQUE (tenant_id, slug);